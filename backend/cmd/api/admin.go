@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type UpdateUserRolePayload struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+// listUsersHandler godoc
+//
+//	@Summary		List users
+//	@Description	Returns a page of every user, newest first. Admins only
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit			query		int		false	"Page size (default 20, max 100)"
+//	@Param			cursor			query		string	false	"Cursor from the previous page"
+//	@Param			include_total	query		bool	false	"Also return the total number of users"
+//	@Success		200				{array}		store.User
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		403				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users [get]
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	pageQuery, err := readPageQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	page, err := app.store.Users.List(r.Context(), pageQuery)
+	if err != nil {
+		switch err {
+		case store.ErrInvalidCursor:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	type envelope struct {
+		Data       []*store.User `json:"data"`
+		NextCursor string        `json:"next_cursor,omitempty"`
+		Total      *int          `json:"total,omitempty"`
+	}
+
+	response := &envelope{
+		Data:       page.Users,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	if err := writeJSON(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateUserRoleHandler godoc
+//
+//	@Summary		Change a user's role
+//	@Description	Makes the user a user, moderator or admin. Their access tokens are revoked so the new role applies right away. Admins can't change their own role. Admins only
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int						true	"User ID"
+//	@Param			payload	body		UpdateUserRolePayload	true	"New role"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/role [put]
+func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdateUserRolePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Otherwise the last admin could lock everyone out
+	admin := getUserFromContext(r)
+	if admin.ID == userID {
+		app.badRequestResponse(w, r, errors.New("you can't change your own role"))
+		return
+	}
+
+	if err := app.store.Users.SetRole(r.Context(), userID, payload.Role); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrInvalidRole:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("Changed user role", "admin_id", admin.ID, "user_id", userID, "role", payload.Role)
+
+	response := map[string]string{"message": "Role updated"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deactivateUserHandler godoc
+//
+//	@Summary		Deactivate a user
+//	@Description	Disables the account and signs the user out of every session. Admins can't deactivate themselves. Admins only
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{userID}/deactivate [post]
+func (app *application) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	admin := getUserFromContext(r)
+	if admin.ID == userID {
+		app.badRequestResponse(w, r, errors.New("you can't deactivate your own account"))
+		return
+	}
+
+	if err := app.store.Users.Deactivate(r.Context(), userID); err != nil {
+		switch err {
+		case store.ErrNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("Deactivated user", "admin_id", admin.ID, "user_id", userID)
+
+	response := map[string]string{"message": "User deactivated"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// removeEventHandler godoc
+//
+//	@Summary		Remove an event
+//	@Description	Deletes an abusive event whoever owns it. Its owner and participants are notified. Moderators and admins only
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/events/{id} [delete]
+func (app *application) removeEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, err := app.store.Events.GetByID(r.Context(), eventID)
+	if err != nil {
+		if err == store.ErrEventNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Events.Delete(r.Context(), eventID); err != nil {
+		if err == store.ErrEventNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	moderator := getUserFromContext(r)
+	app.logger.Infow("Removed event", "moderator_id", moderator.ID, "event_id", eventID)
+
+	// The event is gone, so the notifications can't point to it
+	message := fmt.Sprintf("%q was removed by a moderator", eventName(event))
+	notifications := []*store.Notification{{UserID: event.EventOwner, Type: store.NotificationEventRemoved, Message: message}}
+	for _, p := range event.Participants {
+		if p.UserID != event.EventOwner {
+			notifications = append(notifications, &store.Notification{UserID: p.UserID, Type: store.NotificationEventRemoved, Message: message})
+		}
+	}
+	app.notify(r.Context(), notifications...)
+
+	response := map[string]string{"message": "Event removed"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAdminRequest returns a request from a user with the role, with the
+// given route params
+func newAdminRequest(method, body, role string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, userCtx, &store.User{ID: 1, Role: role})
+	return req.WithContext(ctx)
+}
+
+func TestRequireRole(t *testing.T) {
+	app := newTestApplication()
+	handler := app.RequireRole(store.RoleModerator)(http.HandlerFunc(app.healthCheckHandler))
+
+	tests := []struct {
+		name           string
+		user           *store.User
+		expectedStatus int
+	}{
+		{name: "moderator", user: &store.User{ID: 1, Role: store.RoleModerator}, expectedStatus: http.StatusOK},
+		{name: "admin has every moderator right", user: &store.User{ID: 1, Role: store.RoleAdmin}, expectedStatus: http.StatusOK},
+		{name: "user", user: &store.User{ID: 1, Role: store.RoleUser}, expectedStatus: http.StatusForbidden},
+		{name: "unauthenticated", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/admin/users", nil)
+			if tt.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), userCtx, tt.user))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestJwtCarriesRole(t *testing.T) {
+	app := newTestApplication()
+
+	token, err := app.createJwtToken(&store.User{ID: 1, Role: store.RoleModerator})
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	assert.NoError(t, err)
+	assert.Equal(t, store.RoleModerator, claims["role"])
+}
+
+func TestListUsersHandler(t *testing.T) {
+	app := newTestApplication()
+
+	req := newAdminRequest("GET", "", store.RoleAdmin, nil)
+	w := httptest.NewRecorder()
+	app.listUsersHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []store.User `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, store.RoleAdmin, response.Data[1].Role)
+
+	req = httptest.NewRequest("GET", "/v1/admin/users?cursor=bad", nil)
+	w = httptest.NewRecorder()
+	app.listUsersHandler(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateUserRoleHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "promote to moderator", userID: "2", body: `{"role": "moderator"}`, expectedStatus: http.StatusOK},
+		{name: "unknown role", userID: "2", body: `{"role": "owner"}`, expectedStatus: http.StatusBadRequest},
+		{name: "own role", userID: "1", body: `{"role": "user"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown user", userID: "99", body: `{"role": "moderator"}`, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newAdminRequest("PUT", tt.body, store.RoleAdmin, map[string]string{"userID": tt.userID})
+			w := httptest.NewRecorder()
+			app.updateUserRoleHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestDeactivateUserHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+	}{
+		{name: "deactivate user", userID: "2", expectedStatus: http.StatusOK},
+		{name: "own account", userID: "1", expectedStatus: http.StatusBadRequest},
+		{name: "unknown user", userID: "99", expectedStatus: http.StatusNotFound},
+		{name: "invalid ID", userID: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newAdminRequest("POST", "", store.RoleAdmin, map[string]string{"userID": tt.userID})
+			w := httptest.NewRecorder()
+			app.deactivateUserHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRemoveEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	req := newAdminRequest("DELETE", "", store.RoleModerator, map[string]string{"id": "5"})
+	w := httptest.NewRecorder()
+	app.removeEventHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The owner and the other participant are told
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	notifications.mu.Lock()
+	defer notifications.mu.Unlock()
+	assert.Len(t, notifications.created, 2)
+	for _, n := range notifications.created {
+		assert.Equal(t, store.NotificationEventRemoved, n.Type)
+		assert.Nil(t, n.EventID)
+	}
+}
@@ -2,43 +2,75 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"time"
 
 	"github.com/MishNia/Sportify.git/docs"
 	"github.com/MishNia/Sportify.git/internal/auth"
+	"github.com/MishNia/Sportify.git/internal/mailer"
+	"github.com/MishNia/Sportify.git/internal/ratelimit"
 	"github.com/MishNia/Sportify.git/internal/store"
 	"github.com/MishNia/Sportify.git/internal/websocket"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
-	gorilla "github.com/gorilla/websocket"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout is how long in-flight requests and background jobs get to
+// finish once the server is asked to stop.
+const shutdownTimeout = time.Second * 15
+
 type application struct {
 	config        config
 	store         store.Storage
 	logger        *zap.SugaredLogger
 	authenticator auth.Authenticator
 	validator     *validator.Validate
+	mailer        mailer.Mailer
+	hub           *websocket.Hub
 }
 
 type config struct {
-	addr   string
-	db     dbConfig
-	auth   authConfig
-	apiURL string
+	addr        string
+	db          dbConfig
+	auth        authConfig
+	mail        mailConfig
+	reminders   reminderConfig
+	rateLimit   rateLimitConfig
+	chatBroker  string
+	apiURL      string
+	frontendURL string
+	// trustedProxies may set X-Forwarded-For and X-Real-IP
+	trustedProxies []netip.Prefix
 }
 
 type authConfig struct {
-	token tokenConfig
+	token            tokenConfig
+	refreshExp       time.Duration
+	passwordResetExp time.Duration
+	invitationExp    time.Duration
+}
+
+type mailConfig struct {
+	fromEmail string
+}
+
+// rateLimitConfig has a limit per route group. auth applies to login, signup,
+// verification emails and password resets on top of api, chat to every frame
+// sent to an event chat.
+type rateLimitConfig struct {
+	enabled bool
+	api     ratelimit.Config
+	auth    ratelimit.Config
+	chat    ratelimit.Config
 }
 
 type tokenConfig struct {
@@ -69,7 +101,7 @@ func (app *application) mount() http.Handler {
 
 	// A good base middleware stack
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.RealIPMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -78,9 +110,10 @@ func (app *application) mount() http.Handler {
 	// processing should be stopped.
 	r.Use(middleware.Timeout(60 * time.Second))
 
-	// Initialize websocket hub
-	hub := websocket.NewHub()
-	go hub.Run()
+	// Each limiter counts on its own, so logging in doesn't use up the
+	// general allowance
+	apiLimit := app.RateLimitMiddleware(ratelimit.NewTokenBucket(app.config.rateLimit.api))
+	authLimit := app.RateLimitMiddleware(ratelimit.NewTokenBucket(app.config.rateLimit.auth))
 
 	r.Route("/v1", func(r chi.Router) {
 		r.Get("/health", app.healthCheckHandler)
@@ -91,70 +124,119 @@ func (app *application) mount() http.Handler {
 		))
 
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/signup", app.registerUserHandler)
-			r.Post("/login", app.userLoginHandler)
+			r.Use(apiLimit)
+			r.With(authLimit).Post("/signup", app.registerUserHandler)
+			r.With(authLimit).Post("/login", app.userLoginHandler)
+			r.Post("/verify", app.verifyEmailHandler)
+			r.With(authLimit).Post("/resend-verification", app.resendVerificationHandler)
+			r.Post("/refresh", app.refreshTokenHandler)
+			r.Post("/logout", app.logoutHandler)
+			r.With(authLimit).Post("/forgot-password", app.forgotPasswordHandler)
+			r.With(authLimit).Post("/reset-password", app.resetPasswordHandler)
 			r.Get("/google", app.googleAuthHandler)
 			r.Get("/google/callback", app.googleCallbackHandler)
 		})
 
 		r.Route("/profile", func(r chi.Router) {
 			r.Use(app.AuthTokenMiddleware)
+			r.Use(apiLimit)
 
 			r.Get("/{userID}", app.getUserProfileHandler)
 			r.Post("/", app.createUserProfileHandler)
 			r.Put("/", app.updateUserProfileHandler)
 		})
 
+		r.Route("/users/me", func(r chi.Router) {
+			// Calendar apps authenticate with the feed token instead of a JWT
+			r.With(apiLimit).Get("/calendar.ics", app.getCalendarFeedHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Use(apiLimit)
+				r.Post("/calendar/token", app.createCalendarFeedTokenHandler)
+				r.Delete("/calendar/token", app.revokeCalendarFeedTokenHandler)
+			})
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			// WebSocket endpoint for live notifications - no auth middleware
+			r.Get("/ws", app.notificationsWebSocketHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Use(apiLimit)
+				r.Get("/", app.getNotificationsHandler)
+				r.Post("/read", app.markNotificationsReadHandler)
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(apiLimit)
+			r.Use(app.RequireRole(store.RoleModerator))
+
+			r.Delete("/events/{id}", app.removeEventHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.RequireRole(store.RoleAdmin))
+				r.Get("/users", app.listUsersHandler)
+				r.Put("/users/{userID}/role", app.updateUserRoleHandler)
+				r.Post("/users/{userID}/deactivate", app.deactivateUserHandler)
+			})
+		})
+
 		r.Route("/events", func(r chi.Router) {
 			// New endpoint for getting all events
 
 			// Group protected endpoints
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
+				r.Use(apiLimit)
 				r.Post("/", app.createEventHandler)
+				r.Post("/series", app.createEventSeriesHandler)
+				r.Get("/series/{seriesID}", app.getEventSeriesHandler)
+				r.Put("/{id}/occurrences", app.updateOccurrenceHandler)
+				r.Delete("/{id}/occurrences", app.deleteOccurrenceHandler)
 				r.Put("/{id}", app.updateEventHandler)
 				r.Get("/{id}", app.getEventHandler)
 				r.Delete("/{id}", app.deleteEventHandler)
 				r.Post("/{id}/join", app.joinEventHandler)
 				r.Delete("/{id}/leave", app.leaveEventHandler)
+				r.Get("/{id}/waitlist", app.getEventWaitlistHandler)
+				r.Delete("/{id}/waitlist", app.leaveEventWaitlistHandler)
+				r.Delete("/{id}/participants/{userID}", app.removeEventParticipantHandler)
+				r.Post("/{id}/organizers", app.addEventOrganizerHandler)
+				r.Delete("/{id}/organizers/{userID}", app.removeEventOrganizerHandler)
+				r.Get("/{id}/transfer", app.getEventTransferHandler)
+				r.Post("/{id}/transfer", app.transferEventHandler)
+				r.Delete("/{id}/transfer", app.cancelEventTransferHandler)
+				r.Post("/{id}/transfer/accept", app.acceptEventTransferHandler)
+				r.Post("/{id}/transfer/decline", app.declineEventTransferHandler)
+				r.Post("/{id}/invites", app.createEventInviteHandler)
+				r.Get("/{id}/requests", app.getEventJoinRequestsHandler)
+				r.Post("/{id}/requests/{requestID}/approve", app.approveEventJoinRequestHandler)
+				r.Post("/{id}/requests/{requestID}/reject", app.rejectEventJoinRequestHandler)
+				r.Get("/{id}/messages", app.getEventMessagesHandler)
+				r.Put("/{id}/messages/{messageID}", app.editEventMessageHandler)
+				r.Delete("/{id}/messages/{messageID}", app.deleteEventMessageHandler)
+				r.Post("/{id}/chat/mutes", app.muteChatUserHandler)
+				r.Delete("/{id}/chat/mutes/{userID}", app.unmuteChatUserHandler)
+				r.Post("/{id}/chat/kick", app.kickChatUserHandler)
+				r.Get("/{id}/ical", app.getEventICalHandler)
 				r.Get("/all", app.getAllEventsSimpleHandler)
 				// Existing filtered endpoint
 				r.Get("/", app.getAllEventsHandler)
+				r.Post("/search", app.searchEventsHandler)
 			})
 
 			// WebSocket endpoint for event chat - no auth middleware
 			r.Get("/{id}/chat", func(w http.ResponseWriter, r *http.Request) {
-				// Get token from query parameter
-				token := r.URL.Query().Get("token")
-				if token == "" {
-					app.logger.Warnw("No token provided in websocket connection")
-					app.forbiddenResponse(w, r)
-					return
-				}
-
-				// Validate token
-				jwtToken, err := app.authenticator.ValidateToken(token)
-				if err != nil {
-					app.logger.Warnw("Invalid token in websocket connection", "error", err)
-					app.forbiddenResponse(w, r)
-					return
-				}
-
-				// Add claims to context
-				claims := jwtToken.Claims.(jwt.MapClaims)
-				userIDFloat := claims["sub"].(float64) // sub claim is a float64
-				userIDStr := strconv.FormatInt(int64(userIDFloat), 10)
-
-				// Convert userID to int64
-				userID := int64(userIDFloat)
-
-				// Get user's email from the Users store
-				user, err := app.store.Users.GetByID(r.Context(), userID)
-				if err != nil {
-					app.logger.Errorw("Failed to get user", "error", err)
-					app.internalServerError(w, r, err)
+				user, ok := app.authenticateWebSocket(w, r)
+				if !ok {
 					return
 				}
+				userID := user.ID
+				userIDStr := strconv.FormatInt(userID, 10)
 
 				ctx := context.WithValue(r.Context(), "userID", userIDStr)
 				ctx = context.WithValue(ctx, "userEmail", user.Email)
@@ -173,22 +255,21 @@ func (app *application) mount() http.Handler {
 					return
 				}
 
-				// Verify user is a participant
+				// Verify user is a participant who wasn't kicked
 				event, err := app.store.Events.GetByID(r.Context(), eventID)
 				if err != nil {
 					app.logger.Errorw("Failed to get event", "error", err)
 					app.internalServerError(w, r, err)
 					return
 				}
-				isParticipant := false
-				for _, p := range event.Participants {
-					if p.UserID == userID {
-						isParticipant = true
-						break
-					}
+				allowed, err := app.canChat(r.Context(), event, userID)
+				if err != nil {
+					app.logger.Errorw("Failed to check chat access", "error", err)
+					app.internalServerError(w, r, err)
+					return
 				}
-				if !isParticipant {
-					app.logger.Warnw("User is not a participant", "userID", userID, "eventID", eventID)
+				if !allowed {
+					app.logger.Warnw("User may not join the chat", "userID", userID, "eventID", eventID)
 					app.forbiddenResponse(w, r)
 					return
 				}
@@ -201,16 +282,7 @@ func (app *application) mount() http.Handler {
 					return
 				}
 
-				upgrader := gorilla.Upgrader{
-					ReadBufferSize:  1024,
-					WriteBufferSize: 1024,
-					CheckOrigin: func(r *http.Request) bool {
-						origin := r.Header.Get("Origin")
-						app.logger.Infow("Checking origin", "origin", origin)
-						return true // Allow all origins for development
-					},
-				}
-				conn, err := upgrader.Upgrade(w, r, nil)
+				conn, err := app.upgradeWebSocket(w, r)
 				if err != nil {
 					app.logger.Errorw("Failed to upgrade connection", "error", err)
 					app.internalServerError(w, r, err)
@@ -223,7 +295,7 @@ func (app *application) mount() http.Handler {
 					"username", profile.FirstName+" "+profile.LastName,
 				)
 
-				hub.HandleWebSocket(conn, eventID, userID, profile.FirstName+" "+profile.LastName)
+				app.hub.HandleWebSocket(conn, eventID, userID, profile.FirstName+" "+profile.LastName)
 			})
 		})
 	})
@@ -231,7 +303,7 @@ func (app *application) mount() http.Handler {
 	return r
 }
 
-func (app *application) run(mux http.Handler) error {
+func (app *application) run(ctx context.Context, mux http.Handler) error {
 	// Docs
 	docs.SwaggerInfo.Version = version
 	docs.SwaggerInfo.Host = app.config.apiURL
@@ -245,6 +317,26 @@ func (app *application) run(mux http.Handler) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		app.logger.Infow("shutting down server", "addr", app.config.addr)
+
+		// Let in-flight requests finish
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdown <- srv.Shutdown(ctx)
+	}()
+
 	log.Printf("Server has started at %s", app.config.addr)
-	return srv.ListenAndServe()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if err := <-shutdown; err != nil {
+		return err
+	}
+
+	app.logger.Infow("server stopped", "addr", app.config.addr)
+	return nil
 }
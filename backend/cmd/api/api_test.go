@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/MishNia/Sportify.git/internal/auth"
+	"github.com/MishNia/Sportify.git/internal/mailer"
 	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/MishNia/Sportify.git/internal/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -26,8 +31,45 @@ func (m *mockUserStore) Create(ctx context.Context, user *store.User) error {
 	return nil
 }
 
+func (m *mockUserStore) CreateAndInvite(ctx context.Context, user *store.User, tokenHash string, invitationExp time.Duration) error {
+	// Mock inactive user creation success
+	user.ID = 1
+	user.IsActive = false
+	user.CreatedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
+
+func (m *mockUserStore) Activate(ctx context.Context, tokenHash string) (*store.User, error) {
+	// Only the token "valid-verify-token" is accepted
+	if tokenHash != auth.HashToken("valid-verify-token") {
+		return nil, store.ErrInvalidInvitation
+	}
+	return &store.User{
+		ID:       1,
+		Email:    "test@example.com",
+		IsActive: true,
+	}, nil
+}
+
+func (m *mockUserStore) Delete(ctx context.Context, userID int64) error {
+	// Mock user deletion success
+	return nil
+}
+
+func (m *mockUserStore) Reinvite(ctx context.Context, userID int64, tokenHash string, invitationExp time.Duration) error {
+	// Mock invitation replacement success
+	return nil
+}
+
 func (m *mockUserStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
-	// Mock user not found
+	// Only player@example.com has an account, pending@example.com hasn't
+	// verified theirs yet
+	switch email {
+	case "player@example.com":
+		return &store.User{ID: 2, Email: email, IsActive: true, Role: store.RoleUser}, nil
+	case "pending@example.com":
+		return &store.User{ID: 6, Email: email, Role: store.RoleUser}, nil
+	}
 	return nil, store.ErrNotFound
 }
 
@@ -37,9 +79,40 @@ func (m *mockUserStore) GetByID(ctx context.Context, userID int64) (*store.User,
 		ID:        userID,
 		Email:     "test@example.com",
 		CreatedAt: time.Now().Format(time.RFC3339),
+		IsActive:  true,
+		Role:      store.RoleUser,
+	}, nil
+}
+
+func (m *mockUserStore) List(ctx context.Context, pageQuery store.PageQuery) (*store.UserPage, error) {
+	// Mock a single page of users
+	if pageQuery.Cursor == "bad" {
+		return nil, store.ErrInvalidCursor
+	}
+	return &store.UserPage{
+		Users: []*store.User{
+			{ID: 2, Email: "player@example.com", IsActive: true, Role: store.RoleUser},
+			{ID: 1, Email: "admin@example.com", IsActive: true, Role: store.RoleAdmin},
+		},
 	}, nil
 }
 
+func (m *mockUserStore) Deactivate(ctx context.Context, userID int64) error {
+	// Only users 1 to 10 exist
+	if userID > 10 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mockUserStore) SetRole(ctx context.Context, userID int64, role string) error {
+	// Only users 1 to 10 exist
+	if userID > 10 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
 func (m *mockUserStore) CreateOrUpdateGoogleUser(ctx context.Context, googleID, email, name string) (*store.User, bool, error) {
 	args := m.Called(ctx, googleID, email, name)
 	if args.Get(0) != nil {
@@ -104,6 +177,49 @@ func (m *mockEventStore) GetByID(ctx context.Context, id int64) (*store.Event, e
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 		IsFull:        false,
+		Status:        store.EventStatusScheduled,
+		Participants: []store.EventParticipant{
+			{ID: 1, EventID: id, UserID: 1, FirstName: "Test", LastName: "User"},
+		},
+	}
+	// Event 2 has no spots left
+	if id == 2 {
+		event.MaxPlayers = 1
+		event.IsFull = true
+	}
+	// Event 3 is an occurrence of series 1
+	if id == 3 {
+		seriesID := int64(1)
+		event.SeriesID = &seriesID
+	}
+	// Event 4 has been cancelled
+	if id == 4 {
+		event.Status = store.EventStatusCancelled
+	}
+	// User 3 has also joined event 5
+	if id == 5 {
+		event.Participants = append(event.Participants, store.EventParticipant{ID: 2, EventID: id, UserID: 3, FirstName: "Other", LastName: "Player"})
+	}
+	// Event 6 has user 3 as a co-organizer and user 4 as a plain participant
+	if id == 6 {
+		event.Participants = append(event.Participants,
+			store.EventParticipant{ID: 2, EventID: id, UserID: 3, FirstName: "Co", LastName: "Organizer"},
+			store.EventParticipant{ID: 3, EventID: id, UserID: 4, FirstName: "Other", LastName: "Player"},
+		)
+		event.Organizers = []int64{3}
+	}
+	// Event 7 is private and event 8 is unlisted. User 5 co-organizes event
+	// 7 without playing in it
+	if id == 7 {
+		event.Visibility = store.VisibilityPrivate
+		event.Organizers = []int64{5}
+	}
+	if id == 8 {
+		event.Visibility = store.VisibilityUnlisted
+	}
+	// Event 9 requires approval to join
+	if id == 9 {
+		event.RequiresApproval = true
 	}
 	return event, nil
 }
@@ -119,11 +235,56 @@ func (m *mockEventStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (m *mockEventStore) Join(ctx context.Context, eventID, userID int64) error {
-	// Mock joining an event
-	args := m.Called(ctx, eventID, userID)
-	if args.Get(0) != nil {
-		return args.Get(0).(error)
+func (m *mockEventStore) Cancel(ctx context.Context, eventID int64, reason string) ([]*store.Notification, error) {
+	// Event 4 has already been cancelled
+	if eventID == 4 {
+		return nil, store.ErrEventNotScheduled
+	}
+	return []*store.Notification{}, nil
+}
+
+func (m *mockEventStore) CompletePast(ctx context.Context, now time.Time) (int64, error) {
+	// Mock two events that have started
+	return 2, nil
+}
+
+func (m *mockEventStore) Join(ctx context.Context, eventID, userID int64, inviteHash string) error {
+	// User 1 is already a participant of every event, event 2 is full and
+	// event 4 has been cancelled
+	switch {
+	case eventID == 4:
+		return store.ErrEventNotScheduled
+	case userID == 1:
+		return store.ErrAlreadyJoined
+	case eventID == 7 || eventID == 8:
+		return m.useInvite(ctx, eventID, inviteHash)
+	case eventID == 9:
+		return store.ErrApprovalRequired
+	case eventID == 2:
+		return store.ErrEventFull
+	}
+	return nil
+}
+
+// mockInviteToken is the only invite that has been handed out.
+const mockInviteToken = "mock-invite"
+
+func (m *mockEventStore) useInvite(ctx context.Context, eventID int64, inviteHash string) error {
+	if inviteHash == "" {
+		return store.ErrInviteRequired
+	}
+	return m.CheckInvite(ctx, eventID, inviteHash)
+}
+
+func (m *mockEventStore) CreateInvite(ctx context.Context, invite *store.EventInvite, tokenHash string) error {
+	invite.ID = 1
+	invite.CreatedAt = time.Now()
+	return nil
+}
+
+func (m *mockEventStore) CheckInvite(ctx context.Context, eventID int64, tokenHash string) error {
+	if tokenHash != auth.HashToken(mockInviteToken) {
+		return store.ErrInvalidInvite
 	}
 	return nil
 }
@@ -133,14 +294,404 @@ func (m *mockEventStore) Leave(ctx context.Context, eventID, userID int64) error
 	return nil
 }
 
-func (m *mockEventStore) GetAllWithFilter(ctx context.Context, filter *store.EventFilter) ([]*store.Event, error) {
+func (m *mockEventStore) AddOrganizer(ctx context.Context, eventID, userID, grantedBy int64) error {
+	// Only users 1, 3 and 4 are participants
+	if userID != 1 && userID != 3 && userID != 4 {
+		return store.ErrNotJoined
+	}
+	return nil
+}
+
+func (m *mockEventStore) RemoveOrganizer(ctx context.Context, eventID, userID int64) error {
+	// Mock removing an organizer
+	return nil
+}
+
+func (m *mockEventStore) ProposeTransfer(ctx context.Context, transfer *store.EventTransfer) error {
+	// Only users 1, 3 and 4 are participants
+	if transfer.ToUserID != 1 && transfer.ToUserID != 3 && transfer.ToUserID != 4 {
+		return store.ErrNotJoined
+	}
+	transfer.ID = 1
+	transfer.Status = store.TransferPending
+	transfer.CreatedAt = time.Now()
+	return nil
+}
+
+func (m *mockEventStore) GetPendingTransfer(ctx context.Context, eventID int64) (*store.EventTransfer, error) {
+	// Event 6 has been offered to user 3
+	if eventID != 6 {
+		return nil, store.ErrTransferNotFound
+	}
+	return &store.EventTransfer{ID: 1, EventID: eventID, FromUserID: 1, ToUserID: 3, Status: store.TransferPending, CreatedAt: time.Now()}, nil
+}
+
+func (m *mockEventStore) RespondTransfer(ctx context.Context, eventID, userID int64, accept bool) (*store.EventTransfer, error) {
+	transfer, err := m.GetPendingTransfer(ctx, eventID)
+	if err != nil || transfer.ToUserID != userID {
+		return nil, store.ErrTransferNotFound
+	}
+	transfer.Status = store.TransferDeclined
+	if accept {
+		transfer.Status = store.TransferAccepted
+	}
+	return transfer, nil
+}
+
+func (m *mockEventStore) CancelTransfer(ctx context.Context, eventID int64) error {
+	_, err := m.GetPendingTransfer(ctx, eventID)
+	return err
+}
+
+func (m *mockEventStore) RequestJoin(ctx context.Context, eventID, userID int64, inviteHash string) (*store.JoinRequest, error) {
+	// User 3 is already waiting for an answer and user 4 was just declined
+	switch userID {
+	case 3:
+		return nil, store.ErrAlreadyRequested
+	case 4:
+		return nil, store.ErrJoinRequestDeclined
+	}
+	return &store.JoinRequest{ID: 2, EventID: eventID, UserID: userID, Status: store.JoinRequestPending, CreatedAt: time.Now()}, nil
+}
+
+func (m *mockEventStore) GetJoinRequests(ctx context.Context, eventID int64) ([]store.JoinRequest, error) {
+	return []store.JoinRequest{
+		{ID: 1, EventID: eventID, UserID: 5, FirstName: "New", LastName: "Player", Status: store.JoinRequestPending, CreatedAt: time.Now()},
+	}, nil
+}
+
+func (m *mockEventStore) RespondJoinRequest(ctx context.Context, eventID, requestID, respondedBy int64, approve bool) (*store.JoinRequest, error) {
+	// Request 1 of user 5 is the only pending one and event 2 is full
+	if requestID != 1 {
+		return nil, store.ErrJoinRequestNotFound
+	}
+	if approve && eventID == 2 {
+		return nil, store.ErrEventFull
+	}
+	now := time.Now()
+	request := &store.JoinRequest{ID: requestID, EventID: eventID, UserID: 5, Status: store.JoinRequestRejected, RespondedAt: &now, RespondedBy: &respondedBy}
+	if approve {
+		request.Status = store.JoinRequestApproved
+	}
+	return request, nil
+}
+
+func (m *mockEventStore) JoinWaitlist(ctx context.Context, eventID, userID int64, inviteHash string) (*store.WaitlistEntry, error) {
+	// User 1 is the only participant, user 3 is already waiting and user 4
+	// gets the spot that opened since the event was found full
+	switch userID {
+	case 1:
+		return nil, store.ErrAlreadyJoined
+	case 3:
+		return nil, store.ErrAlreadyWaitlisted
+	case 4:
+		return nil, nil
+	}
+	return &store.WaitlistEntry{ID: 1, EventID: eventID, UserID: userID, Position: 2, JoinedAt: time.Now()}, nil
+}
+
+func (m *mockEventStore) LeaveWaitlist(ctx context.Context, eventID, userID int64) error {
+	// Only user 3 is on the waitlist
+	if userID != 3 {
+		return store.ErrNotWaitlisted
+	}
+	return nil
+}
+
+func (m *mockEventStore) GetWaitlist(ctx context.Context, eventID int64) ([]store.WaitlistEntry, error) {
+	// Mock a single waiting user
+	return []store.WaitlistEntry{
+		{ID: 1, EventID: eventID, UserID: 3, FirstName: "Wait", LastName: "Ing", Position: 1, JoinedAt: time.Now()},
+	}, nil
+}
+
+func (m *mockEventStore) CreateSeries(ctx context.Context, series *store.EventSeries, template *store.Event) error {
+	// Mock materializing the occurrences
+	occurrences, err := series.Occurrences()
+	if err != nil {
+		return err
+	}
+	series.ID = 1
+	for i, at := range occurrences {
+		event := *template
+		event.ID = int64(i + 1)
+		event.EventDateTime = at
+		event.SeriesID = &series.ID
+		series.Events = append(series.Events, &event)
+	}
+	return nil
+}
+
+func (m *mockEventStore) GetSeries(ctx context.Context, seriesID int64) (*store.EventSeries, error) {
+	// Series 1 is made of event 3 and series 7 of the private event 7
+	if seriesID != 1 && seriesID != 7 {
+		return nil, store.ErrSeriesNotFound
+	}
+	eventID := int64(3)
+	if seriesID == 7 {
+		eventID = 7
+	}
+	occurrence, _ := m.GetByID(ctx, eventID)
+	occurrence.SeriesID = &seriesID
+	return &store.EventSeries{ID: seriesID, EventOwner: 1, Frequency: store.FrequencyWeekly, Events: []*store.Event{occurrence}}, nil
+}
+
+func (m *mockEventStore) UpdateFollowing(ctx context.Context, event *store.Event, shift time.Duration) error {
+	// Mock updating the rest of the series
+	return nil
+}
+
+func (m *mockEventStore) CancelFollowing(ctx context.Context, eventID int64, reason string) ([]*store.Notification, error) {
+	// Mock cancelling the rest of the series
+	return []*store.Notification{}, nil
+}
+
+func (m *mockEventStore) GetAllWithFilter(ctx context.Context, filter *store.EventFilter) (*store.EventPage, error) {
 	// Mock getting all events with filter
-	return []*store.Event{}, nil
+	if filter.Cursor == "bad-cursor" {
+		return nil, store.ErrInvalidCursor
+	}
+	return &store.EventPage{Events: []*store.Event{}}, nil
+}
+
+func (m *mockEventStore) GetByUser(ctx context.Context, userID int64) ([]*store.Event, error) {
+	// Mock the user's schedule
+	return []*store.Event{
+		{ID: 1, EventOwner: userID, Sport: "Soccer", Title: "Morning Soccer", LocationName: "Central Park", EventDateTime: time.Now().Add(24 * time.Hour)},
+	}, nil
+}
+
+func (m *mockEventStore) GetAllSimple(ctx context.Context, page store.PageQuery) (*store.EventPage, error) {
+	// Mock a first page of two events with more to come
+	if page.Cursor == "bad-cursor" {
+		return nil, store.ErrInvalidCursor
+	}
+	result := &store.EventPage{
+		Events: []*store.Event{
+			{ID: 2, Title: "Newer Event", Participants: []store.EventParticipant{}},
+			{ID: 1, Title: "Test Event", Participants: []store.EventParticipant{}},
+		},
+		NextCursor: "next-page",
+	}
+	if page.IncludeTotal {
+		total := 3
+		result.Total = &total
+	}
+	return result, nil
+}
+
+type mockChatStore struct {
+	mock.Mock
+	mu      sync.Mutex
+	actions []string
+}
+
+func (m *mockChatStore) Create(ctx context.Context, msg *store.ChatMessage) error {
+	// Mock message persistence
+	msg.ID = 1
+	msg.CreatedAt = time.Now()
+	return nil
+}
+
+func (m *mockChatStore) GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*store.ChatMessage, error) {
+	// Mock a single page of history
+	return []*store.ChatMessage{
+		{ID: 1, EventID: eventID, UserID: 1, Username: "Test User", Content: "Hello", CreatedAt: time.Now()},
+	}, nil
+}
+
+func (m *mockChatStore) GetByID(ctx context.Context, id int64) (*store.ChatMessage, error) {
+	// Messages 1 and 2 are in event 5, sent by users 1 and 3
+	switch id {
+	case 1:
+		return &store.ChatMessage{ID: 1, EventID: 5, UserID: 1, Username: "Test User", Content: "Hello", CreatedAt: time.Now()}, nil
+	case 2:
+		return &store.ChatMessage{ID: 2, EventID: 5, UserID: 3, Username: "Other Player", Content: "Hi", CreatedAt: time.Now()}, nil
+	}
+	return nil, store.ErrMessageNotFound
+}
+
+func (m *mockChatStore) Edit(ctx context.Context, msg *store.ChatMessage, actorID int64) error {
+	// User 3 is muted
+	if msg.UserID == 3 {
+		return store.ErrMuted
+	}
+	now := time.Now()
+	msg.EditedAt = &now
+	m.record(store.ModerationMessageEdited)
+	return nil
+}
+
+func (m *mockChatStore) Delete(ctx context.Context, msg *store.ChatMessage, actorID int64) error {
+	m.record(store.ModerationMessageDeleted)
+	return nil
+}
+
+func (m *mockChatStore) Mute(ctx context.Context, eventID, userID, actorID int64, until time.Time) error {
+	m.record(store.ModerationUserMuted)
+	return nil
+}
+
+func (m *mockChatStore) Unmute(ctx context.Context, eventID, userID, actorID int64) error {
+	m.record(store.ModerationUserUnmuted)
+	return nil
+}
+
+func (m *mockChatStore) Kick(ctx context.Context, eventID, userID, actorID int64, until time.Time) error {
+	m.record(store.ModerationUserKicked)
+	return nil
+}
+
+func (m *mockChatStore) IsKicked(ctx context.Context, eventID, userID int64) (bool, error) {
+	// User 3 was kicked from the chat of event 5
+	return eventID == 5 && userID == 3, nil
+}
+
+// record keeps the moderation actions so tests can check what was logged
+func (m *mockChatStore) record(action string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions = append(m.actions, action)
+}
+
+type mockPasswordResetStore struct {
+	mock.Mock
+}
+
+func (m *mockPasswordResetStore) Create(ctx context.Context, userID int64, tokenHash string, exp time.Duration) error {
+	// Mock reset token persistence
+	return nil
+}
+
+func (m *mockPasswordResetStore) Reset(ctx context.Context, tokenHash string, user *store.User) error {
+	// Only the token "valid-reset-token" is accepted
+	if tokenHash != auth.HashToken("valid-reset-token") {
+		return store.ErrInvalidResetToken
+	}
+	user.ID = 1
+	user.Email = "test@example.com"
+	user.TokenVersion = 1
+	return nil
+}
+
+type mockRefreshTokenStore struct {
+	mock.Mock
+}
+
+func (m *mockRefreshTokenStore) Create(ctx context.Context, token *store.RefreshToken) error {
+	// Mock refresh token persistence
+	token.ID = 1
+	token.FamilyID = "family"
+	return nil
+}
+
+func (m *mockRefreshTokenStore) Rotate(ctx context.Context, oldHash string, next *store.RefreshToken) error {
+	// "valid-refresh-token" rotates, "used-refresh-token" was already rotated
+	switch oldHash {
+	case auth.HashToken("valid-refresh-token"):
+		next.ID = 2
+		next.UserID = 1
+		next.FamilyID = "family"
+		return nil
+	case auth.HashToken("used-refresh-token"):
+		return store.ErrRefreshTokenReused
+	default:
+		return store.ErrInvalidRefreshToken
+	}
+}
+
+func (m *mockRefreshTokenStore) RevokeFamily(ctx context.Context, tokenHash string) error {
+	// Mock revocation success
+	return nil
+}
+
+// Mock CalendarFeedStore
+type mockCalendarFeedStore struct{}
+
+func (m *mockCalendarFeedStore) Rotate(ctx context.Context, userID int64, tokenHash string) error {
+	// Mock issuing a new token
+	return nil
+}
+
+func (m *mockCalendarFeedStore) Revoke(ctx context.Context, userID int64) error {
+	// Mock revocation success
+	return nil
+}
+
+func (m *mockCalendarFeedStore) GetUserID(ctx context.Context, tokenHash string) (int64, error) {
+	// Only "valid-feed-token" belongs to a user
+	if tokenHash != auth.HashToken("valid-feed-token") {
+		return 0, store.ErrInvalidFeedToken
+	}
+	return 1, nil
+}
+
+// Mock NotificationStore
+type mockNotificationStore struct {
+	mu      sync.Mutex
+	created []*store.Notification
+}
+
+func (m *mockNotificationStore) Create(ctx context.Context, notifications ...*store.Notification) error {
+	// Record the notifications so tests can check what was sent
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, n := range notifications {
+		n.ID = int64(len(m.created) + 1)
+		n.CreatedAt = time.Now()
+		m.created = append(m.created, n)
+	}
+	return nil
+}
+
+func (m *mockNotificationStore) GetByUser(ctx context.Context, userID int64, unreadOnly bool, pageQuery store.PageQuery) (*store.NotificationPage, error) {
+	// Mock a page with two notifications, the only ones when total is asked
+	if pageQuery.Cursor == "bad-cursor" {
+		return nil, store.ErrInvalidCursor
+	}
+	eventID := int64(1)
+	page := &store.NotificationPage{
+		Notifications: []*store.Notification{
+			{ID: 2, UserID: userID, EventID: &eventID, Type: store.NotificationEventJoined, Message: "Test User joined \"Test Event\""},
+			{ID: 1, UserID: userID, EventID: &eventID, Type: store.NotificationEventUpdated, Message: "\"Test Event\" has been updated"},
+		},
+	}
+	if pageQuery.IncludeTotal {
+		total := 2
+		page.Total = &total
+	}
+	return page, nil
+}
+
+func (m *mockNotificationStore) MarkRead(ctx context.Context, userID int64, ids []int64) error {
+	// Mock marking notifications as read
+	return nil
 }
 
-func (m *mockEventStore) GetAllSimple(ctx context.Context) ([]*store.Event, error) {
-	// Mock getting all events without filter
-	return []*store.Event{}, nil
+func (m *mockNotificationStore) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	// Mock unread count after marking everything as read
+	return 0, nil
+}
+
+// Mock ReminderStore
+type mockReminderStore struct {
+	mu    sync.Mutex
+	leads []time.Duration
+}
+
+func (m *mockReminderStore) SendDue(ctx context.Context, now time.Time, lead time.Duration) ([]*store.Notification, error) {
+	// Record the leads in the order they are sent; only the 1h lead has a
+	// reminder due
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leads = append(m.leads, lead)
+	if lead != time.Hour {
+		return []*store.Notification{}, nil
+	}
+	eventID := int64(1)
+	return []*store.Notification{
+		{ID: 1, UserID: 2, EventID: &eventID, Type: store.NotificationEventReminder, Message: "\"Test Event\" starts in 1 hour"},
+	}, nil
 }
 
 // Mock Dependencies
@@ -150,11 +701,20 @@ func newTestApplication() *application {
 	sugar := logger.Sugar()
 
 	mockStore := store.Storage{
-		Users:   &mockUserStore{},    // Mock user store
-		Profile: &mockProfileStore{}, // Mock profile store
-		Events:  &mockEventStore{},   // Mock events store
+		Users:          &mockUserStore{},          // Mock user store
+		Profile:        &mockProfileStore{},       // Mock profile store
+		Events:         &mockEventStore{},         // Mock events store
+		Chat:           &mockChatStore{},          // Mock chat store
+		PasswordResets: &mockPasswordResetStore{}, // Mock password reset store
+		RefreshTokens:  &mockRefreshTokenStore{},  // Mock refresh token store
+		CalendarFeeds:  &mockCalendarFeedStore{},  // Mock calendar feed store
+		Notifications:  &mockNotificationStore{},  // Mock notification store
+		Reminders:      &mockReminderStore{},      // Mock reminder store
 	}
 
+	hub := websocket.NewHub(mockStore.Chat)
+	go hub.Run()
+
 	return &application{
 		config: config{
 			addr: ":8080",
@@ -170,12 +730,19 @@ func newTestApplication() *application {
 					exp:    time.Hour,
 					iss:    "test_issuer",
 				},
+				refreshExp: time.Hour * 24,
+			},
+			reminders: reminderConfig{
+				leads:    []time.Duration{time.Hour * 24, time.Hour},
+				interval: time.Millisecond * 10,
 			},
 			apiURL: "http://localhost:8080",
 		},
 		store:         mockStore,
 		logger:        sugar,
 		authenticator: auth.NewJWTAuthenticator("test_secret", "test_audience", "test_issuer"),
+		mailer:        mailer.NewLogMailer(&bytes.Buffer{}, "no-reply@sportify.test"),
+		hub:           hub,
 	}
 }
 
@@ -195,6 +762,8 @@ func TestHealthCheckHandler(t *testing.T) {
 // **Test /auth/signup Route**
 func TestRegisterUserHandler(t *testing.T) {
 	app := newTestApplication()
+	var outbox bytes.Buffer
+	app.mailer = mailer.NewLogMailer(&outbox, "no-reply@sportify.test")
 	router := app.mount()
 
 	requestBody := `{"email": "test@example.com", "password": "securepassword"}`
@@ -205,6 +774,9 @@ func TestRegisterUserHandler(t *testing.T) {
 	router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusCreated, rec.Code, "Signup should return 201 OK")
+
+	assert.Contains(t, outbox.String(), "To: test@example.com", "Verification email should be sent")
+	assert.Contains(t, outbox.String(), "/verify?token=", "Verification email should contain the link")
 }
 
 // **Test Application Run Method**
@@ -219,3 +791,56 @@ func TestRunMethod(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode, "Server should respond with 200")
 }
+
+// **Test Graceful Shutdown**
+func TestRunShutdown(t *testing.T) {
+	app := newTestApplication()
+
+	// Reserve a free port for the server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	app.config.addr = l.Addr().String()
+	l.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- app.run(ctx, mux)
+	}()
+
+	// Start a request, then ask the server to stop while it is in flight
+	responses := make(chan int, 1)
+	go func() {
+		var resp *http.Response
+		assert.Eventually(t, func() bool {
+			var err error
+			resp, err = http.Get("http://" + app.config.addr)
+			return err == nil
+		}, time.Second, time.Millisecond*10)
+		if resp != nil {
+			resp.Body.Close()
+			responses <- resp.StatusCode
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+		t.Fatal("server stopped before the in-flight request finished")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-responses, "In-flight request should finish")
+	assert.NoError(t, <-stopped, "Server should stop cleanly")
+}
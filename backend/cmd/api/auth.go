@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -32,12 +33,12 @@ type UserWithToken struct {
 // registerUserHandler godoc
 //
 //	@Summary		Registers a user
-//	@Description	Registers a user
+//	@Description	Registers an inactive user and emails a verification link to activate it
 //	@Tags			authentication
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		RegisterUserPayload	true	"User credentials"
-//	@Success		201		{string}	string				"Token"
+//	@Success		201		{object}	store.User
 //	@Failure		400		{object}	error
 //	@Failure		500		{object}	error
 //	@Router			/auth/signup [post]
@@ -68,13 +69,14 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	ctx := r.Context()
 
-	// plainToken := uuid.New().String()
-
-	// // hash the token for storage but keep the plain token for email
-	// hash := sha256.Sum256([]byte(plainToken))
-	// hashToken := hex.EncodeToString(hash[:])
+	// hash the token for storage but keep the plain token for email
+	plainToken, hashToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
-	err := app.store.Users.Create(ctx, user)
+	err = app.store.Users.CreateAndInvite(ctx, user, hashToken, app.config.auth.invitationExp)
 	if err != nil {
 		switch err {
 		case store.ErrDuplicateEmail:
@@ -87,14 +89,155 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 	app.logger.Infow("Successfully created new user")
 
-	// Genarate a jwt token
-	token, err := app.createJwtToken(user.ID)
+	if err := app.sendVerificationEmail(ctx, user.Email, plainToken); err != nil {
+		app.logger.Errorw("Failed to send verification email", "error", err)
+
+		// Remove the user so they can sign up again instead of being stuck
+		// with an account they can never activate
+		if err := app.store.Users.Delete(ctx, user.ID); err != nil {
+			app.logger.Errorw("Failed to delete user", "error", err)
+		}
+
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, user); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// sendVerificationEmail emails the link that activates the account.
+func (app *application) sendVerificationEmail(ctx context.Context, email, plainToken string) error {
+	verifyURL := fmt.Sprintf("%s/verify?token=%s", app.config.frontendURL, plainToken)
+	body := fmt.Sprintf("Welcome to Sportify!\n\n"+
+		"Open the link below to verify your email address. It expires in %s.\n\n%s", app.config.auth.invitationExp, verifyURL)
+
+	return app.mailer.Send(ctx, email, "Verify your Sportify account", body)
+}
+
+type ResendVerificationPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// resendVerificationHandler godoc
+//
+//	@Summary		Resends the verification email
+//	@Description	Emails a new verification link if an inactive account exists for the email. Earlier links stop working. Always responds with 200 so the endpoint cannot be used to discover accounts.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ResendVerificationPayload	true	"Account email"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/resend-verification [post]
+func (app *application) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResendVerificationPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	response := map[string]string{
+		"message": "If an unverified account exists for that email, a new verification link has been sent.",
+	}
+
+	ctx := r.Context()
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	switch err {
+	case nil:
+		if user.IsActive {
+			break
+		}
+		// Like password resets, failures are logged rather than told apart
+		// from the response for unknown emails
+		if err := app.resendVerification(ctx, user); err != nil {
+			app.logger.Errorw("Failed to resend verification email", "user_id", user.ID, "error", err)
+		} else {
+			app.logger.Infow("Resent verification email", "user_id", user.ID)
+		}
+	case store.ErrNotFound:
+	default:
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// resendVerification replaces the invitation of the inactive user and emails
+// them the new link.
+func (app *application) resendVerification(ctx context.Context, user *store.User) error {
+	plainToken, hashToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	if err := app.store.Users.Reinvite(ctx, user.ID, hashToken, app.config.auth.invitationExp); err != nil {
+		return err
+	}
+
+	return app.sendVerificationEmail(ctx, user.Email, plainToken)
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// verifyEmailHandler godoc
+//
+//	@Summary		Verifies a user's email
+//	@Description	Activates the account using the token from the verification email and logs the user in
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		VerifyEmailPayload	true	"Verification token"
+//	@Success		200		{object}	AuthTokens
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/verify [post]
+func (app *application) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var payload VerifyEmailPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.store.Users.Activate(r.Context(), auth.HashToken(payload.Token))
+	if err != nil {
+		switch err {
+		case store.ErrInvalidInvitation:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("Verified user email", "user_id", user.ID)
+
+	tokens, err := app.issueTokens(r.Context(), user)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, token); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -112,8 +255,9 @@ type LoginPayload struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		LoginPayload	true	"User credentials"
-//	@Success		200		{string}	string			"Token"
+//	@Success		200		{object}	AuthTokens
 //	@Failure		400		{object}	error
+//	@Failure		403		{object}	error	"Account not verified"
 //	@Failure		500		{object}	error
 //	@Router			/auth/login [post]
 func (app *application) userLoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -152,27 +296,34 @@ func (app *application) userLoginHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Generate a jwt token
-	token, err := app.createJwtToken(user.ID)
+	if !user.IsActive {
+		app.inactiveAccountResponse(w, r)
+		return
+	}
+
+	// Generate the access and refresh tokens
+	tokens, err := app.issueTokens(ctx, user)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, token); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
-func (app *application) createJwtToken(userID int64) (string, error) {
+func (app *application) createJwtToken(user *store.User) (string, error) {
 	// genarate the token -> add claims
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(app.config.auth.token.exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": app.config.auth.token.iss,
-		"aud": app.config.auth.token.iss,
+		"sub":  user.ID,
+		"ver":  user.TokenVersion,
+		"role": user.Role,
+		"exp":  time.Now().Add(app.config.auth.token.exp).Unix(),
+		"iat":  time.Now().Unix(),
+		"nbf":  time.Now().Unix(),
+		"iss":  app.config.auth.token.iss,
+		"aud":  app.config.auth.token.iss,
 	}
 	token, err := app.authenticator.GenerateToken(claims)
 	if err != nil {
@@ -182,6 +333,291 @@ func (app *application) createJwtToken(userID int64) (string, error) {
 	return token, nil
 }
 
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// forgotPasswordHandler godoc
+//
+//	@Summary		Requests a password reset
+//	@Description	Emails a single-use password reset link if an account exists for the email. Always responds with 200 so the endpoint cannot be used to discover accounts.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ForgotPasswordPayload	true	"Account email"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/forgot-password [post]
+func (app *application) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForgotPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	response := map[string]string{
+		"message": "If an account exists for that email, a reset link has been sent.",
+	}
+
+	ctx := r.Context()
+	user, err := app.store.Users.GetByEmail(ctx, payload.Email)
+	switch err {
+	case nil:
+		// Failures only happen for existing accounts, so they are logged
+		// rather than told apart from the response for unknown emails
+		if err := app.sendPasswordReset(ctx, user); err != nil {
+			app.logger.Errorw("Failed to send password reset email", "user_id", user.ID, "error", err)
+		} else {
+			app.logger.Infow("Sent password reset email", "user_id", user.ID)
+		}
+	case store.ErrNotFound:
+	default:
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// sendPasswordReset creates a reset token for the user and emails them the
+// link to use it.
+func (app *application) sendPasswordReset(ctx context.Context, user *store.User) error {
+	plainToken, hashToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	if err := app.store.PasswordResets.Create(ctx, user.ID, hashToken, app.config.auth.passwordResetExp); err != nil {
+		return err
+	}
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", app.config.frontendURL, plainToken)
+	body := fmt.Sprintf("We received a request to reset your Sportify password.\n\n"+
+		"Open the link below to choose a new one. It expires in %s and can only be used once.\n\n%s\n\n"+
+		"If you did not ask for this, you can ignore this email.", app.config.auth.passwordResetExp, resetURL)
+
+	return app.mailer.Send(ctx, user.Email, "Reset your Sportify password", body)
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// resetPasswordHandler godoc
+//
+//	@Summary		Resets a password
+//	@Description	Sets a new password using a token from the reset email. The token can only be used once and every previously issued JWT of the user stops working.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		200		{object}	AuthTokens
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/reset-password [post]
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ResetPasswordPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := &store.User{}
+	if err := user.Password.Set(payload.Password); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	err := app.store.PasswordResets.Reset(r.Context(), auth.HashToken(payload.Token), user)
+	if err != nil {
+		switch err {
+		case store.ErrInvalidResetToken:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("Password reset", "user_id", user.ID)
+
+	// Hand out fresh tokens since every older one was just revoked
+	tokens, err := app.issueTokens(r.Context(), user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type AuthTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueTokens starts a new login session for the user: a short lived access
+// token plus the first refresh token of a new family.
+func (app *application) issueTokens(ctx context.Context, user *store.User) (*AuthTokens, error) {
+	token, err := app.createJwtToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	plainToken, hashToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := &store.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken,
+		ExpiresAt: time.Now().Add(app.config.auth.refreshExp),
+	}
+	if err := app.store.RefreshTokens.Create(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &AuthTokens{Token: token, RefreshToken: plainToken}, nil
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refreshes the access token
+//	@Description	Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can only be used once; replaying one revokes the whole session.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		200		{object}	AuthTokens
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	plainToken, hashToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	next := &store.RefreshToken{
+		TokenHash: hashToken,
+		ExpiresAt: time.Now().Add(app.config.auth.refreshExp),
+	}
+	err = app.store.RefreshTokens.Rotate(ctx, auth.HashToken(payload.RefreshToken), next)
+	if err != nil {
+		switch err {
+		case store.ErrRefreshTokenReused:
+			app.logger.Warnw("Refresh token reused, session revoked")
+			app.unauthorizedErrorResponse(w, r, err)
+		case store.ErrInvalidRefreshToken:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.store.Users.GetByID(ctx, next.UserID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			app.unauthorizedErrorResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !user.IsActive {
+		app.inactiveAccountResponse(w, r)
+		return
+	}
+
+	token, err := app.createJwtToken(user)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tokens := &AuthTokens{Token: token, RefreshToken: plainToken}
+	if err := app.jsonResponse(w, http.StatusOK, tokens); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// logoutHandler godoc
+//
+//	@Summary		Logs out
+//	@Description	Revokes the refresh token and every token rotated from the same login. The current access token stays valid until it expires.
+//	@Tags			authentication
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/auth/logout [post]
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshTokenPayload
+
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.RefreshTokens.RevokeFamily(r.Context(), auth.HashToken(payload.RefreshToken)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]string{"message": "You have been logged out."}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
 func getUserFromContext(r *http.Request) *store.User {
 	user, _ := r.Context().Value(userCtx).(*store.User)
 	return user
@@ -220,17 +656,17 @@ func (app *application) googleCallbackHandler(w http.ResponseWriter, r *http.Req
 
 	app.logger.Infow("Created/updated user", "user_id", user.ID, "is_new", isNewUser)
 
-	// Generate JWT token
-	token, err := app.createJwtToken(user.ID)
+	// Generate JWT and refresh tokens
+	tokens, err := app.issueTokens(r.Context(), user)
 	if err != nil {
-		app.logger.Errorw("Failed to create JWT token", "error", err)
+		app.logger.Errorw("Failed to create tokens", "error", err)
 		app.internalServerError(w, r, err)
 		return
 	}
 
 	// Redirect back to frontend with token and isNewUser flag
 	frontendURL := "http://localhost:3000/auth/google/callback"
-	redirectURL := fmt.Sprintf("%s?token=%s&isNewUser=%v", frontendURL, token, isNewUser)
+	redirectURL := fmt.Sprintf("%s?token=%s&refresh_token=%s&isNewUser=%v", frontendURL, tokens.Token, tokens.RefreshToken, isNewUser)
 	app.logger.Infow("Redirecting to frontend", "url", redirectURL)
 	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
 }
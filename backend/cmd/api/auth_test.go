@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/MishNia/Sportify.git/internal/auth"
+	"github.com/MishNia/Sportify.git/internal/mailer"
 	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -121,3 +128,290 @@ func TestGoogleCallbackHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestForgotPasswordHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		outbox         io.Writer
+		expectedStatus int
+		expectedMail   bool
+	}{
+		{
+			name:           "known email",
+			body:           `{"email": "player@example.com"}`,
+			outbox:         &bytes.Buffer{},
+			expectedStatus: http.StatusOK,
+			expectedMail:   true,
+		},
+		{
+			name:           "unknown email does not leak",
+			body:           `{"email": "nobody@example.com"}`,
+			outbox:         &bytes.Buffer{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "mail failure does not leak",
+			body:           `{"email": "player@example.com"}`,
+			outbox:         failingWriter{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid email",
+			body:           `{"email": "not-an-email"}`,
+			outbox:         &bytes.Buffer{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			app.mailer = mailer.NewLogMailer(tt.outbox, "no-reply@sportify.test")
+			router := app.mount()
+
+			req := httptest.NewRequest("POST", "/v1/auth/forgot-password", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			// Every outcome looks the same to the caller
+			if tt.expectedStatus == http.StatusOK {
+				assert.JSONEq(t, `{"data": {"message": "If an account exists for that email, a reset link has been sent."}}`, rec.Body.String())
+			}
+			if outbox, ok := tt.outbox.(*bytes.Buffer); ok {
+				assert.Equal(t, tt.expectedMail, strings.Contains(outbox.String(), "/reset-password?token="))
+			}
+		})
+	}
+}
+
+func TestResendVerificationHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedMail   bool
+	}{
+		{name: "unverified account", body: `{"email": "pending@example.com"}`, expectedStatus: http.StatusOK, expectedMail: true},
+		{name: "verified account", body: `{"email": "player@example.com"}`, expectedStatus: http.StatusOK},
+		{name: "unknown email does not leak", body: `{"email": "nobody@example.com"}`, expectedStatus: http.StatusOK},
+		{name: "invalid email", body: `{"email": "not-an-email"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication()
+			var outbox bytes.Buffer
+			app.mailer = mailer.NewLogMailer(&outbox, "no-reply@sportify.test")
+			router := app.mount()
+
+			req := httptest.NewRequest("POST", "/v1/auth/resend-verification", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.JSONEq(t, `{"data": {"message": "If an unverified account exists for that email, a new verification link has been sent."}}`, rec.Body.String())
+			}
+			assert.Equal(t, tt.expectedMail, strings.Contains(outbox.String(), "/verify?token="))
+		})
+	}
+}
+
+// failingWriter makes every email fail to send.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("smtp unavailable")
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	app := newTestApplication()
+	router := app.mount()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "valid token",
+			body:           `{"token": "valid-reset-token", "password": "newsecurepassword"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid token",
+			body:           `{"token": "used-or-expired", "password": "newsecurepassword"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "password too short",
+			body:           `{"token": "valid-reset-token", "password": "short"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/auth/reset-password", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data AuthTokens `json:"data"`
+				}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				assert.NotEmpty(t, response.Data.RefreshToken)
+
+				claims := jwt.MapClaims{}
+				_, _, err := jwt.NewParser().ParseUnverified(response.Data.Token, claims)
+				assert.NoError(t, err)
+				assert.Equal(t, float64(1), claims["ver"], "New token should carry the bumped version")
+			}
+		})
+	}
+}
+
+func TestTokenVersionMatches(t *testing.T) {
+	user := &store.User{ID: 1, TokenVersion: 1}
+
+	assert.True(t, tokenVersionMatches(jwt.MapClaims{"ver": float64(1)}, user))
+	assert.False(t, tokenVersionMatches(jwt.MapClaims{"ver": float64(0)}, user), "Tokens issued before a reset should be rejected")
+	assert.False(t, tokenVersionMatches(jwt.MapClaims{}, user), "Unversioned tokens should be rejected once the version moved")
+	assert.True(t, tokenVersionMatches(jwt.MapClaims{}, &store.User{ID: 2}), "Unversioned tokens are valid for untouched accounts")
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	app := newTestApplication()
+	router := app.mount()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "valid token",
+			body:           `{"token": "valid-verify-token"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid token",
+			body:           `{"token": "expired"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing token",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/auth/verify", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestInactiveAccountResponse(t *testing.T) {
+	app := newTestApplication()
+
+	req := httptest.NewRequest("GET", "/v1/events/all", nil)
+	rec := httptest.NewRecorder()
+
+	app.inactiveAccountResponse(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var response struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "account_inactive", response.Code)
+	assert.NotEmpty(t, response.Error)
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+	app := newTestApplication()
+	router := app.mount()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "valid refresh token",
+			body:           `{"refresh_token": "valid-refresh-token"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "reused refresh token",
+			body:           `{"refresh_token": "used-refresh-token"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown refresh token",
+			body:           `{"refresh_token": "unknown"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing refresh token",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/auth/refresh", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data AuthTokens `json:"data"`
+				}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				assert.NotEmpty(t, response.Data.Token)
+				assert.NotEmpty(t, response.Data.RefreshToken)
+				assert.NotEqual(t, "valid-refresh-token", response.Data.RefreshToken, "Refresh token should be rotated")
+			}
+		})
+	}
+}
+
+func TestLogoutHandler(t *testing.T) {
+	app := newTestApplication()
+	router := app.mount()
+
+	req := httptest.NewRequest("POST", "/v1/auth/logout", strings.NewReader(`{"refresh_token": "valid-refresh-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
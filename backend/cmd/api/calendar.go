@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/MishNia/Sportify.git/internal/auth"
+	"github.com/MishNia/Sportify.git/internal/ical"
+	"github.com/MishNia/Sportify.git/internal/store"
+)
+
+type CalendarFeedToken struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// getEventICalHandler godoc
+//
+//	@Summary		Export an event to iCalendar
+//	@Description	Returns the event as an .ics file that can be imported into any calendar app
+//	@Tags			events
+//	@Produce		text/calendar
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/ical [get]
+func (app *application) getEventICalHandler(w http.ResponseWriter, r *http.Request) {
+	event, ok := app.loadVisibleEvent(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, event.ID))
+	app.calendarResponse(w, r, event.Title, []*store.Event{event})
+}
+
+// getCalendarFeedHandler godoc
+//
+//	@Summary		Personal calendar feed
+//	@Description	Returns every event the user owns or joined as an iCalendar feed. Calendar apps can't send a JWT, so the feed is authenticated by the token from POST /users/me/calendar/token instead
+//	@Tags			users
+//	@Produce		text/calendar
+//	@Param			token	query		string	true	"Calendar feed token"
+//	@Success		200		{string}	string
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/users/me/calendar.ics [get]
+func (app *application) getCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	userID, err := app.store.CalendarFeeds.GetUserID(r.Context(), auth.HashToken(token))
+	if err != nil {
+		switch err {
+		case store.ErrInvalidFeedToken:
+			app.unauthorizedErrorResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	events, err := app.store.Events.GetByUser(r.Context(), userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.calendarResponse(w, r, "Sportify", events)
+}
+
+// createCalendarFeedTokenHandler godoc
+//
+//	@Summary		Create a calendar feed token
+//	@Description	Issues the token used to subscribe to the personal calendar feed. Any previous token stops working
+//	@Tags			users
+//	@Produce		json
+//	@Success		201	{object}	CalendarFeedToken
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/calendar/token [post]
+func (app *application) createCalendarFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.CalendarFeeds.Rotate(r.Context(), user.ID, hash); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	feed := CalendarFeedToken{
+		Token: plain,
+		URL:   fmt.Sprintf("webcal://%s/v1/users/me/calendar.ics?token=%s", app.apiHost(), plain),
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, feed); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// revokeCalendarFeedTokenHandler godoc
+//
+//	@Summary		Revoke the calendar feed token
+//	@Description	Stops every calendar app subscribed to the personal feed from receiving updates
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{object}	map[string]string
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/me/calendar/token [delete]
+func (app *application) revokeCalendarFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	if err := app.store.CalendarFeeds.Revoke(r.Context(), user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]string{"message": "Your calendar feed has been revoked"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) calendarResponse(w http.ResponseWriter, r *http.Request, name string, events []*store.Event) {
+	// Event UIDs only need to be unique to this deployment
+	domain, _, _ := strings.Cut(app.apiHost(), ":")
+
+	cal := &ical.Calendar{Name: name, Domain: domain, Events: events}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := cal.WriteTo(w); err != nil {
+		app.logger.Errorw("failed to write calendar", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	}
+}
+
+// apiHost returns the external host of the API, without a scheme.
+func (app *application) apiHost() string {
+	host := strings.TrimPrefix(app.config.apiURL, "https://")
+	return strings.TrimPrefix(host, "http://")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventICalHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		expectedStatus int
+	}{
+		{
+			name:           "existing event",
+			eventID:        "1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid event ID",
+			eventID:        "invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "private event",
+			eventID:        "7",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("GET", "", 2, map[string]string{"id": tt.eventID})
+
+			w := httptest.NewRecorder()
+			app.getEventICalHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Contains(t, w.Header().Get("Content-Disposition"), "event-1.ics")
+				assert.Contains(t, w.Body.String(), "UID:event-1@localhost\r\n")
+				assert.Contains(t, w.Body.String(), "SUMMARY:Test Event\r\n")
+			}
+		})
+	}
+}
+
+func TestGetCalendarFeedHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "valid token",
+			token:          "valid-feed-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "revoked token",
+			token:          "revoked-feed-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/users/me/calendar.ics?token="+tt.token, nil)
+
+			w := httptest.NewRecorder()
+			app.getCalendarFeedHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				body := w.Body.String()
+				assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n"))
+				assert.Equal(t, 1, strings.Count(body, "BEGIN:VEVENT"))
+				assert.Contains(t, body, "SUMMARY:Morning Soccer\r\n")
+			}
+		})
+	}
+}
+
+func TestCalendarFeedTokenHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	t.Run("create token", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/users/me/calendar/token", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: 1}))
+
+		w := httptest.NewRecorder()
+		app.createCalendarFeedTokenHandler(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response struct {
+			Data CalendarFeedToken `json:"data"`
+		}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.NotEmpty(t, response.Data.Token)
+		assert.Equal(t, "webcal://localhost:8080/v1/users/me/calendar.ics?token="+response.Data.Token, response.Data.URL)
+	})
+
+	t.Run("revoke token", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/users/me/calendar/token", nil)
+		req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: 1}))
+
+		w := httptest.NewRecorder()
+		app.revokeCalendarFeedTokenHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/users/me/calendar/token", nil)
+
+		w := httptest.NewRecorder()
+		app.createCalendarFeedTokenHandler(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/MishNia/Sportify.git/internal/websocket"
+	"github.com/go-chi/chi/v5"
+)
+
+// getEventMessagesHandler godoc
+//
+//	@Summary		Get event chat history
+//	@Description	Returns a page of chat messages of an event in chronological order. Pass the id of the oldest message you have as before to load earlier history.
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int	true	"Event ID"
+//	@Param			before	query		int	false	"Only return messages with an id lower than this"
+//	@Param			limit	query		int	false	"Page size (default 50, max 100)"
+//	@Success		200		{array}		websocket.Message
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/messages [get]
+func (app *application) getEventMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	var before int64
+	if v := r.URL.Query().Get("before"); v != "" {
+		before, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 0 {
+			app.badRequestResponse(w, r, errors.New("invalid before"))
+			return
+		}
+	}
+
+	limit := store.DefaultMessagePageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			app.badRequestResponse(w, r, errors.New("invalid limit"))
+			return
+		}
+	}
+
+	event, err := app.store.Events.GetByID(r.Context(), eventID)
+	if err != nil {
+		if err == store.ErrEventNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Only participants can read the chat, same as the websocket endpoint
+	if findParticipant(event, user.ID) == nil {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	stored, err := app.store.Chat.GetByEvent(r.Context(), eventID, before, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	messages := make([]websocket.Message, 0, len(stored))
+	for _, m := range stored {
+		messages = append(messages, websocket.NewMessage(m))
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+type EditMessagePayload struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+// MuteUserPayload mutes a participant for up to a week.
+type MuteUserPayload struct {
+	UserID  int64 `json:"user_id" validate:"required,gt=0"`
+	Minutes int   `json:"minutes" validate:"required,gt=0,max=10080"`
+}
+
+// KickUserPayload keeps a participant out of the chat for 15 minutes by
+// default, and up to a day.
+type KickUserPayload struct {
+	UserID  int64 `json:"user_id" validate:"required,gt=0"`
+	Minutes int   `json:"minutes" validate:"omitempty,gt=0,max=1440"`
+}
+
+const defaultKickDuration = 15 * time.Minute
+
+// editEventMessageHandler godoc
+//
+//	@Summary		Edit a chat message
+//	@Description	Replaces the content of one of your own chat messages. Muted users can't edit their messages. The room is sent a message_edited event.
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Event ID"
+//	@Param			messageID	path		int					true	"Message ID"
+//	@Param			payload		body		EditMessagePayload	true	"New content"
+//	@Success		200			{object}	websocket.Message
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error	"Not the author of the message, or muted"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/messages/{messageID} [put]
+func (app *application) editEventMessageHandler(w http.ResponseWriter, r *http.Request) {
+	var payload EditMessagePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	_, user, msg, ok := app.loadChatMessage(w, r)
+	if !ok {
+		return
+	}
+
+	if msg.UserID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	msg.Content = payload.Content
+	if err := app.store.Chat.Edit(r.Context(), msg, user.ID); err != nil {
+		switch err {
+		case store.ErrMessageNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrMuted:
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	message := websocket.NewMessage(msg)
+	app.hub.Broadcast(&websocket.Envelope{Type: websocket.TypeMessageEdited, EventID: msg.EventID, Message: &message})
+
+	if err := app.jsonResponse(w, http.StatusOK, message); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteEventMessageHandler godoc
+//
+//	@Summary		Delete a chat message
+//	@Description	Deletes one of your own chat messages, or any message of an event you organize. The room is sent a message_deleted event.
+//	@Tags			events
+//	@Produce		json
+//	@Param			id			path		int	true	"Event ID"
+//	@Param			messageID	path		int	true	"Message ID"
+//	@Success		200			{object}	map[string]string
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error	"Neither the author nor an organizer of the event"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/messages/{messageID} [delete]
+func (app *application) deleteEventMessageHandler(w http.ResponseWriter, r *http.Request) {
+	event, user, msg, ok := app.loadChatMessage(w, r)
+	if !ok {
+		return
+	}
+
+	if msg.UserID != user.ID && !can(event, user.ID, permModerateChat) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.store.Chat.Delete(r.Context(), msg, user.ID); err != nil {
+		if err == store.ErrMessageNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.hub.Broadcast(&websocket.Envelope{Type: websocket.TypeMessageDeleted, EventID: msg.EventID, MessageID: msg.ID})
+
+	response := map[string]string{"message": "Message deleted"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// muteChatUserHandler godoc
+//
+//	@Summary		Mute a participant
+//	@Description	Stops a participant from sending chat messages for the given number of minutes, up to a week. Only the event owner and co-organizers can mute, and co-organizers only plain participants. The room is sent a user_muted event.
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Event ID"
+//	@Param			payload	body		MuteUserPayload	true	"Who to mute and for how long"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error	"Invalid payload or the user isn't a participant"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not an organizer of the event"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/chat/mutes [post]
+func (app *application) muteChatUserHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MuteUserPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, target, ok := app.loadModerationTarget(w, r, payload.UserID)
+	if !ok {
+		return
+	}
+
+	until := time.Now().Add(time.Duration(payload.Minutes) * time.Minute)
+	if err := app.store.Chat.Mute(r.Context(), event.ID, target.UserID, user.ID, until); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.hub.Broadcast(&websocket.Envelope{
+		Type:    websocket.TypeUserMuted,
+		EventID: event.ID,
+		User:    presenceUser(target),
+		Until:   until.UTC().Format(time.RFC3339),
+	})
+
+	response := map[string]string{"message": "User muted"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// unmuteChatUserHandler godoc
+//
+//	@Summary		Unmute a participant
+//	@Description	Lets a muted participant send chat messages again. Only the event owner and co-organizers can unmute. The room is sent a user_unmuted event.
+//	@Tags			events
+//	@Produce		json
+//	@Param			id		path		int	true	"Event ID"
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not an organizer of the event"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/chat/mutes/{userID} [delete]
+func (app *application) unmuteChatUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, target, ok := app.loadModerationTarget(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Chat.Unmute(r.Context(), event.ID, target.UserID, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.hub.Broadcast(&websocket.Envelope{Type: websocket.TypeUserUnmuted, EventID: event.ID, User: presenceUser(target)})
+
+	response := map[string]string{"message": "User unmuted"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// kickChatUserHandler godoc
+//
+//	@Summary		Kick a participant from the chat
+//	@Description	Closes every chat connection of a participant and keeps them from reconnecting for the given number of minutes, 15 by default. They stay a participant of the event. Only the event owner and co-organizers can kick, and co-organizers only plain participants. The room is sent a user_kicked event.
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Event ID"
+//	@Param			payload	body		KickUserPayload	true	"Who to kick and for how long"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error	"Invalid payload or the user isn't a participant"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not an organizer of the event"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/chat/kick [post]
+func (app *application) kickChatUserHandler(w http.ResponseWriter, r *http.Request) {
+	var payload KickUserPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, target, ok := app.loadModerationTarget(w, r, payload.UserID)
+	if !ok {
+		return
+	}
+
+	duration := defaultKickDuration
+	if payload.Minutes > 0 {
+		duration = time.Duration(payload.Minutes) * time.Minute
+	}
+	if err := app.store.Chat.Kick(r.Context(), event.ID, target.UserID, user.ID, time.Now().Add(duration)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.hub.Broadcast(&websocket.Envelope{Type: websocket.TypeUserKicked, EventID: event.ID, User: presenceUser(target)})
+
+	response := map[string]string{"message": "User kicked"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// canChat reports whether the user may connect to the event's chat: they must
+// be a participant who wasn't recently kicked.
+func (app *application) canChat(ctx context.Context, event *store.Event, userID int64) (bool, error) {
+	if findParticipant(event, userID) == nil {
+		return false, nil
+	}
+
+	kicked, err := app.store.Chat.IsKicked(ctx, event.ID, userID)
+	if err != nil {
+		return false, err
+	}
+	return !kicked, nil
+}
+
+// loadChatEvent returns the event of the request and the authenticated
+// user, who must be one of its participants. It writes the error response
+// and returns false otherwise.
+func (app *application) loadChatEvent(w http.ResponseWriter, r *http.Request) (*store.Event, *store.User, bool) {
+	event, user, ok := app.loadEvent(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if findParticipant(event, user.ID) == nil {
+		app.forbiddenResponse(w, r)
+		return nil, nil, false
+	}
+
+	return event, user, true
+}
+
+// loadChatMessage is loadChatEvent that also returns the message of the
+// request, which must belong to the event.
+func (app *application) loadChatMessage(w http.ResponseWriter, r *http.Request) (*store.Event, *store.User, *store.ChatMessage, bool) {
+	messageID, err := strconv.ParseInt(chi.URLParam(r, "messageID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, nil, nil, false
+	}
+
+	event, user, ok := app.loadChatEvent(w, r)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	msg, err := app.store.Chat.GetByID(r.Context(), messageID)
+	if err != nil {
+		if err == store.ErrMessageNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return nil, nil, nil, false
+	}
+
+	if msg.EventID != event.ID {
+		app.notFoundResponse(w, r, store.ErrMessageNotFound)
+		return nil, nil, nil, false
+	}
+
+	return event, user, msg, true
+}
+
+// loadModerationTarget is loadChatEvent for actions the event owner and
+// co-organizers can take against a participant they outrank, which it also
+// returns.
+func (app *application) loadModerationTarget(w http.ResponseWriter, r *http.Request, targetID int64) (*store.Event, *store.User, *store.EventParticipant, bool) {
+	event, user, ok := app.loadChatEvent(w, r)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	if !can(event, user.ID, permModerateChat) {
+		app.forbiddenResponse(w, r)
+		return nil, nil, nil, false
+	}
+
+	if !outranks(event, user.ID, targetID) {
+		app.badRequestResponse(w, r, errors.New("the event owner and co-organizers can't be moderated"))
+		return nil, nil, nil, false
+	}
+
+	target := findParticipant(event, targetID)
+	if target == nil {
+		app.badRequestResponse(w, r, errors.New("user is not a participant"))
+		return nil, nil, nil, false
+	}
+
+	return event, user, target, true
+}
+
+// findParticipant returns the participant of the event with the user ID, or
+// nil if the user hasn't joined it.
+func findParticipant(event *store.Event, userID int64) *store.EventParticipant {
+	for i := range event.Participants {
+		if event.Participants[i].UserID == userID {
+			return &event.Participants[i]
+		}
+	}
+	return nil
+}
+
+func presenceUser(p *store.EventParticipant) *websocket.PresenceUser {
+	return &websocket.PresenceUser{UserID: p.UserID, Username: p.FirstName + " " + p.LastName}
+}
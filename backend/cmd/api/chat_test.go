@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/MishNia/Sportify.git/internal/websocket"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEventMessagesHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		query          string
+		setupAuth      func(*http.Request)
+		expectedStatus int
+	}{
+		{
+			name:    "participant reads history",
+			eventID: "1",
+			query:   "?before=10&limit=20",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "not a participant",
+			eventID: "1",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 2}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:    "invalid before",
+			eventID: "1",
+			query:   "?before=abc",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "invalid limit",
+			eventID: "1",
+			query:   "?limit=0",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unauthorized",
+			eventID:        "1",
+			setupAuth:      func(r *http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events/"+tt.eventID+"/messages"+tt.query, nil)
+			tt.setupAuth(req)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.eventID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			app.getEventMessagesHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data []websocket.Message `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Data, 1)
+				assert.Equal(t, int64(1), response.Data[0].ID)
+				assert.Equal(t, "Hello", response.Data[0].Content)
+			}
+		})
+	}
+}
+
+// newChatRequest returns a request from the user, or an anonymous one if
+// userID is 0, with the given route params
+func newChatRequest(method, body string, userID int64, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	if userID != 0 {
+		ctx = context.WithValue(ctx, userCtx, &store.User{ID: userID})
+	}
+	return req.WithContext(ctx)
+}
+
+func TestEditEventMessageHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		messageID      string
+		userID         int64
+		body           string
+		expectedStatus int
+	}{
+		{name: "author edits", eventID: "5", messageID: "1", userID: 1, body: `{"content": "Hello all"}`, expectedStatus: http.StatusOK},
+		{name: "someone else's message", eventID: "5", messageID: "2", userID: 1, body: `{"content": "Hello all"}`, expectedStatus: http.StatusForbidden},
+		{name: "muted author", eventID: "5", messageID: "2", userID: 3, body: `{"content": "Hello all"}`, expectedStatus: http.StatusForbidden},
+		{name: "message of another event", eventID: "1", messageID: "1", userID: 1, body: `{"content": "Hello all"}`, expectedStatus: http.StatusNotFound},
+		{name: "unknown message", eventID: "5", messageID: "9", userID: 1, body: `{"content": "Hello all"}`, expectedStatus: http.StatusNotFound},
+		{name: "empty content", eventID: "5", messageID: "1", userID: 1, body: `{"content": ""}`, expectedStatus: http.StatusBadRequest},
+		{name: "not a participant", eventID: "5", messageID: "1", userID: 2, body: `{"content": "Hello all"}`, expectedStatus: http.StatusForbidden},
+		{name: "unauthorized", eventID: "5", messageID: "1", body: `{"content": "Hello all"}`, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("PUT", tt.body, tt.userID, map[string]string{"id": tt.eventID, "messageID": tt.messageID})
+			w := httptest.NewRecorder()
+			app.editEventMessageHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data websocket.Message `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, "Hello all", response.Data.Content)
+				assert.NotEmpty(t, response.Data.EditedAt)
+			}
+		})
+	}
+}
+
+func TestDeleteEventMessageHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		messageID      string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "author deletes", messageID: "2", userID: 3, expectedStatus: http.StatusOK},
+		{name: "owner deletes any message", messageID: "2", userID: 1, expectedStatus: http.StatusOK},
+		{name: "participant deletes someone else's message", messageID: "1", userID: 3, expectedStatus: http.StatusForbidden},
+		{name: "unknown message", messageID: "9", userID: 1, expectedStatus: http.StatusNotFound},
+		{name: "invalid message ID", messageID: "abc", userID: 1, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("DELETE", "", tt.userID, map[string]string{"id": "5", "messageID": tt.messageID})
+			w := httptest.NewRecorder()
+			app.deleteEventMessageHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestChatModerationHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		userID         int64
+		params         map[string]string
+		body           string
+		expectedStatus int
+		expectedAction string
+	}{
+		{
+			name:           "owner mutes participant",
+			handler:        app.muteChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 3, "minutes": 30}`,
+			expectedStatus: http.StatusOK,
+			expectedAction: store.ModerationUserMuted,
+		},
+		{
+			name:           "participant can't mute",
+			handler:        app.muteChatUserHandler,
+			userID:         3,
+			body:           `{"user_id": 1, "minutes": 30}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "owner can't be muted",
+			handler:        app.muteChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 1, "minutes": 30}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "mute non participant",
+			handler:        app.muteChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 2, "minutes": 30}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "mute longer than a week",
+			handler:        app.muteChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 3, "minutes": 10081}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "owner unmutes participant",
+			handler:        app.unmuteChatUserHandler,
+			userID:         1,
+			params:         map[string]string{"userID": "3"},
+			expectedStatus: http.StatusOK,
+			expectedAction: store.ModerationUserUnmuted,
+		},
+		{
+			name:           "owner kicks participant",
+			handler:        app.kickChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 3}`,
+			expectedStatus: http.StatusOK,
+			expectedAction: store.ModerationUserKicked,
+		},
+		{
+			name:           "kick longer than a day",
+			handler:        app.kickChatUserHandler,
+			userID:         1,
+			body:           `{"user_id": 3, "minutes": 1441}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "participant can't kick",
+			handler:        app.kickChatUserHandler,
+			userID:         3,
+			body:           `{"user_id": 1}`,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	chat := app.store.Chat.(*mockChatStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]string{"id": "5"}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+
+			chat.actions = nil
+			req := newChatRequest("POST", tt.body, tt.userID, params)
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedAction != "" {
+				assert.Equal(t, []string{tt.expectedAction}, chat.actions)
+			} else {
+				assert.Empty(t, chat.actions)
+			}
+		})
+	}
+}
+
+func TestCanChat(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name     string
+		userID   int64
+		expected bool
+	}{
+		{name: "participant", userID: 1, expected: true},
+		{name: "kicked participant", userID: 3, expected: false},
+		{name: "not a participant", userID: 2, expected: false},
+	}
+
+	event, _ := app.store.Events.GetByID(context.Background(), 5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := app.canChat(context.Background(), event, tt.userID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, allowed)
+		})
+	}
+}
@@ -44,6 +44,12 @@ func (app *application) unauthorizedErrorResponse(w http.ResponseWriter, r *http
 	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
 }
 
+func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
+	app.logger.Warnw("inactive account", "method", r.Method, "path", r.URL.Path)
+
+	writeJSONErrorWithCode(w, http.StatusForbidden, errCodeAccountInactive, "please verify your email address before continuing")
+}
+
 func (app *application) unauthorizedBasicErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Warnf("unauthorized basic error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 
@@ -65,3 +71,9 @@ func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Requ
 	message := "you must be authenticated to access this resource"
 	writeJSONError(w, http.StatusUnauthorized, message)
 }
+
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, fields map[string]string) {
+	app.logger.Warnw("failed validation", "method", r.Method, "path", r.URL.Path, "fields", fields)
+
+	writeJSONFieldErrors(w, http.StatusBadRequest, "invalid parameters", fields)
+}
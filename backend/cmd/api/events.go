@@ -1,7 +1,8 @@
 package main
 
 import (
-	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,41 +15,22 @@ import (
 // getEventHandler godoc
 //
 //	@Summary		Get event details
-//	@Description	Get details of a specific event including participants
+//	@Description	Get details of a specific event including participants. Private events are only shown to their participants and to players with an invite
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Event ID"
-//	@Success		200	{object}	store.Event
-//	@Failure		400	{object}	error
-//	@Failure		401	{object}	error
-//	@Failure		404	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int		true	"Event ID"
+//	@Param			invite	query		string	false	"Invite token of a private event"
+//	@Success		200		{object}	store.Event
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/events/{id} [get]
 func (app *application) getEventHandler(w http.ResponseWriter, r *http.Request) {
-	// Get event ID from URL
-	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
-	}
-
-	// Get the authenticated user
-	user := getUserFromContext(r)
-	if user == nil {
-		app.unauthorizedResponse(w, r)
-		return
-	}
-
-	// Get event details
-	event, err := app.store.Events.GetByID(r.Context(), eventID)
-	if err != nil {
-		if err == store.ErrEventNotFound {
-			app.notFoundResponse(w, r, err)
-		} else {
-			app.internalServerError(w, r, err)
-		}
+	event, ok := app.loadVisibleEvent(w, r)
+	if !ok {
 		return
 	}
 
@@ -58,14 +40,16 @@ func (app *application) getEventHandler(w http.ResponseWriter, r *http.Request)
 }
 
 type CreateEventPayload struct {
-	Sport        string    `json:"sport" validate:"required"`
-	EventDate    time.Time `json:"event_date" validate:"required"`
-	MaxPlayers   int       `json:"max_players" validate:"required,gt=0"`
-	LocationName string    `json:"location_name" validate:"required"`
-	Latitude     float64   `json:"latitude" validate:"required"`
-	Longitude    float64   `json:"longitude" validate:"required"`
-	Description  string    `json:"description"`
-	Title        string    `json:"title"`
+	Sport            string    `json:"sport" validate:"required"`
+	EventDate        time.Time `json:"event_date" validate:"required"`
+	MaxPlayers       int       `json:"max_players" validate:"required,gt=0"`
+	LocationName     string    `json:"location_name" validate:"required"`
+	Latitude         float64   `json:"latitude" validate:"required"`
+	Longitude        float64   `json:"longitude" validate:"required"`
+	Description      string    `json:"description"`
+	Title            string    `json:"title"`
+	Visibility       string    `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	RequiresApproval bool      `json:"requires_approval"`
 }
 
 // createEventHandler godoc
@@ -103,16 +87,18 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	event := &store.Event{
-		EventOwner:    user.ID,
-		Sport:         payload.Sport,
-		EventDateTime: payload.EventDate,
-		MaxPlayers:    payload.MaxPlayers,
-		LocationName:  payload.LocationName,
-		Latitude:      payload.Latitude,
-		Longitude:     payload.Longitude,
-		Description:   payload.Description,
-		Title:         payload.Title,
-		IsFull:        false,
+		EventOwner:       user.ID,
+		Sport:            payload.Sport,
+		EventDateTime:    payload.EventDate,
+		MaxPlayers:       payload.MaxPlayers,
+		LocationName:     payload.LocationName,
+		Latitude:         payload.Latitude,
+		Longitude:        payload.Longitude,
+		Description:      payload.Description,
+		Title:            payload.Title,
+		Visibility:       payload.Visibility,
+		RequiresApproval: payload.RequiresApproval,
+		IsFull:           false,
 	}
 
 	if err := app.store.Events.Create(r.Context(), event); err != nil {
@@ -133,20 +119,56 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 }
 
 type UpdateEventPayload struct {
-	Sport        *string    `json:"sport"`
-	EventDate    *time.Time `json:"event_date"`
-	MaxPlayers   *int       `json:"max_players" validate:"gt=0"`
-	LocationName *string    `json:"location_name"`
-	Latitude     *float64   `json:"latitude"`
-	Longitude    *float64   `json:"longitude"`
-	Description  *string    `json:"description"`
-	Title        *string    `json:"title"`
+	Sport            *string    `json:"sport"`
+	EventDate        *time.Time `json:"event_date"`
+	MaxPlayers       *int       `json:"max_players" validate:"omitempty,gt=0"`
+	LocationName     *string    `json:"location_name"`
+	Latitude         *float64   `json:"latitude"`
+	Longitude        *float64   `json:"longitude"`
+	Description      *string    `json:"description"`
+	Title            *string    `json:"title"`
+	Visibility       *string    `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	RequiresApproval *bool      `json:"requires_approval"`
+}
+
+// apply copies every field set in the payload onto event.
+func (payload *UpdateEventPayload) apply(event *store.Event) {
+	if payload.Sport != nil {
+		event.Sport = *payload.Sport
+	}
+	if payload.EventDate != nil {
+		event.EventDateTime = *payload.EventDate
+	}
+	if payload.MaxPlayers != nil {
+		event.MaxPlayers = *payload.MaxPlayers
+	}
+	if payload.LocationName != nil {
+		event.LocationName = *payload.LocationName
+	}
+	if payload.Latitude != nil {
+		event.Latitude = *payload.Latitude
+	}
+	if payload.Longitude != nil {
+		event.Longitude = *payload.Longitude
+	}
+	if payload.Title != nil {
+		event.Title = *payload.Title
+	}
+	if payload.Description != nil {
+		event.Description = *payload.Description
+	}
+	if payload.Visibility != nil {
+		event.Visibility = *payload.Visibility
+	}
+	if payload.RequiresApproval != nil {
+		event.RequiresApproval = *payload.RequiresApproval
+	}
 }
 
 // updateEventHandler godoc
 //
 //	@Summary		Update an event
-//	@Description	Update event details (partial or full). Only owner can update.
+//	@Description	Update event details (partial or full). Only the owner and co-organizers can update.
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
@@ -199,63 +221,57 @@ func (app *application) updateEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Check if user is the event owner
-	if user.ID != event.EventOwner {
+	if !can(event, user.ID, permUpdateEvent) {
 		app.forbiddenResponse(w, r)
 		return
 	}
 
-	// Optional updates
-	if payload.Sport != nil {
-		event.Sport = *payload.Sport
-	}
-	if payload.EventDate != nil {
-		event.EventDateTime = *payload.EventDate
-	}
-	if payload.MaxPlayers != nil {
-		event.MaxPlayers = *payload.MaxPlayers
-	}
-	if payload.LocationName != nil {
-		event.LocationName = *payload.LocationName
-	}
-	if payload.Latitude != nil {
-		event.Latitude = *payload.Latitude
-	}
-	if payload.Longitude != nil {
-		event.Longitude = *payload.Longitude
-	}
-	if payload.Title != nil {
-		event.Title = *payload.Title
-	}
-	if payload.Description != nil {
-		event.Description = *payload.Description
+	if event.Status != store.EventStatusScheduled {
+		app.conflictResponse(w, r, store.ErrEventNotScheduled)
+		return
 	}
+
+	// Optional updates
+	payload.apply(event)
 	event.UpdatedAt = time.Now()
 
 	if err := app.store.Events.Update(ctx, event); err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
+	app.notifyParticipants(ctx, event, store.NotificationEventUpdated, fmt.Sprintf("%q has been updated", eventName(event)))
+
 	if err := app.jsonResponse(w, http.StatusOK, event); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+type CancelEventPayload struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
 // deleteEventHandler godoc
 //
-//	@Summary		Delete an event
-//	@Description	Event owner deletes their event (including all participants)
+//	@Summary		Cancel an event
+//	@Description	Event owner cancels their event. The event stays readable with status cancelled, is left out of listings and every participant is notified
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Event ID"
-//	@Success		200	{object}	map[string]string
-//	@Failure		400	{object}	error	"Invalid ID"
-//	@Failure		401	{object}	error	"Unauthorized"
-//	@Failure		403	{object}	error	"User is not the event owner"
-//	@Failure		404	{object}	error	"Event not found"
-//	@Failure		500	{object}	error	"Internal server error"
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			payload	body		CancelEventPayload	false	"Why the event was cancelled"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error	"Invalid ID"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"User is not the event owner"
+//	@Failure		404		{object}	error	"Event not found"
+//	@Failure		409		{object}	error	"Event is already cancelled or over"
+//	@Failure		500		{object}	error	"Internal server error"
 //	@Security		ApiKeyAuth
 //	@Router			/events/{id} [delete]
 func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -265,6 +281,12 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	reason, err := readCancelReason(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	// Get the authenticated user
 	user := getUserFromContext(r)
 	if user == nil {
@@ -282,42 +304,68 @@ func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Check if user is the event owner
-	if user.ID != event.EventOwner {
+	if !can(event, user.ID, permDeleteEvent) {
 		app.forbiddenResponse(w, r)
 		return
 	}
 
-	err = app.store.Events.Delete(r.Context(), eventID)
+	notifications, err := app.store.Events.Cancel(r.Context(), eventID, reason)
 	if err != nil {
-		if err == store.ErrEventNotFound {
+		switch err {
+		case store.ErrEventNotFound:
 			app.notFoundResponse(w, r, err)
-		} else {
+		case store.ErrEventNotScheduled:
+			app.conflictResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
-	response := map[string]string{"message": "You have successfully deleted the event!"}
+	app.pushNotifications(notifications)
+
+	response := map[string]string{"message": "You have successfully cancelled the event!"}
 	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// readCancelReason reads the optional CancelEventPayload. DELETE requests
+// usually have no body, so an empty one is accepted.
+func readCancelReason(w http.ResponseWriter, r *http.Request) (string, error) {
+	var payload CancelEventPayload
+	if err := readJSON(w, r, &payload); err != nil && err != io.EOF {
+		return "", err
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		return "", err
+	}
+
+	return payload.Reason, nil
+}
+
+type JoinEventPayload struct {
+	InviteToken string `json:"invite_token"`
+}
+
 // joinEventHandler godoc
 //
 //	@Summary		Join an event
-//	@Description	Allows a user to join an existing event
+//	@Description	Allows a user to join an existing event. If the event is full the user is put on its waitlist instead. Unlisted and private events take an invite token. Events that require approval record a join request for the organizers to answer. Players who were declined wait a day before asking again
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Event ID"
-//	@Success		200	{object}	map[string]string
-//	@Failure		400	{object}	error
-//	@Failure		401	{object}	error
-//	@Failure		404	{object}	error
-//	@Failure		409	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			payload	body		JoinEventPayload	false	"Invite to an unlisted or private event"
+//	@Success		200		{object}	map[string]string
+//	@Success		202		{object}	map[string]any	"Put on the waitlist or join request sent"
+//	@Failure		400		{object}	error	"Invalid, expired or used up invite"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"The event takes an invite"
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/events/{id}/join [post]
 func (app *application) joinEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -328,6 +376,12 @@ func (app *application) joinEventHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var payload JoinEventPayload
+	if err := readJSON(w, r, &payload); err != nil && err != io.EOF {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	// Get authenticated user
 	user := getUserFromContext(r)
 	if user == nil {
@@ -335,33 +389,42 @@ func (app *application) joinEventHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get event details
-	event, err := app.store.Events.GetByID(r.Context(), eventID)
-	if err != nil {
-		if err == store.ErrEventNotFound {
-			app.notFoundResponse(w, r, err)
-		} else {
-			app.internalServerError(w, r, err)
+	var inviteHash string
+	if payload.InviteToken != "" {
+		if inviteHash, err = app.inviteHash(eventID, payload.InviteToken); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
 		}
-		return
-	}
-
-	// Check if event is full
-	if event.IsFull {
-		app.conflictResponse(w, r, errors.New("event is already full"))
-		return
 	}
 
-	// Join the event
-	if err := app.store.Events.Join(r.Context(), eventID, user.ID); err != nil {
-		if err == store.ErrAlreadyJoined {
+	// Join the event, or queue the user if there is no spot left
+	if err := app.store.Events.Join(r.Context(), eventID, user.ID, inviteHash); err != nil {
+		switch err {
+		case store.ErrEventFull:
+			app.joinWaitlist(w, r, eventID, user, inviteHash)
+		case store.ErrApprovalRequired:
+			app.requestJoin(w, r, eventID, user, inviteHash)
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrAlreadyJoined, store.ErrEventNotScheduled:
 			app.conflictResponse(w, r, err)
-		} else {
+		case store.ErrInviteRequired:
+			app.forbiddenResponse(w, r)
+		case store.ErrInvalidInvite:
+			app.badRequestResponse(w, r, err)
+		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
+	app.joined(w, r, eventID, user)
+}
+
+// joined lets the owner know the user joined their event and tells the user.
+func (app *application) joined(w http.ResponseWriter, r *http.Request, eventID int64, user *store.User) {
+	app.notifyOwner(r.Context(), eventID, user, store.NotificationEventJoined, "joined")
+
 	// Return success response
 	response := map[string]string{
 		"message": "You have successfully joined the event!",
@@ -414,6 +477,8 @@ func (app *application) leaveEventHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.notifyOwner(r.Context(), eventID, user, store.NotificationEventLeft, "left")
+
 	// Return success response
 	response := map[string]string{
 		"message": "You have successfully left the event!",
@@ -432,30 +497,104 @@ type EventFilterPayload struct {
 	AfterDate    *string  `json:"after_date"`  // RFC3339 string
 	BeforeDate   *string  `json:"before_date"` // RFC3339 string
 	LocationName *string  `json:"location_name"`
-	SortBy       *string  `json:"sort_by"` // event_datetime, created_at, etc.
+	Status       *string  `json:"status"` // scheduled, cancelled, completed
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	RadiusKm     *float64 `json:"radius_km"`
+	SortBy       *string  `json:"sort_by"` // event_datetime, created_at, registered_count, distance
 	Order        *string  `json:"order"`   // asc, desc
+	Limit        *int     `json:"limit"`
+	Cursor       *string  `json:"cursor"`
+	IncludeTotal *bool    `json:"include_total"`
 }
 
-// getAllEventsHandler godoc
-//
-//	@Summary		Get all events with filters
-//	@Description	Returns a list of events filtered by criteria provided in the request body
-//	@Tags			events
-//	@Accept			json
-//	@Produce		json
-//	@Param			filter	body		EventFilterPayload	true	"Event filtering criteria"
-//	@Success		200		{array}		store.Event
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
-//	@Security		ApiKeyAuth
-//	@Router			/events [get]
-func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Request) {
-	var payload EventFilterPayload
-	if err := readJSON(w, r, &payload); err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+// readEventFilterQuery reads the event filters from the URL query. Values that
+// can't be parsed are reported per field.
+func readEventFilterQuery(r *http.Request) (*EventFilterPayload, map[string]string) {
+	q := r.URL.Query()
+	payload := &EventFilterPayload{}
+	fieldErrors := map[string]string{}
+
+	parseInt64 := func(field string) *int64 {
+		if !q.Has(field) {
+			return nil
+		}
+		v, err := strconv.ParseInt(q.Get(field), 10, 64)
+		if err != nil {
+			fieldErrors[field] = "must be an integer"
+			return nil
+		}
+		return &v
+	}
+	parseInt := func(field string) *int {
+		if v := parseInt64(field); v != nil {
+			i := int(*v)
+			return &i
+		}
+		return nil
+	}
+	parseFloat := func(field string) *float64 {
+		if !q.Has(field) {
+			return nil
+		}
+		v, err := strconv.ParseFloat(q.Get(field), 64)
+		if err != nil {
+			fieldErrors[field] = "must be a number"
+			return nil
+		}
+		return &v
+	}
+	parseBool := func(field string) *bool {
+		if !q.Has(field) {
+			return nil
+		}
+		v, err := strconv.ParseBool(q.Get(field))
+		if err != nil {
+			fieldErrors[field] = "must be true or false"
+			return nil
+		}
+		return &v
+	}
+	parseString := func(field string) *string {
+		if !q.Has(field) {
+			return nil
+		}
+		v := q.Get(field)
+		return &v
+	}
+
+	payload.ID = parseInt64("id")
+	payload.MaxPlayers = parseInt("max_players")
+	payload.EventOwner = parseInt64("event_owner")
+	payload.IsFull = parseBool("is_full")
+	payload.AfterDate = parseString("after_date")
+	payload.BeforeDate = parseString("before_date")
+	payload.LocationName = parseString("location_name")
+	payload.Status = parseString("status")
+	payload.Latitude = parseFloat("latitude")
+	payload.Longitude = parseFloat("longitude")
+	payload.RadiusKm = parseFloat("radius_km")
+	payload.SortBy = parseString("sort_by")
+	payload.Order = parseString("order")
+	payload.Limit = parseInt("limit")
+	payload.Cursor = parseString("cursor")
+	payload.IncludeTotal = parseBool("include_total")
+
+	// Sports can be repeated (?sports=a&sports=b) or comma separated
+	for _, v := range q["sports"] {
+		for _, sport := range strings.Split(v, ",") {
+			if sport = strings.TrimSpace(sport); sport != "" {
+				payload.Sports = append(payload.Sports, sport)
+			}
+		}
 	}
 
+	return payload, fieldErrors
+}
+
+// eventFilterFromPayload validates the payload and turns it into a store
+// filter. Every invalid field is added to fieldErrors.
+func eventFilterFromPayload(payload *EventFilterPayload, fieldErrors map[string]string) *store.EventFilter {
 	filter := &store.EventFilter{
 		ID:           payload.ID,
 		Sports:       payload.Sports,
@@ -463,14 +602,18 @@ func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Reque
 		EventOwner:   payload.EventOwner,
 		IsFull:       payload.IsFull,
 		LocationName: payload.LocationName,
+		Latitude:     payload.Latitude,
+		Longitude:    payload.Longitude,
+		RadiusKm:     payload.RadiusKm,
+		SortBy:       "created_at",
+		Order:        "asc",
 	}
 
 	// Parse and convert dates
 	if payload.AfterDate != nil {
 		t, err := time.Parse(time.RFC3339, *payload.AfterDate)
 		if err != nil {
-			app.badRequestResponse(w, r, errors.New("invalid after_date"))
-			return
+			fieldErrors["after_date"] = "must be an RFC3339 timestamp"
 		}
 		filter.AfterDate = &t
 	}
@@ -478,54 +621,205 @@ func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Reque
 	if payload.BeforeDate != nil {
 		t, err := time.Parse(time.RFC3339, *payload.BeforeDate)
 		if err != nil {
-			app.badRequestResponse(w, r, errors.New("invalid before_date"))
-			return
+			fieldErrors["before_date"] = "must be an RFC3339 timestamp"
 		}
 		filter.BeforeDate = &t
 	}
 
+	if filter.AfterDate != nil && filter.BeforeDate != nil && filter.BeforeDate.Before(*filter.AfterDate) {
+		if _, ok := fieldErrors["before_date"]; !ok {
+			fieldErrors["before_date"] = "must not be before after_date"
+		}
+	}
+
+	// Cancelled events are left out unless they are asked for
+	if payload.Status != nil {
+		switch *payload.Status {
+		case store.EventStatusScheduled, store.EventStatusCancelled, store.EventStatusCompleted:
+			filter.Status = payload.Status
+		default:
+			fieldErrors["status"] = "must be one of scheduled, cancelled, completed"
+		}
+	}
+
+	// Distances need both coordinates of the center
+	if payload.Latitude != nil && (*payload.Latitude < -90 || *payload.Latitude > 90) {
+		fieldErrors["latitude"] = "must be between -90 and 90"
+	}
+	if payload.Longitude != nil && (*payload.Longitude < -180 || *payload.Longitude > 180) {
+		fieldErrors["longitude"] = "must be between -180 and 180"
+	}
+	hasCenter := payload.Latitude != nil && payload.Longitude != nil
+	if payload.Latitude != nil && payload.Longitude == nil {
+		fieldErrors["longitude"] = "is required with latitude"
+	}
+	if payload.Longitude != nil && payload.Latitude == nil {
+		fieldErrors["latitude"] = "is required with longitude"
+	}
+	if payload.RadiusKm != nil {
+		switch {
+		case *payload.RadiusKm <= 0:
+			fieldErrors["radius_km"] = "must be greater than 0"
+		case !hasCenter:
+			fieldErrors["radius_km"] = "requires latitude and longitude"
+		}
+	}
+
 	// Validate sort options
 	if payload.SortBy != nil {
 		switch *payload.SortBy {
 		case "event_datetime", "created_at", "registered_count":
 			filter.SortBy = *payload.SortBy
+		case "distance":
+			if !hasCenter {
+				fieldErrors["sort_by"] = "distance requires latitude and longitude"
+			}
+			filter.SortBy = *payload.SortBy
+		default:
+			fieldErrors["sort_by"] = "must be one of event_datetime, created_at, registered_count, distance"
 		}
 	}
 	if payload.Order != nil {
 		order := strings.ToLower(*payload.Order)
-		if order == "desc" || order == "asc" {
+		switch order {
+		case "asc", "desc":
 			filter.Order = order
+		default:
+			fieldErrors["order"] = "must be asc or desc"
+		}
+	}
+
+	if payload.Limit != nil {
+		if *payload.Limit <= 0 {
+			fieldErrors["limit"] = "must be greater than 0"
 		}
+		filter.Limit = *payload.Limit
+	}
+	if payload.Cursor != nil {
+		filter.Cursor = *payload.Cursor
+	}
+	if payload.IncludeTotal != nil {
+		filter.IncludeTotal = *payload.IncludeTotal
 	}
 
-	// Apply defaults
-	if filter.SortBy == "" {
-		filter.SortBy = "created_at"
+	return filter
+}
+
+// getAllEventsHandler godoc
+//
+//	@Summary		Get all events with filters
+//	@Description	Returns a page of events matching the filters in the query string. When latitude and longitude are given every event carries its distance_km from that point. Pass next_cursor back as cursor to get the following page
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				query		int			false	"Event ID"
+//	@Param			sports			query		[]string	false	"Sports, comma separated or repeated"
+//	@Param			max_players		query		int			false	"Exact number of players"
+//	@Param			event_owner		query		int			false	"ID of the owner"
+//	@Param			is_full			query		bool		false	"Only full or only open events"
+//	@Param			after_date		query		string		false	"RFC3339 timestamp"
+//	@Param			before_date		query		string		false	"RFC3339 timestamp"
+//	@Param			location_name	query		string		false	"Part of the location name"
+//	@Param			status			query		string		false	"scheduled, cancelled or completed (default every status but cancelled)"
+//	@Param			latitude		query		number		false	"Latitude of the center"
+//	@Param			longitude		query		number		false	"Longitude of the center"
+//	@Param			radius_km		query		number		false	"Only events within this distance of the center"
+//	@Param			sort_by			query		string		false	"event_datetime, created_at, registered_count or distance"
+//	@Param			order			query		string		false	"asc or desc"
+//	@Param			limit			query		int			false	"Page size (default 20, max 100)"
+//	@Param			cursor			query		string		false	"next_cursor of the previous page"
+//	@Param			include_total	query		bool		false	"Include the total number of matching events"
+//	@Success		200				{array}		store.Event
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events [get]
+func (app *application) getAllEventsHandler(w http.ResponseWriter, r *http.Request) {
+	payload, fieldErrors := readEventFilterQuery(r)
+	app.listEvents(w, r, payload, fieldErrors)
+}
+
+// searchEventsHandler godoc
+//
+//	@Summary		Search events
+//	@Description	Same as GET /events, with the filters sent as a JSON body. Useful for long lists of sports or when the query string would get too long
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			filter	body		EventFilterPayload	true	"Event filtering criteria"
+//	@Success		200		{array}		store.Event
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/search [post]
+func (app *application) searchEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload EventFilterPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
-	if filter.Order == "" {
-		filter.Order = "asc"
+
+	app.listEvents(w, r, &payload, map[string]string{})
+}
+
+func (app *application) listEvents(w http.ResponseWriter, r *http.Request, payload *EventFilterPayload, fieldErrors map[string]string) {
+	filter := eventFilterFromPayload(payload, fieldErrors)
+	if len(fieldErrors) > 0 {
+		app.failedValidationResponse(w, r, fieldErrors)
+		return
 	}
 
 	// Fetch events
-	events, err := app.store.Events.GetAllWithFilter(r.Context(), filter)
+	page, err := app.store.Events.GetAllWithFilter(r.Context(), filter)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		switch err {
+		case store.ErrInvalidCursor:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, events); err != nil {
+	if err := app.pagedJSONResponse(w, http.StatusOK, page.Events, page.NextCursor, page.Total); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
 
+// getAllEventsSimpleHandler godoc
+//
+//	@Summary		Get all events
+//	@Description	Returns a page of all events, newest first, with their owner and participants. Pass next_cursor back as cursor to get the following page
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit			query		int		false	"Page size (default 20, max 100)"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page"
+//	@Param			include_total	query		bool	false	"Include the total number of events"
+//	@Success		200				{array}		store.Event
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/all [get]
 func (app *application) getAllEventsSimpleHandler(w http.ResponseWriter, r *http.Request) {
-	events, err := app.store.Events.GetAllSimple(r.Context())
+	pageQuery, err := readPageQuery(r)
 	if err != nil {
-		app.internalServerError(w, r, err)
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	page, err := app.store.Events.GetAllSimple(r.Context(), pageQuery)
+	if err != nil {
+		switch err {
+		case store.ErrInvalidCursor:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, events); err != nil {
+	if err := app.pagedJSONResponse(w, http.StatusOK, page.Events, page.NextCursor, page.Total); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
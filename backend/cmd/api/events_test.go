@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -236,6 +237,20 @@ func TestUpdateEventHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusForbidden,
 		},
+		{
+			name:    "cancelled event",
+			eventID: "4",
+			payload: UpdateEventPayload{
+				Sport: stringPtr("Basketball"),
+			},
+			setupAuth: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer test-token")
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -265,6 +280,7 @@ func TestDeleteEventHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		eventID        string
+		body           string
 		setupAuth      func(*http.Request)
 		expectedStatus int
 	}{
@@ -279,6 +295,41 @@ func TestDeleteEventHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:    "cancellation with a reason",
+			eventID: "1",
+			body:    `{"reason": "The pitch is closed"}`,
+			setupAuth: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer test-token")
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "reason too long",
+			eventID: "1",
+			body:    `{"reason": "` + strings.Repeat("a", 501) + `"}`,
+			setupAuth: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer test-token")
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "already cancelled",
+			eventID: "4",
+			setupAuth: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer test-token")
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusConflict,
+		},
 		{
 			name:           "unauthorized",
 			eventID:        "1",
@@ -300,7 +351,7 @@ func TestDeleteEventHandler(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("DELETE", "/events/"+tt.eventID, nil)
+			req := httptest.NewRequest("DELETE", "/events/"+tt.eventID, strings.NewReader(tt.body))
 			tt.setupAuth(req)
 
 			rctx := chi.NewRouteContext()
@@ -314,3 +365,299 @@ func TestDeleteEventHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestJoinEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		setupAuth      func(*http.Request)
+		expectedStatus int
+	}{
+		{
+			name:    "valid join",
+			eventID: "1",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 2}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "already joined",
+			eventID: "1",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 1}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:    "full event falls back to the waitlist",
+			eventID: "2",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 2}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:    "cancelled event",
+			eventID: "4",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 2}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:    "invalid event ID",
+			eventID: "invalid",
+			setupAuth: func(r *http.Request) {
+				user := &store.User{ID: 2}
+				ctx := context.WithValue(r.Context(), userCtx, user)
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unauthorized",
+			eventID:        "1",
+			setupAuth:      func(r *http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/events/"+tt.eventID+"/join", nil)
+			tt.setupAuth(req)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.eventID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			app.joinEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetAllEventsHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedFields []string
+	}{
+		{
+			name:           "no filters",
+			query:          "",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "all filters",
+			query:          "?sports=Football,Tennis&sports=Golf&is_full=false&after_date=2025-03-01T00:00:00Z&before_date=2025-04-01T00:00:00Z&location_name=park&sort_by=event_datetime&order=desc&limit=10",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "events near a point sorted by distance",
+			query:          "?latitude=40.78&longitude=-73.96&radius_km=10&sort_by=distance",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid values are reported per field",
+			query:          "?is_full=maybe&after_date=yesterday&max_players=ten&order=up",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"is_full", "after_date", "max_players", "order"},
+		},
+		{
+			name:           "before_date earlier than after_date",
+			query:          "?after_date=2025-04-01T00:00:00Z&before_date=2025-03-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"before_date"},
+		},
+		{
+			name:           "cancelled events",
+			query:          "?status=cancelled",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown status",
+			query:          "?status=postponed",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"status"},
+		},
+		{
+			name:           "unknown sort column",
+			query:          "?sort_by=title",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"sort_by"},
+		},
+		{
+			name:           "latitude without longitude",
+			query:          "?latitude=40.78",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"longitude"},
+		},
+		{
+			name:           "radius without center",
+			query:          "?radius_km=10",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"radius_km"},
+		},
+		{
+			name:           "latitude out of range",
+			query:          "?latitude=140.78&longitude=-73.96",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"latitude"},
+		},
+		{
+			name:           "non-positive radius",
+			query:          "?latitude=40.78&longitude=-73.96&radius_km=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"radius_km"},
+		},
+		{
+			name:           "sort by distance without center",
+			query:          "?sort_by=distance",
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []string{"sort_by"},
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=bad-cursor",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events"+tt.query, nil)
+
+			w := httptest.NewRecorder()
+			app.getAllEventsHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if len(tt.expectedFields) > 0 {
+				var response struct {
+					Fields map[string]string `json:"fields"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response.Fields, len(tt.expectedFields))
+				for _, field := range tt.expectedFields {
+					assert.Contains(t, response.Fields, field)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchEventsHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+	}{
+		{
+			name:           "complex filter",
+			payload:        `{"sports": ["Football", "Tennis", "Golf"], "is_full": false, "latitude": 40.78, "longitude": -73.96, "radius_km": 25, "sort_by": "distance", "limit": 50}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid field",
+			payload:        `{"after_date": "yesterday"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown field",
+			payload:        `{"colour": "red"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/events/search", bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			app.searchEventsHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetAllEventsSimpleHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  *int
+	}{
+		{
+			name:           "first page",
+			query:          "?limit=2",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "first page with total",
+			query:          "?limit=2&include_total=true",
+			expectedStatus: http.StatusOK,
+			expectedTotal:  intPtr(3),
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=zero",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid include_total",
+			query:          "?include_total=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=bad-cursor",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events/all"+tt.query, nil)
+
+			w := httptest.NewRecorder()
+			app.getAllEventsSimpleHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data       []store.Event `json:"data"`
+					NextCursor string        `json:"next_cursor"`
+					Total      *int          `json:"total"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response.Data, 2)
+				assert.Equal(t, "next-page", response.NextCursor)
+				assert.Equal(t, tt.expectedTotal, response.Total)
+			}
+		})
+	}
+}
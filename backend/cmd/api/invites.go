@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/auth"
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultInviteUses     = 25
+	defaultInviteDuration = 7 * 24 * time.Hour
+)
+
+type CreateInvitePayload struct {
+	// MaxUses is how many players can join with the invite, 25 by default
+	MaxUses int `json:"max_uses" validate:"omitempty,gt=0,max=1000"`
+	// ExpiresInHours is how long the invite can be used, a week by default
+	ExpiresInHours int `json:"expires_in_hours" validate:"omitempty,gt=0,max=720"`
+}
+
+// InviteResponse is a new invite with the only copy of its token.
+type InviteResponse struct {
+	*store.EventInvite
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// createEventInviteHandler godoc
+//
+//	@Summary		Create an invite link
+//	@Description	Creates a signed invite to share with the players who may join an unlisted or private event. It expires and can only be used a limited number of times. Only the owner and co-organizers can create invites
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			payload	body		CreateInvitePayload	false	"Limits of the invite"
+//	@Success		201		{object}	InviteResponse
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not an organizer of the event"
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Event is cancelled or over"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/invites [post]
+func (app *application) createEventInviteHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateInvitePayload
+	if err := readJSON(w, r, &payload); err != nil && err != io.EOF {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, ok := app.authorizeEvent(w, r, permManageRoster)
+	if !ok {
+		return
+	}
+
+	if event.Status != store.EventStatusScheduled {
+		app.conflictResponse(w, r, store.ErrEventNotScheduled)
+		return
+	}
+
+	invite := &store.EventInvite{
+		EventID:   event.ID,
+		CreatedBy: user.ID,
+		MaxUses:   defaultInviteUses,
+		ExpiresAt: time.Now().Add(defaultInviteDuration),
+	}
+	if payload.MaxUses > 0 {
+		invite.MaxUses = payload.MaxUses
+	}
+	if payload.ExpiresInHours > 0 {
+		invite.ExpiresAt = time.Now().Add(time.Duration(payload.ExpiresInHours) * time.Hour)
+	}
+
+	plain, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Events.CreateInvite(r.Context(), invite, hash); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	token := app.signInvite(event.ID, plain)
+	response := &InviteResponse{
+		EventInvite: invite,
+		Token:       token,
+		URL:         fmt.Sprintf("%s/events/%d?invite=%s", app.config.frontendURL, event.ID, token),
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// signInvite binds an invite token to its event, so it can't be used to join
+// any other one.
+func (app *application) signInvite(eventID int64, plain string) string {
+	return plain + "." + auth.Sign(app.config.auth.token.secret, inviteSigningData(eventID, plain))
+}
+
+// inviteHash checks the signature of an invite token for the event and
+// returns the hash it is stored under.
+func (app *application) inviteHash(eventID int64, token string) (string, error) {
+	plain, signature, ok := strings.Cut(token, ".")
+	if !ok || !auth.VerifySignature(app.config.auth.token.secret, inviteSigningData(eventID, plain), signature) {
+		return "", store.ErrInvalidInvite
+	}
+	return auth.HashToken(plain), nil
+}
+
+func inviteSigningData(eventID int64, plain string) string {
+	return fmt.Sprintf("invite:%d:%s", eventID, plain)
+}
+
+// loadVisibleEvent returns the event of the request if the authenticated
+// user may see it. Private events are only shown to their members and to
+// whoever holds a valid invite, passed as the invite query parameter. Anyone
+// else gets a 404 so they can't tell the event exists.
+func (app *application) loadVisibleEvent(w http.ResponseWriter, r *http.Request) (*store.Event, bool) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return nil, false
+	}
+
+	event, err := app.store.Events.GetByID(r.Context(), eventID)
+	if err != nil {
+		if err == store.ErrEventNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return nil, false
+	}
+
+	visible, err := app.canView(r, event, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+	if !visible {
+		app.notFoundResponse(w, r, store.ErrEventNotFound)
+		return nil, false
+	}
+
+	return event, true
+}
+
+// canView reports whether the user may see the event. The event must have
+// been loaded with its participants and organizers.
+func (app *application) canView(r *http.Request, event *store.Event, userID int64) (bool, error) {
+	if event.Visibility != store.VisibilityPrivate || can(event, userID, permViewPrivateEvent) || findParticipant(event, userID) != nil {
+		return true, nil
+	}
+
+	hash, err := app.inviteHash(event.ID, r.URL.Query().Get("invite"))
+	if err == nil {
+		err = app.store.Events.CheckInvite(r.Context(), event.ID, hash)
+	}
+	switch err {
+	case nil:
+		return true, nil
+	case store.ErrInvalidInvite:
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEventInviteHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		userID         int64
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner creates an invite", eventID: "7", userID: 1, expectedStatus: http.StatusCreated},
+		{name: "organizer creates an invite", eventID: "6", userID: 3, body: `{"max_uses": 5, "expires_in_hours": 2}`, expectedStatus: http.StatusCreated},
+		{name: "participant can't", eventID: "6", userID: 4, expectedStatus: http.StatusForbidden},
+		{name: "too many uses", eventID: "7", userID: 1, body: `{"max_uses": 5000}`, expectedStatus: http.StatusBadRequest},
+		{name: "cancelled event", eventID: "4", userID: 1, expectedStatus: http.StatusConflict},
+		{name: "unauthorized", eventID: "7", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("POST", tt.body, tt.userID, map[string]string{"id": tt.eventID})
+			w := httptest.NewRecorder()
+			app.createEventInviteHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data InviteResponse `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.NotEmpty(t, response.Data.Token)
+				assert.True(t, strings.HasSuffix(response.Data.URL, "/events/"+tt.eventID+"?invite="+response.Data.Token))
+
+				// The token is bound to the event it was created for
+				_, err = app.inviteHash(response.Data.EventID, response.Data.Token)
+				assert.NoError(t, err)
+				_, err = app.inviteHash(response.Data.EventID+1, response.Data.Token)
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestJoinInviteOnlyEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	valid := app.signInvite(7, mockInviteToken)
+	tests := []struct {
+		name           string
+		eventID        string
+		body           string
+		expectedStatus int
+	}{
+		{name: "public event without invite", eventID: "1", expectedStatus: http.StatusOK},
+		{name: "private event with invite", eventID: "7", body: `{"invite_token": "` + valid + `"}`, expectedStatus: http.StatusOK},
+		{name: "private event without invite", eventID: "7", expectedStatus: http.StatusForbidden},
+		{name: "unlisted event without invite", eventID: "8", expectedStatus: http.StatusForbidden},
+		{name: "invite of another event", eventID: "8", body: `{"invite_token": "` + valid + `"}`, expectedStatus: http.StatusBadRequest},
+		{name: "forged invite", eventID: "7", body: `{"invite_token": "` + mockInviteToken + `.deadbeef"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown invite", eventID: "7", body: `{"invite_token": "` + app.signInvite(7, "other") + `"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("POST", tt.body, 2, map[string]string{"id": tt.eventID})
+			w := httptest.NewRecorder()
+			app.joinEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestGetPrivateEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		userID         int64
+		invite         string
+		expectedStatus int
+	}{
+		{name: "participant sees the event", eventID: "7", userID: 1, expectedStatus: http.StatusOK},
+		{name: "co-organizer sees the event", eventID: "7", userID: 5, expectedStatus: http.StatusOK},
+		{name: "hidden from others", eventID: "7", userID: 2, expectedStatus: http.StatusNotFound},
+		{name: "invitee sees the event", eventID: "7", userID: 2, invite: app.signInvite(7, mockInviteToken), expectedStatus: http.StatusOK},
+		{name: "invalid invite", eventID: "7", userID: 2, invite: app.signInvite(7, "other"), expectedStatus: http.StatusNotFound},
+		{name: "unlisted event is visible", eventID: "8", userID: 2, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("GET", "", tt.userID, map[string]string{"id": tt.eventID})
+			if tt.invite != "" {
+				req.URL.RawQuery = "invite=" + tt.invite
+			}
+			w := httptest.NewRecorder()
+			app.getEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
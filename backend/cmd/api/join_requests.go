@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// requestJoin records a join request for an event that requires approval and
+// lets the owner know about it.
+func (app *application) requestJoin(w http.ResponseWriter, r *http.Request, eventID int64, user *store.User, inviteHash string) {
+	request, err := app.store.Events.RequestJoin(r.Context(), eventID, user.ID, inviteHash)
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrAlreadyJoined, store.ErrAlreadyWaitlisted, store.ErrAlreadyRequested, store.ErrJoinRequestDeclined, store.ErrEventNotScheduled:
+			app.conflictResponse(w, r, err)
+		case store.ErrInviteRequired:
+			app.forbiddenResponse(w, r)
+		case store.ErrInvalidInvite:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.notifyOwner(r.Context(), eventID, user, store.NotificationJoinRequested, "asked to join")
+
+	response := map[string]any{
+		"message":    "Your request to join has been sent to the organizers",
+		"request_id": request.ID,
+	}
+	if err := app.jsonResponse(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getEventJoinRequestsHandler godoc
+//
+//	@Summary		List join requests
+//	@Description	Returns the pending requests to join an event that requires approval, oldest first. Only the owner and co-organizers can see them
+//	@Tags			events
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{array}		store.JoinRequest
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error	"User is not an organizer of the event"
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/requests [get]
+func (app *application) getEventJoinRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	event, _, ok := app.authorizeEvent(w, r, permManageRoster)
+	if !ok {
+		return
+	}
+
+	requests, err := app.store.Events.GetJoinRequests(r.Context(), event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, requests); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// approveEventJoinRequestHandler godoc
+//
+//	@Summary		Approve a join request
+//	@Description	Adds the player to the event if it still has a spot and notifies them. Only the owner and co-organizers can approve requests
+//	@Tags			events
+//	@Produce		json
+//	@Param			id			path		int	true	"Event ID"
+//	@Param			requestID	path		int	true	"Join request ID"
+//	@Success		200			{object}	store.JoinRequest
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error	"User is not an organizer of the event"
+//	@Failure		404			{object}	error	"Event or pending request not found"
+//	@Failure		409			{object}	error	"Event is full, cancelled or over"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/requests/{requestID}/approve [post]
+func (app *application) approveEventJoinRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondJoinRequest(w, r, true)
+}
+
+// rejectEventJoinRequestHandler godoc
+//
+//	@Summary		Reject a join request
+//	@Description	Turns the player down and notifies them. Only the owner and co-organizers can reject requests
+//	@Tags			events
+//	@Produce		json
+//	@Param			id			path		int	true	"Event ID"
+//	@Param			requestID	path		int	true	"Join request ID"
+//	@Success		200			{object}	store.JoinRequest
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error	"User is not an organizer of the event"
+//	@Failure		404			{object}	error	"Event or pending request not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/requests/{requestID}/reject [post]
+func (app *application) rejectEventJoinRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondJoinRequest(w, r, false)
+}
+
+func (app *application) respondJoinRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, ok := app.authorizeEvent(w, r, permManageRoster)
+	if !ok {
+		return
+	}
+
+	request, err := app.store.Events.RespondJoinRequest(r.Context(), event.ID, requestID, user.ID, approve)
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound, store.ErrJoinRequestNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrEventFull, store.ErrEventNotScheduled:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	kind, message := store.NotificationJoinRejected, "Your request to join %q was declined"
+	if approve {
+		kind, message = store.NotificationJoinApproved, "Your request to join %q was approved"
+	}
+	app.notify(r.Context(), &store.Notification{
+		UserID:  request.UserID,
+		EventID: &event.ID,
+		Type:    kind,
+		Message: fmt.Sprintf(message, eventName(event)),
+	})
+
+	if err := app.jsonResponse(w, http.StatusOK, request); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinEventRequiringApprovalHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "request is sent", userID: 2, expectedStatus: http.StatusAccepted},
+		{name: "already requested", userID: 3, expectedStatus: http.StatusConflict},
+		{name: "declined recently", userID: 4, expectedStatus: http.StatusConflict},
+		{name: "already joined", userID: 1, expectedStatus: http.StatusConflict},
+	}
+
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.created = nil
+			req := newChatRequest("POST", "", tt.userID, map[string]string{"id": "9"})
+			w := httptest.NewRecorder()
+			app.joinEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusAccepted {
+				var response struct {
+					Data map[string]any `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, float64(2), response.Data["request_id"])
+
+				// The owner hears about it
+				if assert.Len(t, notifications.created, 1) {
+					assert.Equal(t, int64(1), notifications.created[0].UserID)
+					assert.Equal(t, store.NotificationJoinRequested, notifications.created[0].Type)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEventJoinRequestsHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "owner lists requests", userID: 1, expectedStatus: http.StatusOK},
+		{name: "organizer lists requests", userID: 3, expectedStatus: http.StatusOK},
+		{name: "participant can't", userID: 4, expectedStatus: http.StatusForbidden},
+		{name: "unauthorized", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("GET", "", tt.userID, map[string]string{"id": "6"})
+			w := httptest.NewRecorder()
+			app.getEventJoinRequestsHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data []store.JoinRequest `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Data, 1)
+			}
+		})
+	}
+}
+
+func TestRespondEventJoinRequestHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		eventID        string
+		requestID      string
+		userID         int64
+		expectedStatus int
+		requestStatus  string
+		expectedNotice string
+	}{
+		{
+			name:           "owner approves",
+			handler:        app.approveEventJoinRequestHandler,
+			eventID:        "6",
+			requestID:      "1",
+			userID:         1,
+			expectedStatus: http.StatusOK,
+			requestStatus:  store.JoinRequestApproved,
+			expectedNotice: store.NotificationJoinApproved,
+		},
+		{
+			name:           "organizer rejects",
+			handler:        app.rejectEventJoinRequestHandler,
+			eventID:        "6",
+			requestID:      "1",
+			userID:         3,
+			expectedStatus: http.StatusOK,
+			requestStatus:  store.JoinRequestRejected,
+			expectedNotice: store.NotificationJoinRejected,
+		},
+		{
+			name:           "participant can't",
+			handler:        app.approveEventJoinRequestHandler,
+			eventID:        "6",
+			requestID:      "1",
+			userID:         4,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "event is full",
+			handler:        app.approveEventJoinRequestHandler,
+			eventID:        "2",
+			requestID:      "1",
+			userID:         1,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "no pending request",
+			handler:        app.approveEventJoinRequestHandler,
+			eventID:        "6",
+			requestID:      "7",
+			userID:         1,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid request ID",
+			handler:        app.rejectEventJoinRequestHandler,
+			eventID:        "6",
+			requestID:      "invalid",
+			userID:         1,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.created = nil
+			req := newChatRequest("POST", "", tt.userID, map[string]string{"id": tt.eventID, "requestID": tt.requestID})
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data store.JoinRequest `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.requestStatus, response.Data.Status)
+
+				if assert.Len(t, notifications.created, 1) {
+					assert.Equal(t, int64(5), notifications.created[0].UserID)
+					assert.Equal(t, tt.expectedNotice, notifications.created[0].Type)
+				}
+			} else {
+				assert.Empty(t, notifications.created)
+			}
+		})
+	}
+}
@@ -37,6 +37,29 @@ func writeJSONError(w http.ResponseWriter, status int, message string) error {
 	return writeJSON(w, status, &envelope{Error: message})
 }
 
+// Machine readable error codes for failures the client has to tell apart
+const (
+	errCodeAccountInactive = "account_inactive"
+)
+
+func writeJSONErrorWithCode(w http.ResponseWriter, status int, code, message string) error {
+	type envelope struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+
+	return writeJSON(w, status, &envelope{Error: message, Code: code})
+}
+
+func writeJSONFieldErrors(w http.ResponseWriter, status int, message string, fields map[string]string) error {
+	type envelope struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+
+	return writeJSON(w, status, &envelope{Error: message, Fields: fields})
+}
+
 func (app *application) jsonResponse(w http.ResponseWriter, status int, data any) error {
 	type envelope struct {
 		Data any `json:"data"`
@@ -44,3 +67,15 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 
 	return writeJSON(w, status, &envelope{Data: data})
 }
+
+// pagedJSONResponse writes one page of a listing. next_cursor is left out on
+// the last page and total only when it was requested.
+func (app *application) pagedJSONResponse(w http.ResponseWriter, status int, data any, nextCursor string, total *int) error {
+	type envelope struct {
+		Data       any    `json:"data"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		Total      *int   `json:"total,omitempty"`
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: nextCursor, Total: total})
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 
+	"github.com/MishNia/Sportify.git/internal/ratelimit"
+	"github.com/MishNia/Sportify.git/internal/store"
 	"github.com/golang-jwt/jwt/v5"
+	gorilla "github.com/gorilla/websocket"
 )
 
+var ErrTokenRevoked = errors.New("token has been revoked")
+
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -47,7 +56,222 @@ func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if !tokenVersionMatches(claims, user) {
+			app.unauthorizedErrorResponse(w, r, ErrTokenRevoked)
+			return
+		}
+
+		if !user.IsActive {
+			app.inactiveAccountResponse(w, r)
+			return
+		}
+
 		ctx = context.WithValue(ctx, userCtx, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
-}
\ No newline at end of file
+}
+
+// RequireRole only lets through users with the role or a more privileged
+// one. It must come after AuthTokenMiddleware. The user is loaded from the
+// database there, and changing a role revokes the tokens carrying the old
+// one, so the role claim and the stored role always agree.
+func (app *application) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+			if user == nil {
+				app.unauthorizedResponse(w, r)
+				return
+			}
+
+			if !user.HasRole(role) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitMiddleware limits requests per user, or per client IP for
+// anonymous requests. On protected routes it must come after
+// AuthTokenMiddleware so requests count against the user.
+func (app *application) RateLimitMiddleware(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !app.config.rateLimit.enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := limiter.Allow(rateLimitKey(r)); !ok {
+				app.rateLimitExceededResponse(w, r, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies who sent the request. RealIPMiddleware has
+// already replaced RemoteAddr with the client IP when behind a trusted proxy.
+func rateLimitKey(r *http.Request) string {
+	if user := getUserFromContext(r); user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip
+}
+
+// RealIPMiddleware replaces RemoteAddr with the client IP from the
+// X-Forwarded-For or X-Real-IP header, but only on requests coming from one of
+// the trusted proxies. Anyone else could pick a new address for every request
+// and never run out of rate limit.
+func (app *application) RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := app.forwardedClientIP(r); ok {
+			r.RemoteAddr = ip
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClientIP returns the client IP the trusted proxies in front of the
+// server saw. X-Forwarded-For is read from the right, since the addresses on
+// its left were sent by the client and can't be trusted.
+func (app *application) forwardedClientIP(r *http.Request) (string, bool) {
+	if !app.isTrustedProxy(r.RemoteAddr) {
+		return "", false
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				return "", false
+			}
+			if i == 0 || !app.isTrustedProxy(ip.String()) {
+				return ip.String(), true
+			}
+		}
+	}
+
+	if ip, err := netip.ParseAddr(r.Header.Get("X-Real-IP")); err == nil {
+		return ip.String(), true
+	}
+	return "", false
+}
+
+// isTrustedProxy reports whether addr, with or without a port, belongs to one
+// of the configured proxies.
+func (app *application) isTrustedProxy(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	ip = ip.Unmap()
+	for _, prefix := range app.config.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses a list of IPs and CIDR ranges, e.g. "10.0.0.0/8".
+func parseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+			}
+			ip = ip.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// tokenVersionMatches reports whether the token was issued after the last
+// time the user's tokens were revoked (e.g. by a password reset).
+func tokenVersionMatches(claims jwt.MapClaims, user *store.User) bool {
+	// Tokens issued before versioning was introduced carry no claim
+	ver, _ := claims["ver"].(float64)
+	return int(ver) == user.TokenVersion
+}
+
+// authenticateWebSocket authenticates a websocket handshake. Browsers can't
+// set headers on websocket requests, so the access token is passed in the
+// token query parameter instead.
+func (app *application) authenticateWebSocket(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	// Get token from query parameter
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		app.logger.Warnw("No token provided in websocket connection")
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	// Validate token
+	jwtToken, err := app.authenticator.ValidateToken(token)
+	if err != nil {
+		app.logger.Warnw("Invalid token in websocket connection", "error", err)
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	claims := jwtToken.Claims.(jwt.MapClaims)
+	userID := int64(claims["sub"].(float64)) // sub claim is a float64
+
+	user, err := app.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		app.logger.Errorw("Failed to get user", "error", err)
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if !tokenVersionMatches(claims, user) {
+		app.logger.Warnw("Revoked token in websocket connection", "userID", userID)
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	if !user.IsActive {
+		app.inactiveAccountResponse(w, r)
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*gorilla.Conn, error) {
+	upgrader := gorilla.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			app.logger.Infow("Checking origin", "origin", origin)
+			return true // Allow all origins for development
+		},
+	}
+	return upgrader.Upgrade(w, r, nil)
+}
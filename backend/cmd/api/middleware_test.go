@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/ratelimit"
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	app := newTestApplication()
+	app.config.rateLimit.enabled = true
+
+	// httptest requests come from 192.0.2.1
+	app.config.trustedProxies = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+	limiter := ratelimit.NewTokenBucket(ratelimit.Config{Requests: 2, Window: time.Minute})
+	handler := app.RealIPMiddleware(app.RateLimitMiddleware(limiter)(http.HandlerFunc(app.healthCheckHandler)))
+
+	send := func(ip string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/health", nil)
+		req.Header.Set("X-Forwarded-For", ip)
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: userID}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Anonymous requests are counted per client IP
+	assert.Equal(t, http.StatusOK, send("10.0.0.1", 0).Code)
+	assert.Equal(t, http.StatusOK, send("10.0.0.1", 0).Code)
+	w := send("10.0.0.1", 0)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, send("10.0.0.2", 0).Code)
+
+	// Users are counted wherever they connect from
+	assert.Equal(t, http.StatusOK, send("10.0.0.3", 1).Code)
+	assert.Equal(t, http.StatusOK, send("10.0.0.4", 1).Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.5", 1).Code)
+}
+
+func TestRateLimitMiddleware_UntrustedForwardedFor(t *testing.T) {
+	app := newTestApplication()
+	app.config.rateLimit.enabled = true
+
+	limiter := ratelimit.NewTokenBucket(ratelimit.Config{Requests: 2, Window: time.Minute})
+	handler := app.RealIPMiddleware(app.RateLimitMiddleware(limiter)(http.HandlerFunc(app.healthCheckHandler)))
+
+	send := func(ip string) int {
+		req := httptest.NewRequest("GET", "/v1/health", nil)
+		req.Header.Set("X-Forwarded-For", ip)
+		req.Header.Set("X-Real-IP", ip)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Without trusted proxies a new address per request doesn't get a new
+	// allowance
+	assert.Equal(t, http.StatusOK, send("10.0.0.1"))
+	assert.Equal(t, http.StatusOK, send("10.0.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.3"))
+}
+
+func TestRealIPMiddleware(t *testing.T) {
+	app := newTestApplication()
+	app.config.trustedProxies = []netip.Prefix{
+		netip.MustParsePrefix("192.0.2.0/24"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"untrusted peer", "203.0.113.7:4000", "198.51.100.1", "", "203.0.113.7:4000"},
+		{"trusted peer", "192.0.2.1:4000", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed hops on the left", "192.0.2.1:4000", "1.2.3.4, 198.51.100.1, 10.0.0.5", "", "198.51.100.1"},
+		{"only proxies", "192.0.2.1:4000", "10.0.0.6, 10.0.0.5", "", "10.0.0.6"},
+		{"invalid hop", "192.0.2.1:4000", "nonsense", "", "192.0.2.1:4000"},
+		{"real ip header", "192.0.2.1:4000", "", "198.51.100.2", "198.51.100.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := app.RealIPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest("GET", "/v1/health", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := parseTrustedProxies([]string{"10.0.0.1", "172.16.5.0/12"})
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+	}, prefixes)
+
+	_, err = parseTrustedProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+}
+
+func TestRateLimitMiddleware_Disabled(t *testing.T) {
+	app := newTestApplication()
+
+	limiter := ratelimit.NewTokenBucket(ratelimit.Config{Requests: 1, Window: time.Minute})
+	handler := app.RateLimitMiddleware(limiter)(http.HandlerFunc(app.healthCheckHandler))
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/health", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestAuthRoutesRateLimit(t *testing.T) {
+	app := newTestApplication()
+	app.config.rateLimit = rateLimitConfig{
+		enabled: true,
+		api:     ratelimit.Config{Requests: 100, Window: time.Minute},
+		auth:    ratelimit.Config{Requests: 2, Window: time.Minute},
+	}
+	router := app.mount()
+
+	send := func(path string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Password resets and verification emails share the allowance of login
+	// and signup
+	assert.NotEqual(t, http.StatusTooManyRequests, send("/v1/auth/forgot-password"))
+	assert.NotEqual(t, http.StatusTooManyRequests, send("/v1/auth/reset-password"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/v1/auth/forgot-password"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/v1/auth/reset-password"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/v1/auth/resend-verification"))
+}
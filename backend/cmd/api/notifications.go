@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+)
+
+type MarkNotificationsReadPayload struct {
+	IDs []int64 `json:"ids"`
+}
+
+// getNotificationsHandler godoc
+//
+//	@Summary		List notifications
+//	@Description	Returns a page of the user's notifications, newest first. Pass next_cursor back as cursor to get the following page. With unread and include_total, total is the number of unread notifications
+//	@Tags			notifications
+//	@Produce		json
+//	@Param			unread			query		bool	false	"Only unread notifications"
+//	@Param			limit			query		int		false	"Page size (default 20, max 100)"
+//	@Param			cursor			query		string	false	"next_cursor of the previous page"
+//	@Param			include_total	query		bool	false	"Include the total number of notifications"
+//	@Success		200				{array}		store.Notification
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	pageQuery, err := readPageQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var unreadOnly bool
+	if v := r.URL.Query().Get("unread"); v != "" {
+		unreadOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid unread"))
+			return
+		}
+	}
+
+	page, err := app.store.Notifications.GetByUser(r.Context(), user.ID, unreadOnly, pageQuery)
+	if err != nil {
+		switch err {
+		case store.ErrInvalidCursor:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.pagedJSONResponse(w, http.StatusOK, page.Notifications, page.NextCursor, page.Total); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// markNotificationsReadHandler godoc
+//
+//	@Summary		Mark notifications as read
+//	@Description	Marks the given notifications as read, or every notification of the user when no ids are sent. Returns how many are still unread
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		MarkNotificationsReadPayload	false	"Notifications to mark as read"
+//	@Success		200		{object}	map[string]int
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/read [post]
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	var payload MarkNotificationsReadPayload
+	if err := readJSON(w, r, &payload); err != nil && err != io.EOF {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Notifications.MarkRead(ctx, user.ID, payload.IDs); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	unread, err := app.store.Notifications.UnreadCount(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, map[string]int{"unread": unread}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// notificationsWebSocketHandler godoc
+//
+//	@Summary		Live notifications
+//	@Description	Upgrades to a websocket that receives every new notification of the user as {"type": "notification", "notification": {...}}. Authenticated with the access token in the token query parameter
+//	@Tags			notifications
+//	@Param			token	query	string	true	"Access token"
+//	@Success		101
+//	@Failure		403	{object}	error
+//	@Router			/notifications/ws [get]
+func (app *application) notificationsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.authenticateWebSocket(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := app.upgradeWebSocket(w, r)
+	if err != nil {
+		app.logger.Errorw("Failed to upgrade connection", "error", err)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.hub.HandleNotifications(conn, user.ID)
+}
+
+// notify stores the notifications and pushes them to their users. What they
+// describe has already happened, so failures are logged instead of failing
+// the request.
+func (app *application) notify(ctx context.Context, notifications ...*store.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+
+	if err := app.store.Notifications.Create(ctx, notifications...); err != nil {
+		app.logger.Errorw("failed to create notifications", "error", err)
+		return
+	}
+
+	app.pushNotifications(notifications)
+}
+
+// pushNotifications sends notifications that are already stored to the users
+// that are connected.
+func (app *application) pushNotifications(notifications []*store.Notification) {
+	for _, n := range notifications {
+		app.hub.Notify(n)
+	}
+}
+
+// notifyOwner tells the owner of the event that a player joined or left it.
+func (app *application) notifyOwner(ctx context.Context, eventID int64, user *store.User, kind, action string) {
+	event, err := app.store.Events.GetByID(ctx, eventID)
+	if err != nil {
+		app.logger.Errorw("failed to load event to notify", "eventID", eventID, "error", err)
+		return
+	}
+	if event.EventOwner == user.ID {
+		return
+	}
+
+	name := "A player"
+	if profile, err := app.store.Profile.GetByEmail(ctx, user.Email); err == nil {
+		name = profile.FirstName + " " + profile.LastName
+	}
+
+	app.notify(ctx, &store.Notification{
+		UserID:  event.EventOwner,
+		EventID: &event.ID,
+		Type:    kind,
+		Message: fmt.Sprintf("%s %s %q", name, action, eventName(event)),
+	})
+}
+
+// notifyParticipants tells everyone who joined the event, except its owner,
+// that it changed.
+func (app *application) notifyParticipants(ctx context.Context, event *store.Event, kind, message string) {
+	var notifications []*store.Notification
+	for _, p := range event.Participants {
+		if p.UserID == event.EventOwner {
+			continue
+		}
+		notifications = append(notifications, &store.Notification{
+			UserID:  p.UserID,
+			EventID: &event.ID,
+			Type:    kind,
+			Message: message,
+		})
+	}
+
+	app.notify(ctx, notifications...)
+}
+
+// eventName is how an event is referred to in notifications.
+func eventName(event *store.Event) string {
+	if event.Title != "" {
+		return event.Title
+	}
+	return event.Sport
+}
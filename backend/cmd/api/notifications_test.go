@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNotificationsHandler(t *testing.T) {
+	app := newTestApplication()
+	two := 2
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTotal  *int
+	}{
+		{
+			name:           "first page",
+			query:          "",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unread only",
+			query:          "?unread=true&limit=10",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unread count",
+			query:          "?unread=true&include_total=true",
+			expectedStatus: http.StatusOK,
+			expectedTotal:  &two,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=bad-cursor",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid unread",
+			query:          "?unread=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/notifications"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: 1}))
+
+			w := httptest.NewRecorder()
+			app.getNotificationsHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data  []store.Notification `json:"data"`
+					Total *int                 `json:"total"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response.Data, 2)
+				assert.Equal(t, tt.expectedTotal, response.Total)
+			}
+		})
+	}
+}
+
+func TestMarkNotificationsReadHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "some notifications",
+			body:           `{"ids": [1, 2]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "all notifications",
+			body:           "",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid body",
+			body:           `{"ids": "all"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/notifications/read", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: 1}))
+
+			w := httptest.NewRecorder()
+			app.markNotificationsReadHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.JSONEq(t, `{"data": {"unread": 0}}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestJoinEventNotifiesOwner(t *testing.T) {
+	app := newTestApplication()
+	notifications := app.store.Notifications.(*mockNotificationStore)
+
+	req := httptest.NewRequest("POST", "/events/1/join", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, userCtx, &store.User{ID: 2, Email: "player@example.com"})
+	req = req.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+	app.joinEventHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, notifications.created, 1) {
+		n := notifications.created[0]
+		assert.Equal(t, int64(1), n.UserID)
+		assert.Equal(t, store.NotificationEventJoined, n.Type)
+		assert.Equal(t, `Test User joined "Test Event"`, n.Message)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/MishNia/Sportify.git/internal/websocket"
+	"github.com/go-chi/chi/v5"
+)
+
+type AddOrganizerPayload struct {
+	UserID int64 `json:"user_id" validate:"required,gt=0"`
+}
+
+// addEventOrganizerHandler godoc
+//
+//	@Summary		Add a co-organizer
+//	@Description	Lets a participant update the event, moderate its chat and manage its roster. They can't cancel the event or manage organizers. Only the event owner can add co-organizers
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			payload	body		AddOrganizerPayload	true	"Who to make co-organizer"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error	"Invalid payload or the user isn't a participant"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not the event owner"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/organizers [post]
+func (app *application) addEventOrganizerHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AddOrganizerPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, ok := app.authorizeEvent(w, r, permManageOrganizers)
+	if !ok {
+		return
+	}
+
+	if payload.UserID == event.EventOwner {
+		app.badRequestResponse(w, r, errors.New("the event owner is already an organizer"))
+		return
+	}
+
+	if err := app.store.Events.AddOrganizer(r.Context(), event.ID, payload.UserID, user.ID); err != nil {
+		switch err {
+		case store.ErrNotJoined:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if !isOrganizer(event, payload.UserID) {
+		app.notify(r.Context(), &store.Notification{
+			UserID:  payload.UserID,
+			EventID: &event.ID,
+			Type:    store.NotificationOrganizerAdded,
+			Message: fmt.Sprintf("You are now a co-organizer of %q", eventName(event)),
+		})
+	}
+
+	response := map[string]string{"message": "Organizer added"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// removeEventOrganizerHandler godoc
+//
+//	@Summary		Remove a co-organizer
+//	@Description	Takes the co-organizer rights of a participant away. They stay a participant. Only the event owner can remove co-organizers
+//	@Tags			events
+//	@Produce		json
+//	@Param			id		path		int	true	"Event ID"
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not the event owner"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/organizers/{userID} [delete]
+func (app *application) removeEventOrganizerHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, _, ok := app.authorizeEvent(w, r, permManageOrganizers)
+	if !ok {
+		return
+	}
+
+	if !isOrganizer(event, userID) {
+		app.notFoundResponse(w, r, errors.New("user is not a co-organizer"))
+		return
+	}
+
+	if err := app.store.Events.RemoveOrganizer(r.Context(), event.ID, userID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]string{"message": "Organizer removed"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// removeEventParticipantHandler godoc
+//
+//	@Summary		Remove a participant
+//	@Description	Takes a participant off the roster, frees their spot for the waitlist and disconnects them from the chat. They are notified. The event owner can remove anyone, co-organizers only plain participants
+//	@Tags			events
+//	@Produce		json
+//	@Param			id		path		int	true	"Event ID"
+//	@Param			userID	path		int	true	"User ID"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error	"The user can't be removed"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not an organizer of the event"
+//	@Failure		404		{object}	error	"Event or participant not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/participants/{userID} [delete]
+func (app *application) removeEventParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, ok := app.authorizeEvent(w, r, permManageRoster)
+	if !ok {
+		return
+	}
+
+	target := findParticipant(event, userID)
+	if target == nil {
+		app.notFoundResponse(w, r, store.ErrNotJoined)
+		return
+	}
+
+	if !outranks(event, user.ID, userID) {
+		app.badRequestResponse(w, r, errors.New("the event owner and co-organizers can't be removed"))
+		return
+	}
+
+	if err := app.store.Events.Leave(r.Context(), event.ID, userID); err != nil {
+		switch err {
+		case store.ErrEventNotFound, store.ErrNotJoined:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("Removed participant", "event_id", event.ID, "user_id", userID, "removed_by", user.ID)
+
+	app.hub.Broadcast(&websocket.Envelope{Type: websocket.TypeUserKicked, EventID: event.ID, User: presenceUser(target)})
+	app.notify(r.Context(), &store.Notification{
+		UserID:  userID,
+		EventID: &event.ID,
+		Type:    store.NotificationRemovedFromEvent,
+		Message: fmt.Sprintf("You were removed from %q by an organizer", eventName(event)),
+	})
+
+	response := map[string]string{"message": "Participant removed"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	// User 1 owns the event, user 3 co-organizes it and user 4 just plays
+	event := &store.Event{EventOwner: 1, Organizers: []int64{3}}
+
+	tests := []struct {
+		name     string
+		userID   int64
+		perm     eventPermission
+		expected bool
+	}{
+		{name: "owner updates", userID: 1, perm: permUpdateEvent, expected: true},
+		{name: "owner cancels", userID: 1, perm: permDeleteEvent, expected: true},
+		{name: "owner manages organizers", userID: 1, perm: permManageOrganizers, expected: true},
+		{name: "organizer updates", userID: 3, perm: permUpdateEvent, expected: true},
+		{name: "organizer moderates chat", userID: 3, perm: permModerateChat, expected: true},
+		{name: "organizer manages roster", userID: 3, perm: permManageRoster, expected: true},
+		{name: "organizer can't cancel", userID: 3, perm: permDeleteEvent, expected: false},
+		{name: "organizer can't manage organizers", userID: 3, perm: permManageOrganizers, expected: false},
+		{name: "participant can't update", userID: 4, perm: permUpdateEvent, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, can(event, tt.userID, tt.perm))
+		})
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	event := &store.Event{EventOwner: 1, Organizers: []int64{3, 5}}
+
+	assert.True(t, outranks(event, 1, 3), "owner outranks organizers")
+	assert.True(t, outranks(event, 1, 4), "owner outranks participants")
+	assert.True(t, outranks(event, 3, 4), "organizers outrank participants")
+	assert.False(t, outranks(event, 3, 5), "organizers don't outrank each other")
+	assert.False(t, outranks(event, 3, 1), "nobody outranks the owner")
+	assert.False(t, outranks(event, 4, 2), "participants outrank nobody")
+}
+
+func TestEventOrganizerHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		userID         int64
+		params         map[string]string
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner adds organizer", handler: app.addEventOrganizerHandler, userID: 1, body: `{"user_id": 4}`, expectedStatus: http.StatusOK},
+		{name: "organizer can't add organizers", handler: app.addEventOrganizerHandler, userID: 3, body: `{"user_id": 4}`, expectedStatus: http.StatusForbidden},
+		{name: "owner can't be added", handler: app.addEventOrganizerHandler, userID: 1, body: `{"user_id": 1}`, expectedStatus: http.StatusBadRequest},
+		{name: "add non participant", handler: app.addEventOrganizerHandler, userID: 1, body: `{"user_id": 2}`, expectedStatus: http.StatusBadRequest},
+		{name: "add without user", handler: app.addEventOrganizerHandler, userID: 1, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "owner removes organizer", handler: app.removeEventOrganizerHandler, userID: 1, params: map[string]string{"userID": "3"}, expectedStatus: http.StatusOK},
+		{name: "remove someone who isn't an organizer", handler: app.removeEventOrganizerHandler, userID: 1, params: map[string]string{"userID": "4"}, expectedStatus: http.StatusNotFound},
+		{name: "organizer can't remove organizers", handler: app.removeEventOrganizerHandler, userID: 3, params: map[string]string{"userID": "3"}, expectedStatus: http.StatusForbidden},
+		{name: "unauthorized", handler: app.addEventOrganizerHandler, body: `{"user_id": 4}`, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]string{"id": "6"}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+
+			req := newChatRequest("POST", tt.body, tt.userID, params)
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRemoveEventParticipantHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         int64
+		targetID       string
+		expectedStatus int
+	}{
+		{name: "owner removes participant", userID: 1, targetID: "4", expectedStatus: http.StatusOK},
+		{name: "owner removes organizer", userID: 1, targetID: "3", expectedStatus: http.StatusOK},
+		{name: "organizer removes participant", userID: 3, targetID: "4", expectedStatus: http.StatusOK},
+		{name: "organizer can't remove the owner", userID: 3, targetID: "1", expectedStatus: http.StatusBadRequest},
+		{name: "participant can't remove others", userID: 4, targetID: "3", expectedStatus: http.StatusForbidden},
+		{name: "not a participant", userID: 1, targetID: "2", expectedStatus: http.StatusNotFound},
+		{name: "invalid user ID", userID: 1, targetID: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.created = nil
+			req := newChatRequest("DELETE", "", tt.userID, map[string]string{"id": "6", "userID": tt.targetID})
+			w := httptest.NewRecorder()
+			app.removeEventParticipantHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				if assert.Len(t, notifications.created, 1) {
+					assert.Equal(t, store.NotificationRemovedFromEvent, notifications.created[0].Type)
+				}
+			} else {
+				assert.Empty(t, notifications.created)
+			}
+		})
+	}
+}
+
+func TestCoOrganizerEventPermissions(t *testing.T) {
+	app := newTestApplication()
+
+	// Co-organizers can update the event...
+	req := newChatRequest("PUT", `{"title": "Renamed"}`, 3, map[string]string{"id": "6"})
+	w := httptest.NewRecorder()
+	app.updateEventHandler(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// ...and moderate its chat, but not the other organizers
+	req = newChatRequest("POST", `{"user_id": 4, "minutes": 10}`, 3, map[string]string{"id": "6"})
+	w = httptest.NewRecorder()
+	app.muteChatUserHandler(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = newChatRequest("POST", `{"user_id": 1}`, 3, map[string]string{"id": "6"})
+	w = httptest.NewRecorder()
+	app.kickChatUserHandler(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// They can't cancel it
+	req = newChatRequest("DELETE", "", 3, map[string]string{"id": "6"})
+	w = httptest.NewRecorder()
+	app.deleteEventHandler(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
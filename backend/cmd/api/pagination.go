@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+)
+
+// readPageQuery reads the limit, cursor and include_total query parameters
+// shared by the event listings.
+func readPageQuery(r *http.Request) (store.PageQuery, error) {
+	q := r.URL.Query()
+	page := store.PageQuery{Cursor: q.Get("cursor")}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return page, errors.New("invalid limit")
+		}
+		page.Limit = limit
+	}
+
+	if v := q.Get("include_total"); v != "" {
+		includeTotal, err := strconv.ParseBool(v)
+		if err != nil {
+			return page, errors.New("invalid include_total")
+		}
+		page.IncludeTotal = includeTotal
+	}
+
+	return page, nil
+}
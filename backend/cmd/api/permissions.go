@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// eventPermission is something only some members of an event may do. Who may
+// do what is decided by can, and nowhere else.
+type eventPermission int
+
+const (
+	permUpdateEvent eventPermission = iota
+	permModerateChat
+	permManageRoster
+	permDeleteEvent
+	permManageOrganizers
+	permTransferOwnership
+	permViewPrivateEvent
+)
+
+// organizerPermissions are what co-organizers may do. Everything else is left
+// to the owner.
+var organizerPermissions = map[eventPermission]bool{
+	permUpdateEvent:      true,
+	permModerateChat:     true,
+	permManageRoster:     true,
+	permViewPrivateEvent: true,
+}
+
+// can reports whether the user may act on the event. The owner may do
+// anything, co-organizers only what organizerPermissions allows.
+func can(event *store.Event, userID int64, perm eventPermission) bool {
+	if userID == event.EventOwner {
+		return true
+	}
+	return organizerPermissions[perm] && isOrganizer(event, userID)
+}
+
+// outranks reports whether the actor may moderate the target or remove them
+// from the event. The owner outranks everyone, co-organizers only the other
+// participants.
+func outranks(event *store.Event, actorID, targetID int64) bool {
+	switch {
+	case targetID == event.EventOwner:
+		return false
+	case actorID == event.EventOwner:
+		return true
+	default:
+		return isOrganizer(event, actorID) && !isOrganizer(event, targetID)
+	}
+}
+
+// authorizeEvent loads the event of the request and checks the authenticated
+// user has perm on it. It writes the error response itself and returns false
+// when the request can't go on.
+func (app *application) authorizeEvent(w http.ResponseWriter, r *http.Request, perm eventPermission) (*store.Event, *store.User, bool) {
+	event, user, ok := app.loadEvent(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if !can(event, user.ID, perm) {
+		app.forbiddenResponse(w, r)
+		return nil, nil, false
+	}
+
+	return event, user, true
+}
+
+// loadEvent returns the event of the request and the authenticated user,
+// leaving it to the caller to decide what the user may do with it. It writes
+// the error response itself and returns false when the request can't go on.
+func (app *application) loadEvent(w http.ResponseWriter, r *http.Request) (*store.Event, *store.User, bool) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, nil, false
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return nil, nil, false
+	}
+
+	event, err := app.store.Events.GetByID(r.Context(), eventID)
+	if err != nil {
+		if err == store.ErrEventNotFound {
+			app.notFoundResponse(w, r, err)
+		} else {
+			app.internalServerError(w, r, err)
+		}
+		return nil, nil, false
+	}
+
+	return event, user, true
+}
+
+func isOrganizer(event *store.Event, userID int64) bool {
+	for _, id := range event.Organizers {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
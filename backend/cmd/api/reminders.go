@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type reminderConfig struct {
+	// leads are how long before an event starts its participants are
+	// reminded, e.g. 24h and 1h
+	leads    []time.Duration
+	interval time.Duration
+}
+
+// runReminders completes the events that have started and sends the event
+// reminders that are due every interval until ctx is done. Sent reminders are
+// recorded by the store, so running several API processes or restarting one
+// doesn't send them twice.
+func (app *application) runReminders(ctx context.Context) {
+	ticker := time.NewTicker(app.config.reminders.interval)
+	defer ticker.Stop()
+
+	app.logger.Infow("reminder scheduler started", "leads", app.config.reminders.leads, "interval", app.config.reminders.interval)
+
+	for {
+		now := time.Now()
+		app.completePastEvents(ctx, now)
+		app.sendDueReminders(ctx, now)
+
+		select {
+		case <-ctx.Done():
+			app.logger.Info("reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueReminders sends the reminders of every lead, shortest first, so an
+// event that is already within several leads only gets the closest one.
+func (app *application) sendDueReminders(ctx context.Context, now time.Time) {
+	leads := append([]time.Duration(nil), app.config.reminders.leads...)
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+
+	for _, lead := range leads {
+		if ctx.Err() != nil {
+			return
+		}
+
+		notifications, err := app.store.Reminders.SendDue(ctx, now, lead)
+		if err != nil {
+			app.logger.Errorw("failed to send reminders", "lead", lead, "error", err)
+			continue
+		}
+
+		app.pushNotifications(notifications)
+	}
+}
+
+// completePastEvents marks the events that have started as completed. Events
+// have no end time, so an event counts as completed from its start on.
+func (app *application) completePastEvents(ctx context.Context, now time.Time) {
+	count, err := app.store.Events.CompletePast(ctx, now)
+	if err != nil {
+		app.logger.Errorw("failed to complete past events", "error", err)
+		return
+	}
+	if count > 0 {
+		app.logger.Infow("completed past events", "count", count)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendDueReminders(t *testing.T) {
+	app := newTestApplication()
+	reminders := app.store.Reminders.(*mockReminderStore)
+
+	app.sendDueReminders(context.Background(), time.Now())
+
+	// Shorter leads go first so an event within both only gets the closest
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour * 24}, reminders.leads)
+}
+
+func TestRunRemindersStopsWithContext(t *testing.T) {
+	app := newTestApplication()
+	reminders := app.store.Reminders.(*mockReminderStore)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.runReminders(ctx)
+		close(done)
+	}()
+
+	// Let the scheduler tick a few times
+	assert.Eventually(t, func() bool {
+		reminders.mu.Lock()
+		defer reminders.mu.Unlock()
+		return len(reminders.leads) >= 4
+	}, time.Second, time.Millisecond*5)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after the context was cancelled")
+	}
+}
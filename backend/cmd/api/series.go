@@ -0,0 +1,350 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	scopeThis      = "this"
+	scopeFollowing = "following"
+)
+
+type RecurrencePayload struct {
+	Frequency string     `json:"frequency" validate:"required,oneof=weekly biweekly monthly"`
+	Until     *time.Time `json:"until"`
+	Count     *int       `json:"count" validate:"omitempty,gt=0,lte=52"`
+}
+
+type CreateEventSeriesPayload struct {
+	CreateEventPayload
+	Recurrence RecurrencePayload `json:"recurrence" validate:"required"`
+}
+
+// createEventSeriesHandler godoc
+//
+//	@Summary		Create a recurring event
+//	@Description	Creates an event series and one event per occurrence. The recurrence needs either until or count and can produce at most 52 events
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateEventSeriesPayload	true	"Event details and recurrence"
+//	@Success		201		{object}	store.EventSeries
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/series [post]
+func (app *application) createEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateEventSeriesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	series := &store.EventSeries{
+		EventOwner: user.ID,
+		Frequency:  payload.Recurrence.Frequency,
+		StartsAt:   payload.EventDate,
+		Until:      payload.Recurrence.Until,
+		Count:      payload.Recurrence.Count,
+	}
+	template := &store.Event{
+		Sport:            payload.Sport,
+		MaxPlayers:       payload.MaxPlayers,
+		LocationName:     payload.LocationName,
+		Latitude:         payload.Latitude,
+		Longitude:        payload.Longitude,
+		Description:      payload.Description,
+		Title:            payload.Title,
+		Visibility:       payload.Visibility,
+		RequiresApproval: payload.RequiresApproval,
+	}
+
+	if err := app.store.Events.CreateSeries(r.Context(), series, template); err != nil {
+		switch err {
+		case store.ErrInvalidRecurrence, store.ErrTooManyOccurrences:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, series); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getEventSeriesHandler godoc
+//
+//	@Summary		Get an event series
+//	@Description	Returns the recurrence of a series and its occurrences in chronological order. Private occurrences are only listed for their participants and for players with an invite
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			seriesID	path		int		true	"Series ID"
+//	@Param			invite		query		string	false	"Invite token of a private occurrence"
+//	@Success		200			{object}	store.EventSeries
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/series/{seriesID} [get]
+func (app *application) getEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := strconv.ParseInt(chi.URLParam(r, "seriesID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	series, err := app.store.Events.GetSeries(r.Context(), seriesID)
+	if err != nil {
+		switch err {
+		case store.ErrSeriesNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Hide the private occurrences the user may not see, and the whole series
+	// if that leaves nothing
+	visible := make([]*store.Event, 0, len(series.Events))
+	for _, occurrence := range series.Events {
+		if occurrence.Visibility != store.VisibilityPrivate {
+			visible = append(visible, occurrence)
+			continue
+		}
+
+		// The participants are needed to tell whether the user is one of them
+		event, err := app.store.Events.GetByID(r.Context(), occurrence.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ok, err := app.canView(r, event, user.ID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if ok {
+			visible = append(visible, occurrence)
+		}
+	}
+	if len(visible) == 0 && len(series.Events) > 0 {
+		app.notFoundResponse(w, r, store.ErrSeriesNotFound)
+		return
+	}
+	series.Events = visible
+
+	if err := app.jsonResponse(w, http.StatusOK, series); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateOccurrenceHandler godoc
+//
+//	@Summary		Update an occurrence of a recurring event
+//	@Description	Updates only this occurrence (scope=this) or this and every later occurrence of its series (scope=following). A new event_date moves every updated occurrence by the same amount. Only the owner and co-organizers can update
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			scope	query		string				false	"this (default) or following"
+//	@Param			payload	body		UpdateEventPayload	true	"Fields to update"
+//	@Success		200		{object}	store.Event
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/occurrences [put]
+func (app *application) updateOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	event, scope, ok := app.readOccurrence(w, r, permUpdateEvent)
+	if !ok {
+		return
+	}
+
+	var payload UpdateEventPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if scope == scopeThis && event.Status != store.EventStatusScheduled {
+		app.conflictResponse(w, r, store.ErrEventNotScheduled)
+		return
+	}
+
+	ctx := r.Context()
+	startsAt := event.EventDateTime
+	payload.apply(event)
+	event.UpdatedAt = time.Now()
+
+	var err error
+	switch scope {
+	case scopeFollowing:
+		err = app.store.Events.UpdateFollowing(ctx, event, event.EventDateTime.Sub(startsAt))
+	default:
+		err = app.store.Events.Update(ctx, event)
+	}
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	message := fmt.Sprintf("%q has been updated", eventName(event))
+	if scope == scopeFollowing {
+		message = fmt.Sprintf("%q and its following occurrences have been updated", eventName(event))
+	}
+	app.notifyParticipants(ctx, event, store.NotificationEventUpdated, message)
+
+	if err := app.jsonResponse(w, http.StatusOK, event); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteOccurrenceHandler godoc
+//
+//	@Summary		Cancel an occurrence of a recurring event
+//	@Description	Cancels only this occurrence (scope=this) or this and every later scheduled occurrence of its series (scope=following), and notifies their participants. Only the owner can cancel
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Event ID"
+//	@Param			scope	query		string				false	"this (default) or following"
+//	@Param			payload	body		CancelEventPayload	false	"Why the occurrences were cancelled"
+//	@Success		200		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/occurrences [delete]
+func (app *application) deleteOccurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	event, scope, ok := app.readOccurrence(w, r, permDeleteEvent)
+	if !ok {
+		return
+	}
+
+	reason, err := readCancelReason(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var notifications []*store.Notification
+	switch scope {
+	case scopeFollowing:
+		notifications, err = app.store.Events.CancelFollowing(r.Context(), event.ID, reason)
+	default:
+		notifications, err = app.store.Events.Cancel(r.Context(), event.ID, reason)
+	}
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrEventNotScheduled:
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.pushNotifications(notifications)
+
+	response := map[string]string{"message": "The occurrences have been cancelled"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// readOccurrence loads the occurrence named in the URL and the requested
+// scope, and checks the user has perm on it. It writes the error response itself and
+// returns false when the request can't go on.
+func (app *application) readOccurrence(w http.ResponseWriter, r *http.Request, perm eventPermission) (*store.Event, string, bool) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, "", false
+	}
+
+	scope := r.URL.Query().Get("scope")
+	switch scope {
+	case "":
+		scope = scopeThis
+	case scopeThis, scopeFollowing:
+	default:
+		app.badRequestResponse(w, r, errors.New("scope must be this or following"))
+		return nil, "", false
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return nil, "", false
+	}
+
+	event, err := app.store.Events.GetByID(r.Context(), eventID)
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, "", false
+	}
+
+	if !can(event, user.ID, perm) {
+		app.forbiddenResponse(w, r)
+		return nil, "", false
+	}
+
+	if event.SeriesID == nil {
+		app.badRequestResponse(w, r, store.ErrNotInSeries)
+		return nil, "", false
+	}
+
+	return event, scope, true
+}
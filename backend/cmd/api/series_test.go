@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEventSeriesHandler(t *testing.T) {
+	app := newTestApplication()
+
+	event := `"sport": "Football", "event_date": "2025-01-07T18:00:00Z", "max_players": 10, "location_name": "Central Park", "latitude": 40.78, "longitude": -73.96, "title": "Tuesday pickup"`
+
+	tests := []struct {
+		name           string
+		payload        string
+		expectedStatus int
+		expectedEvents int
+	}{
+		{
+			name:           "weekly with count",
+			payload:        `{` + event + `, "recurrence": {"frequency": "weekly", "count": 4}}`,
+			expectedStatus: http.StatusCreated,
+			expectedEvents: 4,
+		},
+		{
+			name:           "monthly until",
+			payload:        `{` + event + `, "recurrence": {"frequency": "monthly", "until": "2025-03-31T00:00:00Z"}}`,
+			expectedStatus: http.StatusCreated,
+			expectedEvents: 3,
+		},
+		{
+			name:           "unknown frequency",
+			payload:        `{` + event + `, "recurrence": {"frequency": "daily", "count": 4}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "neither until nor count",
+			payload:        `{` + event + `, "recurrence": {"frequency": "weekly"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many occurrences",
+			payload:        `{` + event + `, "recurrence": {"frequency": "weekly", "until": "2027-01-01T00:00:00Z"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/events/series", bytes.NewBufferString(tt.payload))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(context.WithValue(req.Context(), userCtx, &store.User{ID: 1}))
+
+			w := httptest.NewRecorder()
+			app.createEventSeriesHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data store.EventSeries `json:"data"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response.Data.Events, tt.expectedEvents)
+			}
+		})
+	}
+}
+
+func TestGetEventSeriesHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		seriesID       string
+		userID         int64
+		invite         string
+		expectedStatus int
+		expectedEvents int
+	}{
+		{
+			name:           "existing series",
+			seriesID:       "1",
+			userID:         2,
+			expectedStatus: http.StatusOK,
+			expectedEvents: 1,
+		},
+		{
+			name:           "private series is hidden",
+			seriesID:       "7",
+			userID:         2,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "private series of a participant",
+			seriesID:       "7",
+			userID:         1,
+			expectedStatus: http.StatusOK,
+			expectedEvents: 1,
+		},
+		{
+			name:           "private series with an invite",
+			seriesID:       "7",
+			userID:         2,
+			invite:         app.signInvite(7, mockInviteToken),
+			expectedStatus: http.StatusOK,
+			expectedEvents: 1,
+		},
+		{
+			name:           "unknown series",
+			seriesID:       "2",
+			userID:         2,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid series ID",
+			seriesID:       "invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("GET", "", tt.userID, map[string]string{"seriesID": tt.seriesID})
+			if tt.invite != "" {
+				req.URL.RawQuery = "invite=" + tt.invite
+			}
+
+			w := httptest.NewRecorder()
+			app.getEventSeriesHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data store.EventSeries `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Data.Events, tt.expectedEvents)
+			}
+		})
+	}
+}
+
+func TestOccurrenceHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		method         string
+		eventID        string
+		scope          string
+		userID         int64
+		expectedStatus int
+	}{
+		{
+			name:           "update this occurrence",
+			method:         "PUT",
+			eventID:        "3",
+			userID:         1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "update this and following",
+			method:         "PUT",
+			eventID:        "3",
+			scope:          "following",
+			userID:         1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "cancel this and following",
+			method:         "DELETE",
+			eventID:        "3",
+			scope:          "following",
+			userID:         1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown scope",
+			method:         "DELETE",
+			eventID:        "3",
+			scope:          "all",
+			userID:         1,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "standalone event",
+			method:         "DELETE",
+			eventID:        "1",
+			scope:          "following",
+			userID:         1,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not owner",
+			method:         "PUT",
+			eventID:        "3",
+			scope:          "following",
+			userID:         2,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"title": "Renamed"}`
+			if tt.method == "DELETE" {
+				body = `{"reason": "The pitch is closed"}`
+			}
+
+			req := httptest.NewRequest(tt.method, "/events/"+tt.eventID+"/occurrences?scope="+tt.scope, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.eventID)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(context.WithValue(ctx, userCtx, &store.User{ID: tt.userID}))
+
+			w := httptest.NewRecorder()
+			if tt.method == "PUT" {
+				app.updateOccurrenceHandler(w, req)
+			} else {
+				app.deleteOccurrenceHandler(w, req)
+			}
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+)
+
+type TransferEventPayload struct {
+	UserID int64 `json:"user_id" validate:"required,gt=0"`
+}
+
+// transferEventHandler godoc
+//
+//	@Summary		Offer the event to another participant
+//	@Description	Proposes a participant as the new owner. Nothing changes until they accept. A new offer replaces the pending one. Only the event owner can transfer it
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Event ID"
+//	@Param			payload	body		TransferEventPayload	true	"Proposed owner"
+//	@Success		201		{object}	store.EventTransfer
+//	@Failure		400		{object}	error	"Invalid payload or the user isn't a participant"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"User is not the event owner"
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/transfer [post]
+func (app *application) transferEventHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TransferEventPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	event, user, ok := app.authorizeEvent(w, r, permTransferOwnership)
+	if !ok {
+		return
+	}
+
+	if payload.UserID == user.ID {
+		app.badRequestResponse(w, r, errors.New("you already own this event"))
+		return
+	}
+
+	transfer := &store.EventTransfer{EventID: event.ID, FromUserID: user.ID, ToUserID: payload.UserID}
+	if err := app.store.Events.ProposeTransfer(r.Context(), transfer); err != nil {
+		switch err {
+		case store.ErrNotJoined:
+			app.badRequestResponse(w, r, err)
+		case store.ErrForbidden:
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.notify(r.Context(), &store.Notification{
+		UserID:  payload.UserID,
+		EventID: &event.ID,
+		Type:    store.NotificationTransferProposed,
+		Message: fmt.Sprintf("%s wants you to take over %q", participantName(event, user.ID), eventName(event)),
+	})
+
+	if err := app.jsonResponse(w, http.StatusCreated, transfer); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getEventTransferHandler godoc
+//
+//	@Summary		Get the pending transfer of an event
+//	@Description	Returns the ownership offer waiting for an answer. Only the event owner and the proposed owner can see it
+//	@Tags			events
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	store.EventTransfer
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error	"Event not found or no pending transfer"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/transfer [get]
+func (app *application) getEventTransferHandler(w http.ResponseWriter, r *http.Request) {
+	event, user, ok := app.loadEvent(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := app.store.Events.GetPendingTransfer(r.Context(), event.ID)
+	if err != nil {
+		switch err {
+		case store.ErrTransferNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Nobody else needs to know
+	if !can(event, user.ID, permTransferOwnership) && user.ID != transfer.ToUserID {
+		app.notFoundResponse(w, r, store.ErrTransferNotFound)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, transfer); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// cancelEventTransferHandler godoc
+//
+//	@Summary		Withdraw an ownership offer
+//	@Description	Cancels the pending transfer of the event. Only the event owner can withdraw it
+//	@Tags			events
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error	"User is not the event owner"
+//	@Failure		404	{object}	error	"Event not found or no pending transfer"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/transfer [delete]
+func (app *application) cancelEventTransferHandler(w http.ResponseWriter, r *http.Request) {
+	event, _, ok := app.authorizeEvent(w, r, permTransferOwnership)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Events.CancelTransfer(r.Context(), event.ID); err != nil {
+		switch err {
+		case store.ErrTransferNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]string{"message": "Transfer cancelled"}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// acceptEventTransferHandler godoc
+//
+//	@Summary		Accept an ownership offer
+//	@Description	Makes the proposed owner the owner of the event. The previous owner stays a participant and is notified
+//	@Tags			events
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	store.EventTransfer
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error	"Event not found or no transfer offered to the user"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/transfer/accept [post]
+func (app *application) acceptEventTransferHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondEventTransfer(w, r, true)
+}
+
+// declineEventTransferHandler godoc
+//
+//	@Summary		Decline an ownership offer
+//	@Description	Turns down the transfer offered to the user. The owner is notified
+//	@Tags			events
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	store.EventTransfer
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error	"Event not found or no transfer offered to the user"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/transfer/decline [post]
+func (app *application) declineEventTransferHandler(w http.ResponseWriter, r *http.Request) {
+	app.respondEventTransfer(w, r, false)
+}
+
+// respondEventTransfer answers the transfer offered to the user. The store
+// only finds transfers offered to them, so no other check is needed.
+func (app *application) respondEventTransfer(w http.ResponseWriter, r *http.Request, accept bool) {
+	event, user, ok := app.loadEvent(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := app.store.Events.RespondTransfer(r.Context(), event.ID, user.ID, accept)
+	if err != nil {
+		switch err {
+		case store.ErrTransferNotFound:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	kind, action := store.NotificationTransferDeclined, "declined to take over"
+	if accept {
+		kind, action = store.NotificationTransferAccepted, "took over"
+		app.logger.Infow("Transferred event", "event_id", event.ID, "from_user_id", transfer.FromUserID, "to_user_id", user.ID)
+	}
+	app.notify(r.Context(), &store.Notification{
+		UserID:  transfer.FromUserID,
+		EventID: &event.ID,
+		Type:    kind,
+		Message: fmt.Sprintf("%s %s %q", participantName(event, user.ID), action, eventName(event)),
+	})
+
+	if err := app.jsonResponse(w, http.StatusOK, transfer); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// participantName returns the full name of a participant of the event.
+func participantName(event *store.Event, userID int64) string {
+	if p := findParticipant(event, userID); p != nil {
+		return p.FirstName + " " + p.LastName
+	}
+	return "A player"
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		userID         int64
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner offers the event", userID: 1, body: `{"user_id": 4}`, expectedStatus: http.StatusCreated},
+		{name: "organizer can't transfer", userID: 3, body: `{"user_id": 4}`, expectedStatus: http.StatusForbidden},
+		{name: "participant can't transfer", userID: 4, body: `{"user_id": 3}`, expectedStatus: http.StatusForbidden},
+		{name: "offer to self", userID: 1, body: `{"user_id": 1}`, expectedStatus: http.StatusBadRequest},
+		{name: "offer to non participant", userID: 1, body: `{"user_id": 2}`, expectedStatus: http.StatusBadRequest},
+		{name: "missing user", userID: 1, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "unauthorized", body: `{"user_id": 4}`, expectedStatus: http.StatusUnauthorized},
+	}
+
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.created = nil
+			req := newChatRequest("POST", tt.body, tt.userID, map[string]string{"id": "6"})
+			w := httptest.NewRecorder()
+			app.transferEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response struct {
+					Data store.EventTransfer `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(4), response.Data.ToUserID)
+				assert.Equal(t, store.TransferPending, response.Data.Status)
+
+				if assert.Len(t, notifications.created, 1) {
+					assert.Equal(t, int64(4), notifications.created[0].UserID)
+					assert.Equal(t, store.NotificationTransferProposed, notifications.created[0].Type)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEventTransferHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "owner sees the offer", eventID: "6", userID: 1, expectedStatus: http.StatusOK},
+		{name: "recipient sees the offer", eventID: "6", userID: 3, expectedStatus: http.StatusOK},
+		{name: "other participants don't", eventID: "6", userID: 4, expectedStatus: http.StatusNotFound},
+		{name: "not a participant", eventID: "6", userID: 2, expectedStatus: http.StatusNotFound},
+		{name: "no pending transfer", eventID: "1", userID: 1, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("GET", "", tt.userID, map[string]string{"id": tt.eventID})
+			w := httptest.NewRecorder()
+			app.getEventTransferHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestRespondEventTransferHandlers(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		eventID        string
+		userID         int64
+		expectedStatus int
+		transferStatus string
+		expectedNotice string
+	}{
+		{
+			name:           "recipient accepts",
+			handler:        app.acceptEventTransferHandler,
+			eventID:        "6",
+			userID:         3,
+			expectedStatus: http.StatusOK,
+			transferStatus: store.TransferAccepted,
+			expectedNotice: store.NotificationTransferAccepted,
+		},
+		{
+			name:           "recipient declines",
+			handler:        app.declineEventTransferHandler,
+			eventID:        "6",
+			userID:         3,
+			expectedStatus: http.StatusOK,
+			transferStatus: store.TransferDeclined,
+			expectedNotice: store.NotificationTransferDeclined,
+		},
+		{
+			name:           "not offered to the user",
+			handler:        app.acceptEventTransferHandler,
+			eventID:        "6",
+			userID:         4,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no pending transfer",
+			handler:        app.acceptEventTransferHandler,
+			eventID:        "1",
+			userID:         1,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "not a participant",
+			handler:        app.acceptEventTransferHandler,
+			eventID:        "6",
+			userID:         2,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	notifications := app.store.Notifications.(*mockNotificationStore)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications.created = nil
+			req := newChatRequest("POST", "", tt.userID, map[string]string{"id": tt.eventID})
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data store.EventTransfer `json:"data"`
+				}
+				err := json.NewDecoder(w.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.transferStatus, response.Data.Status)
+
+				// The previous owner hears back
+				if assert.Len(t, notifications.created, 1) {
+					assert.Equal(t, int64(1), notifications.created[0].UserID)
+					assert.Equal(t, tt.expectedNotice, notifications.created[0].Type)
+				}
+			} else {
+				assert.Empty(t, notifications.created)
+			}
+		})
+	}
+}
+
+func TestCancelEventTransferHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "owner withdraws the offer", eventID: "6", userID: 1, expectedStatus: http.StatusOK},
+		{name: "recipient can't withdraw it", eventID: "6", userID: 3, expectedStatus: http.StatusForbidden},
+		{name: "no pending transfer", eventID: "1", userID: 1, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newChatRequest("DELETE", "", tt.userID, map[string]string{"id": tt.eventID})
+			w := httptest.NewRecorder()
+			app.cancelEventTransferHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
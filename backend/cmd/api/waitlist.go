@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// joinWaitlist puts the user on the waitlist of a full event and reports the
+// position they were given, or lets them in if a spot opened in the meantime.
+func (app *application) joinWaitlist(w http.ResponseWriter, r *http.Request, eventID int64, user *store.User, inviteHash string) {
+	entry, err := app.store.Events.JoinWaitlist(r.Context(), eventID, user.ID, inviteHash)
+	if err != nil {
+		switch err {
+		case store.ErrEventNotFound:
+			app.notFoundResponse(w, r, err)
+		case store.ErrAlreadyJoined, store.ErrAlreadyWaitlisted:
+			app.conflictResponse(w, r, err)
+		case store.ErrApprovalRequired:
+			app.requestJoin(w, r, eventID, user, inviteHash)
+		case store.ErrInviteRequired:
+			app.forbiddenResponse(w, r)
+		case store.ErrInvalidInvite:
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if entry == nil {
+		app.joined(w, r, eventID, user)
+		return
+	}
+
+	response := map[string]any{
+		"message":  "The event is full, you have been added to the waitlist",
+		"position": entry.Position,
+	}
+	if err := app.jsonResponse(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getEventWaitlistHandler godoc
+//
+//	@Summary		Get the waitlist of an event
+//	@Description	Returns the users waiting for a spot in the event, in the order they will be promoted
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{array}		store.WaitlistEntry
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/waitlist [get]
+func (app *application) getEventWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	event, ok := app.loadVisibleEvent(w, r)
+	if !ok {
+		return
+	}
+
+	waitlist, err := app.store.Events.GetWaitlist(r.Context(), event.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, waitlist); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// leaveEventWaitlistHandler godoc
+//
+//	@Summary		Leave the waitlist of an event
+//	@Description	Removes the authenticated user from the waitlist of an event
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	map[string]string
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/events/{id}/waitlist [delete]
+func (app *application) leaveEventWaitlistHandler(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUserFromContext(r)
+	if user == nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	if err := app.store.Events.LeaveWaitlist(r.Context(), eventID, user.ID); err != nil {
+		switch err {
+		case store.ErrNotWaitlisted:
+			app.notFoundResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]string{
+		"message": "You have left the waitlist",
+	}
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
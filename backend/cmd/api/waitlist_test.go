@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinFullEventHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name             string
+		userID           int64
+		expectedStatus   int
+		expectedPosition float64
+	}{
+		{
+			name:             "queued on the waitlist",
+			userID:           2,
+			expectedStatus:   http.StatusAccepted,
+			expectedPosition: 2,
+		},
+		{
+			name:           "a spot opened in the meantime",
+			userID:         4,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "already a participant",
+			userID:         1,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "already on the waitlist",
+			userID:         3,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/events/2/join", nil)
+			ctx := context.WithValue(req.Context(), userCtx, &store.User{ID: tt.userID})
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "2")
+			req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			app.joinEventHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusAccepted {
+				var response struct {
+					Data map[string]any `json:"data"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Equal(t, tt.expectedPosition, response.Data["position"])
+			}
+		})
+	}
+}
+
+func TestGetEventWaitlistHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		eventID        string
+		expectedStatus int
+	}{
+		{
+			name:           "valid waitlist retrieval",
+			eventID:        "2",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid event ID",
+			eventID:        "invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/events/"+tt.eventID+"/waitlist", nil)
+			ctx := context.WithValue(req.Context(), userCtx, &store.User{ID: 1})
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.eventID)
+			req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			app.getEventWaitlistHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var response struct {
+					Data []store.WaitlistEntry `json:"data"`
+				}
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+				assert.Len(t, response.Data, 1)
+				assert.Equal(t, 1, response.Data[0].Position)
+			}
+		})
+	}
+}
+
+func TestLeaveEventWaitlistHandler(t *testing.T) {
+	app := newTestApplication()
+
+	tests := []struct {
+		name           string
+		setupAuth      func(*http.Request)
+		expectedStatus int
+	}{
+		{
+			name: "leave the waitlist",
+			setupAuth: func(r *http.Request) {
+				ctx := context.WithValue(r.Context(), userCtx, &store.User{ID: 3})
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "not on the waitlist",
+			setupAuth: func(r *http.Request) {
+				ctx := context.WithValue(r.Context(), userCtx, &store.User{ID: 2})
+				*r = *r.WithContext(ctx)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unauthorized",
+			setupAuth:      func(r *http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/events/2/waitlist", nil)
+			tt.setupAuth(req)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "2")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			app.leaveEventWaitlistHandler(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_messages;
\ No newline at end of file
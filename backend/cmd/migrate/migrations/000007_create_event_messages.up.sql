@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS event_messages (
+    id BIGSERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    username TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_messages_event_id ON event_messages (event_id, id DESC);
\ No newline at end of file
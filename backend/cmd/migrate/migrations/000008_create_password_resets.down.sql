@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS token_version;
\ No newline at end of file
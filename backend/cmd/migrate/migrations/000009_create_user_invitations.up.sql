@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_invitations (
+    token TEXT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_invitations_user_id ON user_invitations (user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_waitlist;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS event_waitlist (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    position INT NOT NULL CHECK (position > 0),
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_waitlist_event_id_position ON event_waitlist (event_id, position);
\ No newline at end of file
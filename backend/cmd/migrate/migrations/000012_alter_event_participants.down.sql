@@ -0,0 +1 @@
+ALTER TABLE event_participants DROP CONSTRAINT IF EXISTS event_participants_event_id_user_id_key;
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Drop duplicate rows left behind by concurrent joins before enforcing uniqueness
+DELETE FROM event_participants a
+USING event_participants b
+WHERE a.event_id = b.event_id AND a.user_id = b.user_id AND a.id > b.id;
+
+ALTER TABLE event_participants
+    ADD CONSTRAINT event_participants_event_id_user_id_key UNIQUE (event_id, user_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE events DROP COLUMN IF EXISTS series_id;
+DROP TABLE IF EXISTS event_series;
\ No newline at end of file
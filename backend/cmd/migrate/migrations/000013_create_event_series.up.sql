@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS event_series (
+    id SERIAL PRIMARY KEY,
+    event_owner INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('weekly', 'biweekly', 'monthly')),
+    starts_at TIMESTAMP NOT NULL,
+    until TIMESTAMP,
+    count INT CHECK (count > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE events
+ADD COLUMN series_id INT REFERENCES event_series(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_series_id ON events (series_id, event_datetime);
\ No newline at end of file
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS notifications;
+DROP INDEX IF EXISTS idx_events_status;
+ALTER TABLE events
+DROP COLUMN IF EXISTS cancelled_at,
+DROP COLUMN IF EXISTS cancellation_reason,
+DROP COLUMN IF EXISTS status;
\ No newline at end of file
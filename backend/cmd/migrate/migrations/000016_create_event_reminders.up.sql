@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS event_reminders (
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lead_minutes INT NOT NULL,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, user_id, lead_minutes)
+);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS chat_moderation_log;
+DROP TABLE IF EXISTS event_chat_mutes;
+ALTER TABLE event_messages
+DROP COLUMN IF EXISTS deleted_at,
+DROP COLUMN IF EXISTS edited_at;
\ No newline at end of file
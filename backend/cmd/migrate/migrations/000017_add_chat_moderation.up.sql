@@ -0,0 +1,25 @@
+ALTER TABLE event_messages
+ADD COLUMN edited_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN deleted_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS event_chat_mutes (
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_by INT REFERENCES users(id) ON DELETE SET NULL,
+    muted_until TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_moderation_log (
+    id BIGSERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    target_user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    message_id BIGINT REFERENCES event_messages(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_moderation_log_event_id ON chat_moderation_log (event_id, created_at DESC);
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- The first admin is promoted by hand:
+-- UPDATE users SET role = 'admin', token_version = token_version + 1 WHERE email = '...';
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_organizers;
\ No newline at end of file
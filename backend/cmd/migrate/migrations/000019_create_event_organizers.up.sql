@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS event_organizers (
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_transfers;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS event_transfers (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    from_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+-- An event has at most one pending transfer at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_transfers_pending ON event_transfers (event_id) WHERE status = 'pending';
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS event_invites;
+ALTER TABLE events
+DROP COLUMN IF EXISTS visibility;
\ No newline at end of file
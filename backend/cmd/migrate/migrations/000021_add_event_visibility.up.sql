@@ -0,0 +1,15 @@
+ALTER TABLE events
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'unlisted', 'private'));
+
+CREATE TABLE IF NOT EXISTS event_invites (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    max_uses INT NOT NULL CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_invites_event_id ON event_invites (event_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS event_join_requests;
+ALTER TABLE events
+DROP COLUMN IF EXISTS requires_approval;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE events
+ADD COLUMN requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS event_join_requests (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP(0) WITH TIME ZONE,
+    responded_by INT REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A user has at most one pending request per event
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_join_requests_pending ON event_join_requests (event_id, user_id) WHERE status = 'pending';
\ No newline at end of file
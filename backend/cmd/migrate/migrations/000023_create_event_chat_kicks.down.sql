@@ -0,0 +1 @@
+DROP TABLE IF EXISTS event_chat_kicks;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS event_chat_kicks (
+    event_id INT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kicked_by INT REFERENCES users(id) ON DELETE SET NULL,
+    kicked_until TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, user_id)
+);
\ No newline at end of file
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewOpaqueToken returns a random token to hand out to the user along with
+// the hash that should be stored in the database.
+func NewOpaqueToken() (plain string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	plain = hex.EncodeToString(b)
+	return plain, HashToken(plain), nil
+}
+
+// HashToken hashes an opaque token for storage and lookup.
+func HashToken(plain string) string {
+	hash := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(hash[:])
+}
+
+// Sign returns the HMAC-SHA256 of data under secret, hex encoded, so tokens
+// handed out can be checked for tampering before they are looked up.
+func Sign(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the Sign of data under secret.
+func VerifySignature(secret, data, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, data)), []byte(signature))
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOpaqueToken(t *testing.T) {
+	plain, hash, err := NewOpaqueToken()
+	assert.NoError(t, err)
+	assert.Len(t, plain, 64)
+	assert.Equal(t, HashToken(plain), hash)
+	assert.NotEqual(t, plain, hash)
+
+	other, _, err := NewOpaqueToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, plain, other, "Tokens should be random")
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", "invite:1:abc")
+	assert.Len(t, signature, 64)
+	assert.True(t, VerifySignature("secret", "invite:1:abc", signature))
+
+	assert.False(t, VerifySignature("other", "invite:1:abc", signature), "Another secret should not verify")
+	assert.False(t, VerifySignature("secret", "invite:2:abc", signature), "Other data should not verify")
+	assert.False(t, VerifySignature("secret", "invite:1:abc", ""), "An empty signature should not verify")
+}
@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -45,3 +46,87 @@ func TestGetInt(t *testing.T) {
 	result = GetInt(keyInvalid, 10)
 	assert.Equal(t, 10, result, "Expected the fallback value when conversion fails")
 }
+
+func TestGetBool(t *testing.T) {
+	key := "TEST_BOOL"
+	os.Setenv(key, "false")
+	defer os.Unsetenv(key)
+
+	// Test when env variable is set
+	result := GetBool(key, true)
+	assert.False(t, result, "Expected the boolean value from environment variable")
+
+	// Test when env variable is not set
+	result = GetBool("NON_EXISTENT_KEY", true)
+	assert.True(t, result, "Expected the fallback value")
+
+	// Test when env variable contains an invalid boolean
+	keyInvalid := "TEST_INVALID_BOOL"
+	os.Setenv(keyInvalid, "maybe")
+	defer os.Unsetenv(keyInvalid)
+
+	result = GetBool(keyInvalid, true)
+	assert.True(t, result, "Expected the fallback value when parsing fails")
+}
+
+func TestGetDuration(t *testing.T) {
+	key := "TEST_DURATION"
+	os.Setenv(key, "90s")
+	defer os.Unsetenv(key)
+
+	// Test when env variable is set
+	result := GetDuration(key, time.Minute)
+	assert.Equal(t, 90*time.Second, result, "Expected the duration from environment variable")
+
+	// Test when env variable is not set
+	result = GetDuration("NON_EXISTENT_KEY", time.Minute)
+	assert.Equal(t, time.Minute, result, "Expected the fallback value")
+
+	// Test when env variable contains an invalid duration
+	keyInvalid := "TEST_INVALID_DURATION"
+	os.Setenv(keyInvalid, "soon")
+	defer os.Unsetenv(keyInvalid)
+
+	result = GetDuration(keyInvalid, time.Minute)
+	assert.Equal(t, time.Minute, result, "Expected the fallback value when parsing fails")
+}
+
+func TestGetDurations(t *testing.T) {
+	fallback := []time.Duration{time.Hour}
+
+	key := "TEST_DURATIONS"
+	os.Setenv(key, "24h, 1h")
+	defer os.Unsetenv(key)
+
+	// Test when env variable is set
+	result := GetDurations(key, fallback)
+	assert.Equal(t, []time.Duration{24 * time.Hour, time.Hour}, result, "Expected every duration of the list")
+
+	// Test when env variable is not set
+	result = GetDurations("NON_EXISTENT_KEY", fallback)
+	assert.Equal(t, fallback, result, "Expected the fallback value")
+
+	// Test when one of the durations is invalid
+	keyInvalid := "TEST_INVALID_DURATIONS"
+	os.Setenv(keyInvalid, "24h,soon")
+	defer os.Unsetenv(keyInvalid)
+
+	result = GetDurations(keyInvalid, fallback)
+	assert.Equal(t, fallback, result, "Expected the fallback value when parsing fails")
+}
+
+func TestGetStrings(t *testing.T) {
+	fallback := []string{"127.0.0.1"}
+
+	key := "TEST_STRINGS"
+	os.Setenv(key, "10.0.0.0/8, ,127.0.0.1")
+	defer os.Unsetenv(key)
+
+	// Test when env variable is set
+	result := GetStrings(key, fallback)
+	assert.Equal(t, []string{"10.0.0.0/8", "127.0.0.1"}, result, "Expected every non-blank entry of the list")
+
+	// Test when env variable is not set
+	result = GetStrings("NON_EXISTENT_KEY", fallback)
+	assert.Equal(t, fallback, result, "Expected the fallback value")
+}
@@ -0,0 +1,182 @@
+// Package ical renders events as an iCalendar (RFC 5545) document.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+)
+
+// DefaultEventDuration is used for DTEND since events only record when they
+// start.
+const DefaultEventDuration = 2 * time.Hour
+
+const (
+	prodID     = "-//Sportify//Events//EN"
+	timeLayout = "20060102T150405Z"
+	// maxLineOctets is the longest a content line may be before it has to be
+	// folded.
+	maxLineOctets = 75
+)
+
+// Calendar is a named collection of events.
+type Calendar struct {
+	Name   string
+	Events []*store.Event
+	// Domain makes the UID of every event globally unique.
+	Domain string
+	// Now is used for DTSTAMP, it defaults to the current time.
+	Now time.Time
+}
+
+// WriteTo writes the calendar to w.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	cw := &writer{w: bufio.NewWriter(w)}
+
+	now := c.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		c.writeEvent(cw, e, now)
+	}
+
+	cw.line("END:VCALENDAR")
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (c *Calendar) writeEvent(cw *writer, e *store.Event, now time.Time) {
+	summary := e.Title
+	if summary == "" {
+		summary = e.Sport
+	}
+
+	description := "Sport: " + e.Sport
+	if e.Description != "" {
+		description += "\n\n" + e.Description
+	}
+
+	cw.line("BEGIN:VEVENT")
+	cw.line(fmt.Sprintf("UID:event-%d@%s", e.ID, c.Domain))
+	cw.line("DTSTAMP:" + formatTime(now))
+	cw.line("DTSTART:" + formatTime(e.EventDateTime))
+	cw.line("DTEND:" + formatTime(e.EventDateTime.Add(DefaultEventDuration)))
+	if !e.UpdatedAt.IsZero() {
+		cw.line("LAST-MODIFIED:" + formatTime(e.UpdatedAt))
+	}
+	cw.line("SUMMARY:" + escapeText(summary))
+	cw.line("DESCRIPTION:" + escapeText(description))
+	cw.line("CATEGORIES:" + escapeText(e.Sport))
+	cw.line("LOCATION:" + escapeText(e.LocationName))
+	cw.line(fmt.Sprintf("GEO:%f;%f", e.Latitude, e.Longitude))
+	cw.line("STATUS:" + status(e))
+	if e.OwnerEmail != "" {
+		cw.line(fmt.Sprintf("ORGANIZER;CN=%s:mailto:%s", quoteParam(fullName(e.OwnerFirstName, e.OwnerLastName)), e.OwnerEmail))
+	}
+	for _, p := range e.Participants {
+		// Participants' emails aren't shared with other players, so they are
+		// addressed by user id instead
+		cw.line(fmt.Sprintf("ATTENDEE;CN=%s;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:urn:x-sportify:user:%d",
+			quoteParam(fullName(p.FirstName, p.LastName)), p.UserID))
+	}
+	cw.line("END:VEVENT")
+}
+
+// writer writes CRLF terminated, folded content lines and keeps the first
+// error.
+type writer struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *writer) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(fold(s) + "\r\n")
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// fold splits lines longer than 75 octets, continuing them on the next line
+// after a single space. It never splits a UTF-8 sequence.
+func fold(s string) string {
+	if len(s) <= maxLineOctets {
+		return s
+	}
+
+	var b strings.Builder
+	limit := maxLineOctets
+	size := 0
+	for _, r := range s {
+		l := len(string(r))
+		if size+l > limit {
+			b.WriteString("\r\n ")
+			// The leading space counts towards the continuation line
+			limit = maxLineOctets - 1
+			size = 0
+		}
+		b.WriteRune(r)
+		size += l
+	}
+
+	return b.String()
+}
+
+// status maps the event status to the VEVENT one, so cancelled events are
+// struck out in subscribed calendars instead of silently disappearing.
+func status(e *store.Event) string {
+	if e.Status == store.EventStatusCancelled {
+		return "CANCELLED"
+	}
+	return "CONFIRMED"
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escapeText escapes a TEXT property value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// quoteParam makes s safe to use as a parameter value.
+func quoteParam(s string) string {
+	s = strings.NewReplacer(`"`, "", "\r", "", "\n", " ").Replace(s)
+	if strings.ContainsAny(s, ";:,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+func fullName(first, last string) string {
+	return strings.TrimSpace(first + " " + last)
+}
@@ -0,0 +1,94 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar_WriteTo(t *testing.T) {
+	start := time.Date(2025, 3, 4, 18, 30, 0, 0, time.UTC)
+	cal := &Calendar{
+		Name:   "My Sportify events",
+		Domain: "sportify.local",
+		Now:    time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+		Events: []*store.Event{
+			{
+				ID:             7,
+				Sport:          "Football",
+				Title:          "Tuesday pickup, bring water; cleats",
+				EventDateTime:  start,
+				LocationName:   "Central Park",
+				Latitude:       40.7829,
+				Longitude:      -73.9654,
+				Description:    "Line one\nLine two",
+				OwnerFirstName: "Owner",
+				OwnerLastName:  "User",
+				OwnerEmail:     "owner@example.com",
+				Participants: []store.EventParticipant{
+					{UserID: 2, FirstName: "Test", LastName: "User"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	n, err := cal.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	// Unfold long lines before looking for properties
+	out := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+
+	for _, line := range []string{
+		"X-WR-CALNAME:My Sportify events",
+		"UID:event-7@sportify.local",
+		"DTSTAMP:20250301T090000Z",
+		"DTSTART:20250304T183000Z",
+		"DTEND:20250304T203000Z",
+		`SUMMARY:Tuesday pickup\, bring water\; cleats`,
+		`DESCRIPTION:Sport: Football\n\nLine one\nLine two`,
+		"LOCATION:Central Park",
+		"GEO:40.782900;-73.965400",
+		"STATUS:CONFIRMED",
+		"ORGANIZER;CN=Owner User:mailto:owner@example.com",
+		"ATTENDEE;CN=Test User;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:urn:x-sportify:user:2",
+	} {
+		assert.Contains(t, out, line+"\r\n")
+	}
+}
+
+func TestCalendar_WriteTo_Cancelled(t *testing.T) {
+	cal := &Calendar{
+		Domain: "sportify.local",
+		Events: []*store.Event{
+			{ID: 7, Sport: "Football", EventDateTime: time.Now(), Status: store.EventStatusCancelled},
+		},
+	}
+
+	var buf bytes.Buffer
+	_, err := cal.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "STATUS:CANCELLED\r\n")
+}
+
+func TestFold(t *testing.T) {
+	short := strings.Repeat("a", maxLineOctets)
+	assert.Equal(t, short, fold(short))
+
+	long := "DESCRIPTION:" + strings.Repeat("é", 100)
+	folded := fold(long)
+	for _, line := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(line), maxLineOctets)
+	}
+
+	// Unfolding gives back the original line
+	assert.Equal(t, long, strings.ReplaceAll(folded, "\r\n ", ""))
+}
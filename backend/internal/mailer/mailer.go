@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// LogMailer writes every email to w instead of delivering it. It is meant for
+// local development (w = os.Stdout or a file) and tests (w = bytes.Buffer).
+type LogMailer struct {
+	from string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{
+		from: from,
+		w:    w,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s\n\n",
+		m.from, to, time.Now().Format(time.RFC1123Z), subject, body)
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf, "no-reply@sportify.test")
+
+	err := m.Send(context.Background(), "user@example.com", "Hello", "Body text")
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "From: no-reply@sportify.test")
+	assert.Contains(t, out, "To: user@example.com")
+	assert.Contains(t, out, "Subject: Hello")
+	assert.Contains(t, out, "Body text")
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a caller, identified by key, may go ahead.
+type Limiter interface {
+	// Allow takes a token for key. When none is left it returns false and
+	// how long until the next one is available.
+	Allow(key string) (bool, time.Duration)
+}
+
+// Config allows Requests per Window for each key, all at once at most.
+type Config struct {
+	Requests int
+	Window   time.Duration
+}
+
+// TokenBucket gives every key a bucket of Requests tokens, refilled evenly
+// over Window. Buckets are kept in memory, so each API instance counts on
+// its own.
+type TokenBucket struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	window    time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(cfg Config) *TokenBucket {
+	return &TokenBucket{
+		rate:      float64(cfg.Requests) / cfg.Window.Seconds(),
+		burst:     float64(cfg.Requests),
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		window:    cfg.Window,
+	}
+}
+
+func (tb *TokenBucket) Allow(key string) (bool, time.Duration) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.sweep(now)
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: tb.burst, last: now}
+		tb.buckets[key] = b
+	}
+
+	b.tokens = tb.refill(b, now)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / tb.rate * float64(time.Second))
+	return false, wait
+}
+
+// refill returns the tokens of the bucket at now.
+func (tb *TokenBucket) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*tb.rate
+	if tokens > tb.burst {
+		return tb.burst
+	}
+	return tokens
+}
+
+// sweep forgets the buckets that have filled up again, at most once per
+// window, so keys that stopped calling don't pile up. The caller must hold
+// tb.mu.
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Sub(tb.lastSweep) < tb.window {
+		return
+	}
+	tb.lastSweep = now
+
+	for key, b := range tb.buckets {
+		if tb.refill(b, now) >= tb.burst {
+			delete(tb.buckets, key)
+		}
+	}
+}
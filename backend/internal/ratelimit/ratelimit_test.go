@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBucket returns a bucket driven by the returned clock
+func newTestBucket(cfg Config) (*TokenBucket, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tb := NewTokenBucket(cfg)
+	tb.now = func() time.Time { return now }
+	tb.lastSweep = now
+	return tb, &now
+}
+
+func TestTokenBucket_Allow(t *testing.T) {
+	tb, now := newTestBucket(Config{Requests: 3, Window: 3 * time.Second})
+
+	// The whole burst is available at once
+	for i := 0; i < 3; i++ {
+		ok, _ := tb.Allow("user:1")
+		assert.True(t, ok, "request %d should be allowed", i+1)
+	}
+
+	ok, wait := tb.Allow("user:1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	// Other keys have their own bucket
+	ok, _ = tb.Allow("user:2")
+	assert.True(t, ok)
+
+	// One token comes back every second
+	*now = now.Add(time.Second)
+	ok, _ = tb.Allow("user:1")
+	assert.True(t, ok)
+	ok, _ = tb.Allow("user:1")
+	assert.False(t, ok)
+}
+
+func TestTokenBucket_RefillCapped(t *testing.T) {
+	tb, now := newTestBucket(Config{Requests: 2, Window: time.Minute})
+
+	tb.Allow("ip:10.0.0.1")
+	*now = now.Add(time.Hour)
+
+	// A long pause doesn't allow more than the burst
+	for i := 0; i < 2; i++ {
+		ok, _ := tb.Allow("ip:10.0.0.1")
+		assert.True(t, ok)
+	}
+	ok, wait := tb.Allow("ip:10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+}
+
+func TestTokenBucket_Sweep(t *testing.T) {
+	tb, now := newTestBucket(Config{Requests: 2, Window: time.Minute})
+
+	tb.Allow("user:1")
+	tb.Allow("user:2")
+	tb.Allow("user:2")
+	assert.Len(t, tb.buckets, 2)
+
+	// After a window both buckets are full again and forgotten, only the
+	// new one is left
+	*now = now.Add(time.Minute)
+	tb.Allow("user:3")
+	assert.Len(t, tb.buckets, 1)
+}
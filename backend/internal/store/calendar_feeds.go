@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrInvalidFeedToken = errors.New("calendar feed token is invalid")
+)
+
+// CalendarFeedStore keeps the token calendar apps use to subscribe to a
+// user's schedule. A user has at most one token, so issuing a new one revokes
+// the previous.
+type CalendarFeedStore struct {
+	db *sql.DB
+}
+
+// Rotate replaces the user's feed token with the one matching tokenHash.
+func (s *CalendarFeedStore) Rotate(ctx context.Context, userID int64, tokenHash string) error {
+	query := `
+		INSERT INTO calendar_feed_tokens (user_id, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, created_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tokenHash)
+	return err
+}
+
+// Revoke deletes the user's feed token, if any.
+func (s *CalendarFeedStore) Revoke(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM calendar_feed_tokens WHERE user_id = $1`, userID)
+	return err
+}
+
+// GetUserID returns the user the feed token belongs to.
+func (s *CalendarFeedStore) GetUserID(ctx context.Context, tokenHash string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `SELECT user_id FROM calendar_feed_tokens WHERE token_hash = $1`, tokenHash).Scan(&userID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return 0, ErrInvalidFeedToken
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
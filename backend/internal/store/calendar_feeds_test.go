@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Rotate replaces the user's token in place
+func TestCalendarFeedStore_Rotate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &CalendarFeedStore{db: db}
+
+	mock.ExpectExec(`INSERT INTO calendar_feed_tokens \(user_id, token_hash\) VALUES \(\$1, \$2\) ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(int64(1), "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := store.Rotate(context.Background(), 1, "hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetUserID resolves a token and rejects unknown ones
+func TestCalendarFeedStore_GetUserID(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &CalendarFeedStore{db: db}
+
+	mock.ExpectQuery(`SELECT user_id FROM calendar_feed_tokens WHERE token_hash = \$1`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(7))
+	mock.ExpectQuery(`SELECT user_id FROM calendar_feed_tokens WHERE token_hash = \$1`).
+		WithArgs("revoked").
+		WillReturnError(sql.ErrNoRows)
+
+	userID, err := store.GetUserID(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+
+	_, err = store.GetUserID(context.Background(), "revoked")
+	assert.Equal(t, ErrInvalidFeedToken, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
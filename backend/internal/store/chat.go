@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMessagePageSize = 50
+	MaxMessagePageSize     = 100
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrMuted           = errors.New("you are muted in this chat")
+)
+
+// Moderation actions recorded in chat_moderation_log
+const (
+	ModerationMessageEdited  = "message_edited"
+	ModerationMessageDeleted = "message_deleted"
+	ModerationUserMuted      = "user_muted"
+	ModerationUserUnmuted    = "user_unmuted"
+	ModerationUserKicked     = "user_kicked"
+)
+
+type ChatMessage struct {
+	ID        int64      `json:"id"`
+	EventID   int64      `json:"event_id"`
+	UserID    int64      `json:"user_id"`
+	Username  string     `json:"username"`
+	Content   string     `json:"content"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+const chatMessageColumns = `id, event_id, COALESCE(user_id, 0), username, content, edited_at, created_at`
+
+type ChatStore struct {
+	db *sql.DB
+}
+
+func NewChatStore(db *sql.DB) *ChatStore {
+	return &ChatStore{db: db}
+}
+
+// Create stores the message, or returns ErrMuted if its author is muted in
+// the event's chat.
+func (s *ChatStore) Create(ctx context.Context, msg *ChatMessage) error {
+	query := `
+		INSERT INTO event_messages (event_id, user_id, username, content)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM event_chat_mutes
+			WHERE event_id = $1 AND user_id = $2 AND muted_until > NOW()
+		)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		msg.EventID,
+		msg.UserID,
+		msg.Username,
+		msg.Content,
+	).Scan(
+		&msg.ID,
+		&msg.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return ErrMuted
+	}
+	return err
+}
+
+// GetByID returns a message that hasn't been deleted.
+func (s *ChatStore) GetByID(ctx context.Context, id int64) (*ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` FROM event_messages WHERE id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var m ChatMessage
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&m.ID, &m.EventID, &m.UserID, &m.Username, &m.Content, &m.EditedAt, &m.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Edit replaces the content of the message and records the previous one in
+// the moderation log.
+func (s *ChatStore) Edit(ctx context.Context, msg *ChatMessage, actorID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var previous string
+		var muted bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT m.content, EXISTS(
+				SELECT 1 FROM event_chat_mutes cm
+				WHERE cm.event_id = m.event_id AND cm.user_id = m.user_id AND cm.muted_until > NOW()
+			)
+			FROM event_messages m
+			WHERE m.id = $1 AND m.deleted_at IS NULL
+			FOR UPDATE OF m`, msg.ID).Scan(&previous, &muted)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrMessageNotFound
+			}
+			return err
+		}
+		if muted {
+			return ErrMuted
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			UPDATE event_messages SET content = $1, edited_at = NOW()
+			WHERE id = $2
+			RETURNING edited_at`, msg.Content, msg.ID).Scan(&msg.EditedAt)
+		if err != nil {
+			return err
+		}
+
+		return logModeration(ctx, tx, msg.EventID, actorID, msg.UserID, msg.ID, ModerationMessageEdited, previous)
+	})
+}
+
+// Delete hides the message from the chat. Its content is kept in the
+// moderation log.
+func (s *ChatStore) Delete(ctx context.Context, msg *ChatMessage, actorID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var content string
+		err := tx.QueryRowContext(ctx, `
+			UPDATE event_messages SET deleted_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING content`, msg.ID).Scan(&content)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrMessageNotFound
+			}
+			return err
+		}
+
+		return logModeration(ctx, tx, msg.EventID, actorID, msg.UserID, msg.ID, ModerationMessageDeleted, content)
+	})
+}
+
+// Mute stops the user from sending messages to the event's chat until the
+// given time, replacing any previous mute.
+func (s *ChatStore) Mute(ctx context.Context, eventID, userID, actorID int64, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO event_chat_mutes (event_id, user_id, muted_by, muted_until)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (event_id, user_id) DO UPDATE
+			SET muted_by = EXCLUDED.muted_by, muted_until = EXCLUDED.muted_until, created_at = NOW()`,
+			eventID, userID, actorID, until)
+		if err != nil {
+			return err
+		}
+
+		return logModeration(ctx, tx, eventID, actorID, userID, 0, ModerationUserMuted, fmt.Sprintf("until %s", until.UTC().Format(time.RFC3339)))
+	})
+}
+
+// Unmute lifts the user's mute in the event's chat, if any.
+func (s *ChatStore) Unmute(ctx context.Context, eventID, userID, actorID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM event_chat_mutes WHERE event_id = $1 AND user_id = $2`, eventID, userID)
+		if err != nil {
+			return err
+		}
+
+		return logModeration(ctx, tx, eventID, actorID, userID, 0, ModerationUserUnmuted, "")
+	})
+}
+
+// Kick keeps the user out of the event's chat until the given time, replacing
+// any previous kick. Closing their open connections is left to the hub.
+func (s *ChatStore) Kick(ctx context.Context, eventID, userID, actorID int64, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO event_chat_kicks (event_id, user_id, kicked_by, kicked_until)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (event_id, user_id) DO UPDATE
+			SET kicked_by = EXCLUDED.kicked_by, kicked_until = EXCLUDED.kicked_until, created_at = NOW()`,
+			eventID, userID, actorID, until)
+		if err != nil {
+			return err
+		}
+
+		return logModeration(ctx, tx, eventID, actorID, userID, 0, ModerationUserKicked, fmt.Sprintf("until %s", until.UTC().Format(time.RFC3339)))
+	})
+}
+
+// IsKicked reports whether the user was kicked from the event's chat and may
+// not reconnect yet.
+func (s *ChatStore) IsKicked(ctx context.Context, eventID, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var kicked bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM event_chat_kicks
+			WHERE event_id = $1 AND user_id = $2 AND kicked_until > NOW()
+		)`, eventID, userID).Scan(&kicked)
+	return kicked, err
+}
+
+// logModeration adds an entry to the moderation log. A messageID of 0 means
+// the action isn't about a message.
+func logModeration(ctx context.Context, tx *sql.Tx, eventID, actorID, targetID, messageID int64, action, details string) error {
+	var message *int64
+	if messageID != 0 {
+		message = &messageID
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO chat_moderation_log (event_id, actor_id, target_user_id, message_id, action, details)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)`,
+		eventID, actorID, targetID, message, action, details)
+	return err
+}
+
+// GetByEvent returns up to limit messages of an event that are older than the
+// message with id before (or the newest ones when before is 0), in
+// chronological order.
+func (s *ChatStore) GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*ChatMessage, error) {
+	if limit <= 0 {
+		limit = DefaultMessagePageSize
+	}
+	if limit > MaxMessagePageSize {
+		limit = MaxMessagePageSize
+	}
+
+	query := `
+		SELECT ` + chatMessageColumns + `
+		FROM event_messages
+		WHERE event_id = $1 AND ($2 = 0 OR id < $2) AND deleted_at IS NULL
+		ORDER BY id DESC
+		LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, eventID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*ChatMessage{}
+	for rows.Next() {
+		var m ChatMessage
+		if err := rows.Scan(&m.ID, &m.EventID, &m.UserID, &m.Username, &m.Content, &m.EditedAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Rows come back newest first so the LIMIT keeps the latest page; flip
+	// them so callers can render top to bottom.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
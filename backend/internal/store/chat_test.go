@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Create Chat Message
+func TestChatStore_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	msg := &ChatMessage{
+		EventID:  1,
+		UserID:   2,
+		Username: "Test User",
+		Content:  "Hello",
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO event_messages \(event_id, user_id, username, content\)`).
+		WithArgs(msg.EventID, msg.UserID, msg.Username, msg.Content).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+
+	err := store.Create(context.Background(), msg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), msg.ID)
+	assert.Equal(t, now, msg.CreatedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Create Chat Message from a muted user
+func TestChatStore_Create_Muted(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	msg := &ChatMessage{EventID: 1, UserID: 2, Username: "Test User", Content: "Hello"}
+
+	mock.ExpectQuery(`INSERT INTO event_messages .* WHERE NOT EXISTS \( SELECT 1 FROM event_chat_mutes`).
+		WithArgs(msg.EventID, msg.UserID, msg.Username, msg.Content).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+	err := store.Create(context.Background(), msg)
+	assert.Equal(t, ErrMuted, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Get Messages by Event returns chronological order
+func TestChatStore_GetByEvent(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT id, event_id, COALESCE\(user_id, 0\), username, content, edited_at, created_at FROM event_messages.*deleted_at IS NULL`).
+		WithArgs(int64(1), int64(10), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "user_id", "username", "content", "edited_at", "created_at"}).
+			AddRow(9, 1, 2, "Test User", "second", now, now).
+			AddRow(8, 1, 2, "Test User", "first", nil, now.Add(-time.Minute)))
+
+	messages, err := store.GetByEvent(context.Background(), 1, 10, 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, int64(8), messages[0].ID)
+	assert.Equal(t, int64(9), messages[1].ID)
+	assert.Nil(t, messages[0].EditedAt)
+	assert.NotNil(t, messages[1].EditedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Get Messages by Event caps the page size
+func TestChatStore_GetByEvent_LimitCapped(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+
+	mock.ExpectQuery(`SELECT id, event_id, COALESCE\(user_id, 0\), username, content, edited_at, created_at FROM event_messages.*deleted_at IS NULL`).
+		WithArgs(int64(1), int64(0), MaxMessagePageSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "user_id", "username", "content", "edited_at", "created_at"}))
+
+	messages, err := store.GetByEvent(context.Background(), 1, 0, 1000)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Edit keeps the previous content in the moderation log
+func TestChatStore_Edit(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	msg := &ChatMessage{ID: 7, EventID: 1, UserID: 2, Content: "Hello all"}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT m.content, EXISTS\(.*event_chat_mutes.*\) FROM event_messages m WHERE m.id = \$1 AND m.deleted_at IS NULL FOR UPDATE OF m`).
+		WithArgs(msg.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"content", "muted"}).AddRow("Hello", false))
+	mock.ExpectQuery(`UPDATE event_messages SET content = \$1, edited_at = NOW\(\)`).
+		WithArgs("Hello all", msg.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"edited_at"}).AddRow(now))
+	mock.ExpectExec(`INSERT INTO chat_moderation_log`).
+		WithArgs(msg.EventID, int64(2), msg.UserID, &msg.ID, ModerationMessageEdited, "Hello").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Edit(context.Background(), msg, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, now, *msg.EditedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Edit refuses to change the messages of a muted author
+func TestChatStore_Edit_Muted(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	msg := &ChatMessage{ID: 7, EventID: 1, UserID: 2, Content: "Hello all"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT m.content, EXISTS\(`).
+		WithArgs(msg.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"content", "muted"}).AddRow("Hello", true))
+	mock.ExpectRollback()
+
+	err := store.Edit(context.Background(), msg, 2)
+	assert.Equal(t, ErrMuted, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Delete of a message that is already gone
+func TestChatStore_Delete_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	msg := &ChatMessage{ID: 7, EventID: 1, UserID: 2}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE event_messages SET deleted_at = NOW\(\)`).
+		WithArgs(msg.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"content"}))
+	mock.ExpectRollback()
+
+	err := store.Delete(context.Background(), msg, 1)
+	assert.Equal(t, ErrMessageNotFound, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Kick keeps the user out until the given time and logs it
+func TestChatStore_Kick(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	until := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO event_chat_kicks .* ON CONFLICT \(event_id, user_id\) DO UPDATE`).
+		WithArgs(int64(1), int64(3), int64(2), until).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO chat_moderation_log`).
+		WithArgs(int64(1), int64(2), int64(3), nil, ModerationUserKicked, "until 2026-05-01T18:00:00Z").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Kick(context.Background(), 1, 3, 2, until)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test IsKicked only counts kicks that haven't run out
+func TestChatStore_IsKicked(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+
+	mock.ExpectQuery(`SELECT EXISTS\(\s+SELECT 1 FROM event_chat_kicks\s+WHERE event_id = \$1 AND user_id = \$2 AND kicked_until > NOW\(\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	kicked, err := store.IsKicked(context.Background(), 1, 3)
+	assert.NoError(t, err)
+	assert.True(t, kicked)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Mute replaces any previous mute and logs without a message
+func TestChatStore_Mute(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := NewChatStore(db)
+	until := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO event_chat_mutes .* ON CONFLICT \(event_id, user_id\) DO UPDATE`).
+		WithArgs(int64(1), int64(3), int64(2), until).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO chat_moderation_log`).
+		WithArgs(int64(1), int64(2), int64(3), nil, ModerationUserMuted, "until 2026-05-01T18:00:00Z").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := store.Mute(context.Background(), 1, 3, 2, until)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
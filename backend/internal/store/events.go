@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -14,6 +16,16 @@ var (
 	ErrAlreadyJoined = errors.New("user has already joined this event")
 	ErrNotJoined     = errors.New("user is not a participant of this event")
 	ErrForbidden     = errors.New("user is not the event owner")
+	ErrEventFull     = errors.New("event is already full")
+	// ErrEventNotScheduled is returned when changing an event that was
+	// cancelled or has already taken place.
+	ErrEventNotScheduled = errors.New("event is no longer scheduled")
+)
+
+const (
+	EventStatusScheduled = "scheduled"
+	EventStatusCancelled = "cancelled"
+	EventStatusCompleted = "completed"
 )
 
 type EventParticipant struct {
@@ -26,24 +38,33 @@ type EventParticipant struct {
 }
 
 type Event struct {
-	ID              int64              `json:"id"`
-	EventOwner      int64              `json:"event_owner"`
-	OwnerFirstName  string             `json:"owner_first_name"`
-	OwnerLastName   string             `json:"owner_last_name"`
-	OwnerEmail      string             `json:"owner_email"`
-	Sport           string             `json:"sport"`
-	EventDateTime   time.Time          `json:"event_datetime"`
-	MaxPlayers      int                `json:"max_players"`
-	LocationName    string             `json:"location_name"`
-	Latitude        float64            `json:"latitude"`
-	Longitude       float64            `json:"longitude"`
-	Description     string             `json:"description"`
-	Title           string             `json:"title"`
-	IsFull          bool               `json:"is_full"`
-	CreatedAt       time.Time          `json:"created_at"`
-	UpdatedAt       time.Time          `json:"updated_at"`
-	RegisteredCount int                `json:"registered_count"`
-	Participants    []EventParticipant `json:"participants"`
+	ID                 int64              `json:"id"`
+	EventOwner         int64              `json:"event_owner"`
+	OwnerFirstName     string             `json:"owner_first_name"`
+	OwnerLastName      string             `json:"owner_last_name"`
+	OwnerEmail         string             `json:"owner_email"`
+	Sport              string             `json:"sport"`
+	EventDateTime      time.Time          `json:"event_datetime"`
+	MaxPlayers         int                `json:"max_players"`
+	LocationName       string             `json:"location_name"`
+	Latitude           float64            `json:"latitude"`
+	Longitude          float64            `json:"longitude"`
+	Description        string             `json:"description"`
+	Title              string             `json:"title"`
+	IsFull             bool               `json:"is_full"`
+	Status             string             `json:"status"`
+	Visibility         string             `json:"visibility"`
+	RequiresApproval   bool               `json:"requires_approval"`
+	CancellationReason *string            `json:"cancellation_reason,omitempty"`
+	CancelledAt        *time.Time         `json:"cancelled_at,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	RegisteredCount    int                `json:"registered_count"`
+	DistanceKm         *float64           `json:"distance_km,omitempty"`
+	SeriesID           *int64             `json:"series_id,omitempty"`
+	Participants       []EventParticipant `json:"participants"`
+	Organizers         []int64            `json:"organizers"` // user ids of the co-organizers
+	Waitlist           []WaitlistEntry    `json:"waitlist"`
 }
 
 type EventFilter struct {
@@ -55,9 +76,33 @@ type EventFilter struct {
 	AfterDate    *time.Time
 	BeforeDate   *time.Time
 	LocationName *string
-	SortBy       string
-	Order        string
+	// Status defaults to every event that wasn't cancelled.
+	Status *string
+	// Latitude and Longitude set the point distances are measured from.
+	// RadiusKm optionally drops every event farther away than that.
+	Latitude  *float64
+	Longitude *float64
+	RadiusKm  *float64
+	SortBy    string
+	Order     string
+	PageQuery
 }
+
+// earthRadiusKm is the mean radius used by the haversine formula.
+const earthRadiusKm = 6371.0
+
+// distanceKmSQL returns a haversine expression for the distance in km between
+// an event and the point bound to the latArg and lngArg placeholders. Rounding
+// can push the square root just past 1 for antipodal points, where ASIN would
+// fail, so it is capped.
+func distanceKmSQL(latArg, lngArg int) string {
+	return fmt.Sprintf(`(%[3]g * 2 * ASIN(LEAST(1, SQRT(
+			POWER(SIN(RADIANS(e.latitude::float8 - $%[1]d::float8) / 2), 2) +
+			COS(RADIANS($%[1]d::float8)) * COS(RADIANS(e.latitude::float8)) *
+			POWER(SIN(RADIANS(e.longitude::float8 - $%[2]d::float8) / 2), 2)
+		))))`, latArg, lngArg, earthRadiusKm)
+}
+
 type EventStore struct {
 	db *sql.DB
 }
@@ -71,10 +116,15 @@ func (s *EventStore) Create(ctx context.Context, event *Event) error {
 		INSERT INTO events (
 			event_owner, sport, event_datetime, max_players, 
 			location_name, latitude, longitude, description, 
-			title, is_full, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			title, is_full, created_at, updated_at, visibility,
+			requires_approval
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at, updated_at`
 
+	if event.Visibility == "" {
+		event.Visibility = VisibilityPublic
+	}
+
 	args := []interface{}{
 		event.EventOwner,
 		event.Sport,
@@ -88,6 +138,8 @@ func (s *EventStore) Create(ctx context.Context, event *Event) error {
 		false, // is_full starts as false
 		time.Now(),
 		time.Now(),
+		event.Visibility,
+		event.RequiresApproval,
 	}
 
 	return s.db.QueryRowContext(ctx, query, args...).Scan(
@@ -110,7 +162,9 @@ func (s *EventStore) Update(ctx context.Context, event *Event) error {
 			description = $7,
 			title = $8,
 			is_full = $9,
-			updated_at = $10
+			updated_at = $10,
+			visibility = $12,
+			requires_approval = $13
 		WHERE id = $11
 		RETURNING updated_at`
 
@@ -132,24 +186,51 @@ func (s *EventStore) Update(ctx context.Context, event *Event) error {
 		event.IsFull,
 		event.UpdatedAt,
 		event.ID,
+		event.Visibility,
+		event.RequiresApproval,
 	}
 
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&event.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return ErrEventNotFound
-	}
-	if err != nil {
-		return err
-	}
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the event so Join can't take spots freed by a raised
+		// max_players before the waitlist does
+		var maxPlayers int
+		err := tx.QueryRowContext(ctx, `SELECT max_players FROM events WHERE id = $1 FOR UPDATE`, event.ID).Scan(&maxPlayers)
+		if err == sql.ErrNoRows {
+			return ErrEventNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&event.UpdatedAt); err != nil {
+			return err
+		}
+		if event.MaxPlayers <= maxPlayers {
+			return nil
+		}
 
-	return nil
+		// Raising max_players frees spots for whoever is waiting
+		promoted, err := promoteFromWaitlist(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+		if len(promoted) == 0 {
+			return nil
+		}
+		return tx.QueryRowContext(ctx, `
+			UPDATE events
+			SET is_full = (SELECT COUNT(*) >= max_players FROM event_participants WHERE event_id = $1)
+			WHERE id = $1
+			RETURNING is_full`, event.ID).Scan(&event.IsFull)
+	})
 }
 
 func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 	query := `
 		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players,
 		       e.location_name, e.latitude, e.longitude, e.description, title,
-		       e.is_full, e.created_at, e.updated_at,
+		       e.is_full, e.status, e.visibility, e.requires_approval, e.cancellation_reason, e.cancelled_at,
+		       e.created_at, e.updated_at, e.series_id,
 		       p.first_name, p.last_name, u.email
 		FROM events e
 		JOIN users u ON e.event_owner = u.id
@@ -169,8 +250,14 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 		&event.Description,
 		&event.Title,
 		&event.IsFull,
+		&event.Status,
+		&event.Visibility,
+		&event.RequiresApproval,
+		&event.CancellationReason,
+		&event.CancelledAt,
 		&event.CreatedAt,
 		&event.UpdatedAt,
+		&event.SeriesID,
 		&event.OwnerFirstName,
 		&event.OwnerLastName,
 		&event.OwnerEmail,
@@ -216,6 +303,16 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 		return nil, err
 	}
 
+	event.Organizers, err = s.getOrganizers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Waitlist, err = s.GetWaitlist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	return event, nil
 }
 
@@ -246,17 +343,79 @@ func (s *EventStore) Delete(ctx context.Context, eventID int64) error {
 	return tx.Commit()
 }
 
-func (s *EventStore) Join(ctx context.Context, eventID, userID int64) error {
-	// Check if user has already joined
-	query := `SELECT EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2)`
-	var exists bool
-	if err := s.db.QueryRowContext(ctx, query, eventID, userID).Scan(&exists); err != nil {
+// Cancel marks a scheduled event as cancelled and notifies its participants.
+// The event and everyone who joined it are kept so it stays readable. It
+// returns the notifications it created.
+func (s *EventStore) Cancel(ctx context.Context, eventID int64, reason string) ([]*Notification, error) {
+	var notifications []*Notification
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&status)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+		if status != EventStatusScheduled {
+			return ErrEventNotScheduled
+		}
+
+		now := time.Now()
+		_, err = tx.ExecContext(ctx, `
+			UPDATE events
+			SET status = $2, cancellation_reason = NULLIF($3, ''), cancelled_at = $4, updated_at = $4
+			WHERE id = $1`, eventID, EventStatusCancelled, reason, now)
+		if err != nil {
+			return err
+		}
+
+		notifications, err = notifyEventCancelled(ctx, tx, []int64{eventID}, reason)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	if exists {
-		return ErrAlreadyJoined
+
+	return notifications, nil
+}
+
+// CompletePast marks the scheduled events that started by now as completed,
+// so they can't be joined anymore, and drops their waitlists since no spot
+// will open up. It returns how many events were completed.
+func (s *EventStore) CompletePast(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		WITH completed AS (
+			UPDATE events
+			SET status = $1, updated_at = $2
+			WHERE status = $3 AND event_datetime <= $2
+			RETURNING id
+		), dropped AS (
+			DELETE FROM event_waitlist
+			WHERE event_id IN (SELECT id FROM completed)
+		)
+		SELECT COUNT(*) FROM completed`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int64
+	err := s.db.QueryRowContext(ctx, query, EventStatusCompleted, now, EventStatusScheduled).Scan(&count)
+	if err != nil {
+		return 0, err
 	}
 
+	return count, nil
+}
+
+// Join adds the user to the event. The event row is locked for the duration
+// of the transaction so concurrent joins are serialized and can never push the
+// event over max_players. Unlisted and private events take the hash of an
+// invite, which the join uses up, except from their owner.
+func (s *EventStore) Join(ctx context.Context, eventID, userID int64, inviteHash string) error {
 	// Start transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -264,10 +423,59 @@ func (s *EventStore) Join(ctx context.Context, eventID, userID int64) error {
 	}
 	defer tx.Rollback()
 
+	// Lock the event row
+	var maxPlayers int
+	var owner int64
+	var status, visibility string
+	var requiresApproval bool
+	err = tx.QueryRowContext(ctx, `SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&maxPlayers, &status, &visibility, &requiresApproval, &owner)
+	if err == sql.ErrNoRows {
+		return ErrEventNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != EventStatusScheduled {
+		return ErrEventNotScheduled
+	}
+
+	// Check if user has already joined and whether there is a spot left
+	var exists bool
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2),
+			(SELECT COUNT(*) FROM event_participants WHERE event_id = $1)`,
+		eventID, userID).Scan(&exists, &count)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadyJoined
+	}
+	// Checked before the spots so only invitees end up on the waitlist. A
+	// full event rolls the use back.
+	if userID != owner {
+		if err := useInvite(ctx, tx, eventID, visibility, inviteHash); err != nil {
+			return err
+		}
+		if requiresApproval {
+			return ErrApprovalRequired
+		}
+	}
+	if count >= maxPlayers {
+		return ErrEventFull
+	}
+
 	// Insert participant
 	_, err = tx.ExecContext(ctx, `INSERT INTO event_participants (event_id, user_id) VALUES ($1, $2)`, eventID, userID)
 	if err != nil {
-		return err
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "event_participants_event_id_user_id_key"`:
+			return ErrAlreadyJoined
+		default:
+			return err
+		}
 	}
 
 	// Update is_full status if needed
@@ -294,17 +502,19 @@ func (s *EventStore) Leave(ctx context.Context, eventID, userID int64) error {
 	}
 	defer tx.Rollback()
 
-	// Check if event exists
-	var exists bool
-	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM events WHERE id = $1)`, eventID).Scan(&exists)
+	// Lock the event row so the promotion below can't race a Join for the
+	// freed spot
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrEventNotFound
+	}
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return ErrEventNotFound
-	}
 
 	// Check if user is a participant
+	var exists bool
 	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2)`, eventID, userID).Scan(&exists)
 	if err != nil {
 		return err
@@ -322,6 +532,16 @@ func (s *EventStore) Leave(ctx context.Context, eventID, userID int64) error {
 		return ErrNotJoined // fallback safeguard
 	}
 
+	// Organizers have to be participants
+	if err := removeOrganizer(ctx, tx, eventID, userID); err != nil {
+		return err
+	}
+
+	// Hand the freed spot to whoever is first in line
+	if _, err := promoteFromWaitlist(ctx, tx, eventID); err != nil {
+		return err
+	}
+
 	// Recalculate is_full
 	_, err = tx.ExecContext(ctx, `
 		UPDATE events
@@ -340,21 +560,52 @@ func (s *EventStore) Leave(ctx context.Context, eventID, userID int64) error {
 	return tx.Commit()
 }
 
-func (s *EventStore) GetAllWithFilter(ctx context.Context, filter *EventFilter) ([]*Event, error) {
+// GetAllWithFilter returns one page of the events matching filter.
+func (s *EventStore) GetAllWithFilter(ctx context.Context, filter *EventFilter) (*EventPage, error) {
 	var args []interface{}
 	var conditions []string
 
+	// Filters
+	argID := 1 // PostgreSQL placeholder counter
+
+	// Distance from the requested center, NULL when no center was given
+	distance := "NULL::float8"
+	if filter.Latitude != nil && filter.Longitude != nil {
+		distance = distanceKmSQL(argID, argID+1)
+		args = append(args, *filter.Latitude, *filter.Longitude)
+		argID += 2
+
+		if filter.RadiusKm != nil {
+			conditions = append(conditions, fmt.Sprintf("%s <= $%d", distance, argID))
+			args = append(args, *filter.RadiusKm)
+			argID++
+		}
+	}
+
 	query := `
 		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players,
 		       e.location_name, e.latitude, e.longitude, e.description,
-		       e.title, e.is_full, e.created_at, e.updated_at,
-		       COUNT(ep.id) AS registered_count
+		       e.title, e.is_full, e.status, e.cancellation_reason, e.cancelled_at,
+		       e.created_at, e.updated_at,
+		       COUNT(ep.id) AS registered_count,
+		       ` + distance + ` AS distance_km
 		FROM events e
 		LEFT JOIN event_participants ep ON e.id = ep.event_id
 	`
 
-	// Filters
-	argID := 1 // PostgreSQL placeholder counter
+	if filter.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("e.status = $%d", argID))
+		args = append(args, *filter.Status)
+		argID++
+	} else {
+		conditions = append(conditions, fmt.Sprintf("e.status <> $%d", argID))
+		args = append(args, EventStatusCancelled)
+		argID++
+	}
+
+	conditions = append(conditions, fmt.Sprintf("e.visibility = $%d", argID))
+	args = append(args, VisibilityPublic)
+	argID++
 
 	if filter.ID != nil {
 		conditions = append(conditions, fmt.Sprintf("e.id = $%d", argID))
@@ -417,20 +668,54 @@ func (s *EventStore) GetAllWithFilter(ctx context.Context, filter *EventFilter)
 	`
 
 	// Sorting
-	sortField := "e.created_at"
-	if filter.SortBy == "event_datetime" || filter.SortBy == "created_at" {
-		sortField = "e." + filter.SortBy
-	} else if filter.SortBy == "registered_count" {
-		sortField = "registered_count"
+	sortBy := "created_at"
+	switch filter.SortBy {
+	case "event_datetime", "registered_count":
+		sortBy = filter.SortBy
+	case "distance":
+		if filter.Latitude != nil && filter.Longitude != nil {
+			sortBy = filter.SortBy
+		}
 	}
 	sortOrder := "ASC"
 	if strings.ToLower(filter.Order) == "desc" {
 		sortOrder = "DESC"
 	}
-	query += fmt.Sprintf(" ORDER BY %s %s", sortField, sortOrder)
 
-	println("Query:", query)
-	println("Args:", args)
+	page := &EventPage{Events: []*Event{}}
+
+	if filter.IncludeTotal {
+		var total int
+		countQuery := `SELECT COUNT(*) FROM (` + query + `) ev`
+		if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	// Keyset pagination on the sort column with the id as tie breaker
+	key := eventSortKeys[sortBy]
+	query = `SELECT * FROM (` + query + `) ev`
+
+	if filter.Cursor != "" {
+		cursor, err := decodeEventCursor(filter.Cursor, sortBy, sortOrder)
+		if err != nil {
+			return nil, err
+		}
+
+		cmp := ">"
+		if sortOrder == "DESC" {
+			cmp = "<"
+		}
+		query += fmt.Sprintf(" WHERE (ev.%s, ev.id) %s ($%d::%s, $%d)", key.column, cmp, argID, key.cast, argID+1)
+		args = append(args, cursor.Value, cursor.ID)
+		argID += 2
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := filter.limit()
+	query += fmt.Sprintf(" ORDER BY ev.%s %s, ev.id %s LIMIT $%d", key.column, sortOrder, sortOrder, argID)
+	args = append(args, limit+1)
 
 	// Execute query
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -439,52 +724,78 @@ func (s *EventStore) GetAllWithFilter(ctx context.Context, filter *EventFilter)
 	}
 	defer rows.Close()
 
-	var events []*Event
 	for rows.Next() {
 		var e Event
 		err := rows.Scan(
 			&e.ID, &e.EventOwner, &e.Sport, &e.EventDateTime, &e.MaxPlayers,
 			&e.LocationName, &e.Latitude, &e.Longitude, &e.Description,
-			&e.Title, &e.IsFull, &e.CreatedAt, &e.UpdatedAt, &e.RegisteredCount,
+			&e.Title, &e.IsFull, &e.Status, &e.CancellationReason, &e.CancelledAt,
+			&e.CreatedAt, &e.UpdatedAt, &e.RegisteredCount, &e.DistanceKm,
 		)
 		if err != nil {
 			return nil, err
 		}
-		events = append(events, &e)
+		page.Events = append(page.Events, &e)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return events, nil
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.NextCursor = encodeEventCursor(sortBy, sortOrder, page.Events[limit-1])
+	}
+
+	return page, nil
 }
 
-func (s *EventStore) GetAllSimple(ctx context.Context) ([]*Event, error) {
+// GetAllSimple returns one page of all events that weren't cancelled, newest
+// first, with their owner and participants.
+func (s *EventStore) GetAllSimple(ctx context.Context, pageQuery PageQuery) (*EventPage, error) {
+	args := []interface{}{EventStatusCancelled, VisibilityPublic}
+
+	page := &EventPage{Events: []*Event{}}
+
+	if pageQuery.IncludeTotal {
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events WHERE status <> $1 AND visibility = $2`, EventStatusCancelled, VisibilityPublic).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
 	query := `
-		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players, e.location_name, e.latitude, e.longitude, e.description, e.title, e.is_full, e.created_at, e.updated_at,
-		COALESCE(ep.id, 0), COALESCE(ep.user_id, 0), COALESCE(ep.joined_at, CURRENT_TIMESTAMP),
-		COALESCE(p.first_name, ''), COALESCE(p.last_name, ''),
+		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players, e.location_name, e.latitude, e.longitude, e.description, e.title, e.is_full, e.status, e.created_at, e.updated_at,
 		owner_p.first_name, owner_p.last_name, owner_u.email
 		FROM events e
-		LEFT JOIN event_participants ep ON e.id = ep.event_id
-		LEFT JOIN users u ON ep.user_id = u.id
-		LEFT JOIN profile p ON u.email = p.email
 		JOIN users owner_u ON e.event_owner = owner_u.id
 		JOIN profile owner_p ON owner_u.email = owner_p.email
-		ORDER BY created_at DESC, e.id, ep.joined_at
+		WHERE e.status <> $1 AND e.visibility = $2
 	`
 
-	rows, err := s.db.QueryContext(ctx, query)
+	if pageQuery.Cursor != "" {
+		cursor, err := decodeEventCursor(pageQuery.Cursor, "created_at", "DESC")
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (e.created_at, e.id) < ($3::timestamp, $4)`
+		args = append(args, cursor.Value, cursor.ID)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := pageQuery.limit()
+	query += fmt.Sprintf(" ORDER BY e.created_at DESC, e.id DESC LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	eventMap := make(map[int64]*Event)
 	for rows.Next() {
-		var event Event
-		var participant EventParticipant
+		event := &Event{Participants: []EventParticipant{}}
 		err := rows.Scan(
 			&event.ID,
 			&event.EventOwner,
@@ -497,13 +808,9 @@ func (s *EventStore) GetAllSimple(ctx context.Context) ([]*Event, error) {
 			&event.Description,
 			&event.Title,
 			&event.IsFull,
+			&event.Status,
 			&event.CreatedAt,
 			&event.UpdatedAt,
-			&participant.ID,
-			&participant.UserID,
-			&participant.JoinedAt,
-			&participant.FirstName,
-			&participant.LastName,
 			&event.OwnerFirstName,
 			&event.OwnerLastName,
 			&event.OwnerEmail,
@@ -511,30 +818,129 @@ func (s *EventStore) GetAllSimple(ctx context.Context) ([]*Event, error) {
 		if err != nil {
 			return nil, err
 		}
+		page.Events = append(page.Events, event)
+	}
 
-		ev, exists := eventMap[event.ID]
-		if !exists {
-			event.Participants = []EventParticipant{}
-			eventMap[event.ID] = &event
-			ev = &event
-		}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Only add participant if it has a valid ID
-		if participant.ID > 0 {
-			participant.EventID = event.ID
-			ev.Participants = append(ev.Participants, participant)
-		}
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		page.NextCursor = encodeEventCursor("created_at", "DESC", page.Events[limit-1])
+	}
+
+	if err := s.loadParticipants(ctx, page.Events); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// GetByUser returns every event the user owns or takes part in, in
+// chronological order, with their owner and participants.
+func (s *EventStore) GetByUser(ctx context.Context, userID int64) ([]*Event, error) {
+	query := `
+		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players, e.location_name, e.latitude, e.longitude, e.description, e.title, e.is_full,
+		e.status, e.cancellation_reason, e.cancelled_at, e.created_at, e.updated_at,
+		owner_p.first_name, owner_p.last_name, owner_u.email
+		FROM events e
+		JOIN users owner_u ON e.event_owner = owner_u.id
+		JOIN profile owner_p ON owner_u.email = owner_p.email
+		WHERE e.event_owner = $1
+		   OR EXISTS(SELECT 1 FROM event_participants ep WHERE ep.event_id = e.id AND ep.user_id = $1)
+		ORDER BY e.event_datetime ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	var events []*Event
-	for _, evt := range eventMap {
-		evt.RegisteredCount = len(evt.Participants)
-		events = append(events, evt)
+	events := []*Event{}
+	for rows.Next() {
+		event := &Event{Participants: []EventParticipant{}}
+		err := rows.Scan(
+			&event.ID,
+			&event.EventOwner,
+			&event.Sport,
+			&event.EventDateTime,
+			&event.MaxPlayers,
+			&event.LocationName,
+			&event.Latitude,
+			&event.Longitude,
+			&event.Description,
+			&event.Title,
+			&event.IsFull,
+			&event.Status,
+			&event.CancellationReason,
+			&event.CancelledAt,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.OwnerFirstName,
+			&event.OwnerLastName,
+			&event.OwnerEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
+	if err := s.loadParticipants(ctx, events); err != nil {
+		return nil, err
+	}
+
 	return events, nil
 }
+
+// loadParticipants fills in the participants of the given events with a
+// single query.
+func (s *EventStore) loadParticipants(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Event, len(events))
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		byID[e.ID] = e
+		ids = append(ids, e.ID)
+	}
+
+	query := `
+		SELECT ep.id, ep.event_id, ep.user_id, ep.joined_at,
+		COALESCE(p.first_name, ''), COALESCE(p.last_name, '')
+		FROM event_participants ep
+		JOIN users u ON ep.user_id = u.id
+		LEFT JOIN profile p ON u.email = p.email
+		WHERE ep.event_id = ANY($1)
+		ORDER BY ep.event_id, ep.joined_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p EventParticipant
+		if err := rows.Scan(&p.ID, &p.EventID, &p.UserID, &p.JoinedAt, &p.FirstName, &p.LastName); err != nil {
+			return err
+		}
+		ev, ok := byID[p.EventID]
+		if !ok {
+			continue
+		}
+		ev.Participants = append(ev.Participants, p)
+		ev.RegisteredCount = len(ev.Participants)
+	}
+
+	return rows.Err()
+}
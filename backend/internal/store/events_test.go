@@ -3,9 +3,13 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -282,7 +286,7 @@ func TestEventStore_Join(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := store.Join(ctx, tt.eventID, tt.userID)
+			err := store.Join(ctx, tt.eventID, tt.userID, "")
 			if tt.wantErr {
 				assert.Error(t, err)
 				if tt.name == "already joined" {
@@ -301,3 +305,304 @@ func TestEventStore_Join(t *testing.T) {
 		})
 	}
 }
+
+func TestEventStore_Join_Concurrent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewEventStore(db)
+	ctx := context.Background()
+
+	const maxPlayers = 5
+	const joiners = 20
+
+	_, err := db.ExecContext(ctx, `INSERT INTO users (id, email, password) VALUES (1, 'owner@example.com', 'testpassword')`)
+	require.NoError(t, err)
+	for i := 2; i <= joiners+1; i++ {
+		_, err := db.ExecContext(ctx, `INSERT INTO users (id, email, password) VALUES ($1, $2, 'testpassword')`, i, fmt.Sprintf("user%d@example.com", i))
+		require.NoError(t, err)
+	}
+
+	event := &Event{
+		EventOwner:    1,
+		Sport:         "Football",
+		EventDateTime: time.Now().Add(24 * time.Hour),
+		MaxPlayers:    maxPlayers,
+		LocationName:  "Central Park",
+		Title:         "Test Event",
+	}
+	require.NoError(t, store.Create(ctx, event))
+
+	// Fire all joins at once
+	var wg sync.WaitGroup
+	errs := make(chan error, joiners)
+	start := make(chan struct{})
+	for i := 2; i <= joiners+1; i++ {
+		wg.Add(1)
+		go func(userID int64) {
+			defer wg.Done()
+			<-start
+			errs <- store.Join(ctx, event.ID, userID, "")
+		}(int64(i))
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	joined, full := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			joined++
+		case errors.Is(err, ErrEventFull):
+			full++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, maxPlayers, joined)
+	assert.Equal(t, joiners-maxPlayers, full)
+
+	var count int
+	var isFull bool
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(ep.id), e.is_full
+		FROM events e
+		LEFT JOIN event_participants ep ON e.id = ep.event_id
+		WHERE e.id = $1
+		GROUP BY e.id`, event.ID).Scan(&count, &isFull)
+	require.NoError(t, err)
+	assert.Equal(t, maxPlayers, count)
+	assert.True(t, isFull)
+}
+
+// Test Join refuses a user once the event has no spot left
+func TestEventStore_Join_Full(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(2, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists", "count"}).AddRow(false, 2))
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 3, "")
+	assert.ErrorIs(t, err, ErrEventFull)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Join maps a lost race on the unique constraint to ErrAlreadyJoined
+func TestEventStore_Join_DuplicateParticipant(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(10, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists", "count"}).AddRow(false, 1))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "event_participants_event_id_user_id_key"`))
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 3, "")
+	assert.ErrorIs(t, err, ErrAlreadyJoined)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Join on an event that doesn't exist
+func TestEventStore_Join_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(999)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 999, 3, "")
+	assert.ErrorIs(t, err, ErrEventNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Join on a cancelled event
+func TestEventStore_Join_Cancelled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(10, EventStatusCancelled, VisibilityPublic, false, 1))
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 3, "")
+	assert.ErrorIs(t, err, ErrEventNotScheduled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Cancel keeps the event and notifies its participants
+func TestEventStore_Cancel(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(EventStatusScheduled))
+	mock.ExpectExec(`UPDATE events SET status = \$2, cancellation_reason = NULLIF\(\$3, ''\), cancelled_at = \$4`).
+		WithArgs(int64(1), EventStatusCancelled, "Rained out", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO notifications \(user_id, event_id, type, message\) SELECT ep.user_id, e.id, \$2, '"' \|\| COALESCE\(NULLIF\(e.title, ''\), e.sport\) .* RETURNING`).
+		WithArgs(sqlmock.AnyArg(), NotificationEventCancelled, "Rained out").
+		WillReturnRows(sqlmock.NewRows(notificationTestColumns).
+			AddRow(1, 2, 1, NotificationEventCancelled, `"Test" has been cancelled: Rained out`, nil, time.Now()).
+			AddRow(2, 3, 1, NotificationEventCancelled, `"Test" has been cancelled: Rained out`, nil, time.Now()))
+	mock.ExpectCommit()
+
+	notifications, err := store.Cancel(context.Background(), 1, "Rained out")
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Cancel only applies to scheduled events
+func TestEventStore_Cancel_AlreadyCancelled(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(EventStatusCancelled))
+	mock.ExpectRollback()
+
+	_, err := store.Cancel(context.Background(), 1, "")
+	assert.ErrorIs(t, err, ErrEventNotScheduled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CompletePast completes the scheduled events that have started and drops
+// their waitlists
+func TestEventStore_CompletePast(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery(`WITH completed AS \( UPDATE events SET status = \$1, updated_at = \$2 WHERE status = \$3 AND event_datetime <= \$2 RETURNING id \), dropped AS \( DELETE FROM event_waitlist`).
+		WithArgs(EventStatusCompleted, now, EventStatusScheduled).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := store.CompletePast(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetAllWithFilter limits events to the radius and sorts them by distance
+func TestEventStore_GetAllWithFilter_Distance(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	lat, lng, radius := 40.78, -73.96, 10.0
+
+	columns := []string{
+		"id", "event_owner", "sport", "event_datetime", "max_players",
+		"location_name", "latitude", "longitude", "description",
+		"title", "is_full", "status", "cancellation_reason", "cancelled_at",
+		"created_at", "updated_at", "registered_count", "distance_km",
+	}
+	mock.ExpectQuery(`ASIN\(LEAST\(1, SQRT\(.*\)\)\)\) AS distance_km .* WHERE \(6371 \* 2 \* ASIN.*\) <= \$3 AND e.status <> \$4 AND e.visibility = \$5 GROUP BY e.id \) ev ORDER BY ev.distance_km ASC, ev.id ASC LIMIT \$6`).
+		WithArgs(lat, lng, radius, EventStatusCancelled, VisibilityPublic, DefaultEventPageSize+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "Football", time.Now(), 10, "Central Park", 40.7829, -73.9654, "", "Near", false, EventStatusScheduled, nil, nil, time.Now(), time.Now(), 0, 0.4).
+			AddRow(2, 1, "Tennis", time.Now(), 4, "Brooklyn", 40.6782, -73.9442, "", "Far", false, EventStatusScheduled, nil, nil, time.Now(), time.Now(), 0, 11.5))
+
+	page, err := store.GetAllWithFilter(context.Background(), &EventFilter{
+		Latitude:  &lat,
+		Longitude: &lng,
+		RadiusKm:  &radius,
+		SortBy:    "distance",
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	require.NotNil(t, page.Events[0].DistanceKm)
+	assert.Equal(t, 0.4, *page.Events[0].DistanceKm)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetAllWithFilter leaves distance_km empty without a center
+func TestEventStore_GetAllWithFilter_NoCenter(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	columns := []string{
+		"id", "event_owner", "sport", "event_datetime", "max_players",
+		"location_name", "latitude", "longitude", "description",
+		"title", "is_full", "status", "cancellation_reason", "cancelled_at",
+		"created_at", "updated_at", "registered_count", "distance_km",
+	}
+	mock.ExpectQuery(`NULL::float8 AS distance_km .* ORDER BY ev.created_at ASC, ev.id ASC`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "Football", time.Now(), 10, "Central Park", 40.7829, -73.9654, "", "Test", false, EventStatusScheduled, nil, nil, time.Now(), time.Now(), 0, nil))
+
+	// Sorting by distance is ignored when there is nothing to measure from
+	page, err := store.GetAllWithFilter(context.Background(), &EventFilter{SortBy: "distance"})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Nil(t, page.Events[0].DistanceKm)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetAllWithFilter only returns cancelled events when asked for them
+func TestEventStore_GetAllWithFilter_Status(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	status := EventStatusCancelled
+
+	columns := []string{
+		"id", "event_owner", "sport", "event_datetime", "max_players",
+		"location_name", "latitude", "longitude", "description",
+		"title", "is_full", "status", "cancellation_reason", "cancelled_at",
+		"created_at", "updated_at", "registered_count", "distance_km",
+	}
+	mock.ExpectQuery(`WHERE e.status = \$1 AND e.visibility = \$2 GROUP BY e.id`).
+		WithArgs(EventStatusCancelled, VisibilityPublic, DefaultEventPageSize+1).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "Football", time.Now(), 10, "Central Park", 40.7829, -73.9654, "", "Test", false, EventStatusCancelled, "Rained out", time.Now(), time.Now(), time.Now(), 0, nil))
+
+	page, err := store.GetAllWithFilter(context.Background(), &EventFilter{Status: &status})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Equal(t, EventStatusCancelled, page.Events[0].Status)
+	require.NotNil(t, page.Events[0].CancellationReason)
+	assert.Equal(t, "Rained out", *page.Events[0].CancellationReason)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInviteRequired is returned when joining an unlisted or private
+	// event without an invite.
+	ErrInviteRequired = errors.New("an invite is required to join this event")
+	// ErrInvalidInvite is returned for invites that don't exist, belong to
+	// another event, have expired or have been used up.
+	ErrInvalidInvite = errors.New("invite is invalid, expired or used up")
+)
+
+const (
+	// VisibilityPublic events are listed and anyone can join them.
+	VisibilityPublic = "public"
+	// VisibilityUnlisted events aren't listed but anyone with the link can
+	// see them. Joining takes an invite.
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPrivate events are only visible to their participants and
+	// to invitees. Joining takes an invite.
+	VisibilityPrivate = "private"
+)
+
+// EventInvite lets whoever holds its token join a non public event, up to
+// MaxUses times before ExpiresAt. Only the hash of the token is stored.
+type EventInvite struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	CreatedBy int64     `json:"created_by"`
+	MaxUses   int       `json:"max_uses"`
+	Uses      int       `json:"uses"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateInvite stores an invite for the event identified by the token hash.
+func (s *EventStore) CreateInvite(ctx context.Context, invite *EventInvite, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		INSERT INTO event_invites (event_id, created_by, token_hash, max_uses, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return s.db.QueryRowContext(ctx, query, invite.EventID, invite.CreatedBy, tokenHash, invite.MaxUses, invite.ExpiresAt).Scan(
+		&invite.ID,
+		&invite.CreatedAt,
+	)
+}
+
+// CheckInvite returns ErrInvalidInvite unless the invite could still be used
+// to join the event. It doesn't use it up.
+func (s *EventStore) CheckInvite(ctx context.Context, eventID int64, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var valid bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM event_invites
+			WHERE event_id = $1 AND token_hash = $2 AND expires_at > NOW() AND uses < max_uses
+		)`, eventID, tokenHash).Scan(&valid)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidInvite
+	}
+	return nil
+}
+
+// useInvite lets the user in if the event is public, and otherwise uses up
+// one use of the invite.
+func useInvite(ctx context.Context, tx *sql.Tx, eventID int64, visibility, tokenHash string) error {
+	if visibility == VisibilityPublic {
+		return nil
+	}
+	if tokenHash == "" {
+		return ErrInviteRequired
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE event_invites SET uses = uses + 1
+		WHERE event_id = $1 AND token_hash = $2 AND expires_at > NOW() AND uses < max_uses`,
+		eventID, tokenHash)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrInvalidInvite
+	}
+	return nil
+}
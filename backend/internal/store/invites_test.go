@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectJoinLock(mock sqlmock.Sqlmock, visibility string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(10, EventStatusScheduled, visibility, false, 1))
+	mock.ExpectQuery(`SELECT\s+EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists", "count"}).AddRow(false, 1))
+}
+
+// Test Join uses up one use of the invite of a private event
+func TestEventStore_Join_WithInvite(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinLock(mock, VisibilityPrivate)
+	mock.ExpectExec(`UPDATE event_invites SET uses = uses \+ 1`).
+		WithArgs(int64(1), "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE events\s+SET is_full`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.Join(context.Background(), 1, 2, "hash")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Join turns players away from an unlisted event without an invite
+func TestEventStore_Join_InviteRequired(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinLock(mock, VisibilityUnlisted)
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 2, "")
+	assert.Equal(t, ErrInviteRequired, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Join with an invite that expired or was used up
+func TestEventStore_Join_InvalidInvite(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinLock(mock, VisibilityPrivate)
+	mock.ExpectExec(`UPDATE event_invites SET uses = uses \+ 1`).
+		WithArgs(int64(1), "hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 2, "hash")
+	assert.Equal(t, ErrInvalidInvite, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CreateInvite stores the hash of the token
+func TestEventStore_CreateInvite(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expiresAt := time.Now().Add(time.Hour)
+	mock.ExpectQuery(`INSERT INTO event_invites \(event_id, created_by, token_hash, max_uses, expires_at\)`).
+		WithArgs(int64(1), int64(2), "hash", 5, expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+
+	invite := &EventInvite{EventID: 1, CreatedBy: 2, MaxUses: 5, ExpiresAt: expiresAt}
+	err := store.CreateInvite(context.Background(), invite, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), invite.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CheckInvite with an invite that can't be used anymore
+func TestEventStore_CheckInvite_Invalid(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectQuery(`SELECT EXISTS\(\s+SELECT 1 FROM event_invites`).
+		WithArgs(int64(1), "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	err := store.CheckInvite(context.Background(), 1, "hash")
+	assert.Equal(t, ErrInvalidInvite, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
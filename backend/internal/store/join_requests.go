@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrApprovalRequired is returned by Join for events whose organizers
+	// vet their players. Those players send a join request instead.
+	ErrApprovalRequired    = errors.New("joining this event requires the approval of its organizers")
+	ErrAlreadyRequested    = errors.New("user has already asked to join this event")
+	ErrJoinRequestNotFound = errors.New("no pending join request found")
+	ErrJoinRequestDeclined = errors.New("user's request to join this event was declined recently")
+)
+
+// JoinRequestCooldown is how long a player whose request was declined waits
+// before asking again.
+const JoinRequestCooldown = 24 * time.Hour
+
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestRejected = "rejected"
+)
+
+// JoinRequest is a player asking to join an event that requires approval.
+type JoinRequest struct {
+	ID          int64      `json:"id"`
+	EventID     int64      `json:"event_id"`
+	UserID      int64      `json:"user_id"`
+	FirstName   string     `json:"first_name"`
+	LastName    string     `json:"last_name"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	RespondedBy *int64     `json:"responded_by,omitempty"`
+}
+
+// RequestJoin records a pending request of the user to join the event. Like
+// Join, it uses up the invite for unlisted and private events. Players on the
+// waitlist already have their place, and players who were declined within
+// JoinRequestCooldown can't ask again yet.
+func (s *EventStore) RequestJoin(ctx context.Context, eventID, userID int64, inviteHash string) (*JoinRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	request := &JoinRequest{EventID: eventID, UserID: userID, Status: JoinRequestPending}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var owner int64
+		var status, visibility string
+		err := tx.QueryRowContext(ctx, `SELECT status, visibility, event_owner FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&status, &visibility, &owner)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+		if status != EventStatusScheduled {
+			return ErrEventNotScheduled
+		}
+
+		var joined, waitlisted, requested, declined bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT
+				EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2),
+				EXISTS(SELECT 1 FROM event_waitlist WHERE event_id = $1 AND user_id = $2),
+				EXISTS(SELECT 1 FROM event_join_requests WHERE event_id = $1 AND user_id = $2 AND status = 'pending'),
+				EXISTS(SELECT 1 FROM event_join_requests WHERE event_id = $1 AND user_id = $2 AND status = 'rejected' AND responded_at > $3)`,
+			eventID, userID, time.Now().Add(-JoinRequestCooldown)).Scan(&joined, &waitlisted, &requested, &declined)
+		if err != nil {
+			return err
+		}
+		switch {
+		case joined:
+			return ErrAlreadyJoined
+		case waitlisted:
+			return ErrAlreadyWaitlisted
+		case requested:
+			return ErrAlreadyRequested
+		case declined:
+			return ErrJoinRequestDeclined
+		}
+		if userID != owner {
+			if err := useInvite(ctx, tx, eventID, visibility, inviteHash); err != nil {
+				return err
+			}
+		}
+
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO event_join_requests (event_id, user_id)
+			VALUES ($1, $2)
+			RETURNING id, created_at`, eventID, userID).Scan(
+			&request.ID,
+			&request.CreatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// GetJoinRequests returns the pending join requests of the event, oldest
+// first.
+func (s *EventStore) GetJoinRequests(ctx context.Context, eventID int64) ([]JoinRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `
+		SELECT jr.id, jr.event_id, jr.user_id, p.first_name, p.last_name, jr.status, jr.created_at
+		FROM event_join_requests jr
+		JOIN users u ON jr.user_id = u.id
+		JOIN profile p ON u.email = p.email
+		WHERE jr.event_id = $1 AND jr.status = 'pending'
+		ORDER BY jr.created_at ASC, jr.id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []JoinRequest{}
+	for rows.Next() {
+		var jr JoinRequest
+		err := rows.Scan(&jr.ID, &jr.EventID, &jr.UserID, &jr.FirstName, &jr.LastName, &jr.Status, &jr.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, jr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// RespondJoinRequest approves or rejects a pending join request of the event.
+// Approval adds the player to the event under the same lock Join takes, so it
+// returns ErrEventFull rather than going over max_players and leaves the
+// request pending.
+func (s *EventStore) RespondJoinRequest(ctx context.Context, eventID, requestID, respondedBy int64, approve bool) (*JoinRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	request := &JoinRequest{ID: requestID, EventID: eventID}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var maxPlayers int
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT max_players, status FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&maxPlayers, &status)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			SELECT user_id, created_at FROM event_join_requests
+			WHERE id = $1 AND event_id = $2 AND status = 'pending'
+			FOR UPDATE`, requestID, eventID).Scan(&request.UserID, &request.CreatedAt)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrJoinRequestNotFound
+			default:
+				return err
+			}
+		}
+
+		request.Status = JoinRequestRejected
+		if approve {
+			if status != EventStatusScheduled {
+				return ErrEventNotScheduled
+			}
+
+			var count int
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM event_participants WHERE event_id = $1`, eventID).Scan(&count); err != nil {
+				return err
+			}
+			if count >= maxPlayers {
+				return ErrEventFull
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO event_participants (event_id, user_id) VALUES ($1, $2)
+				ON CONFLICT (event_id, user_id) DO NOTHING`, eventID, request.UserID)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE events
+				SET is_full = (
+					SELECT COUNT(*) >= max_players
+					FROM event_participants
+					WHERE event_id = $1
+				)
+				WHERE id = $1`, eventID)
+			if err != nil {
+				return err
+			}
+			request.Status = JoinRequestApproved
+		}
+
+		now := time.Now()
+		request.RespondedAt = &now
+		request.RespondedBy = &respondedBy
+		_, err = tx.ExecContext(ctx, `
+			UPDATE event_join_requests SET status = $1, responded_at = $2, responded_by = $3 WHERE id = $4`,
+			request.Status, now, respondedBy, requestID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Join sends players of an event that requires approval to RequestJoin
+func TestEventStore_Join_ApprovalRequired(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(10, EventStatusScheduled, VisibilityPublic, true, 1))
+	mock.ExpectQuery(`SELECT\s+EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists", "count"}).AddRow(false, 1))
+	mock.ExpectRollback()
+
+	err := store.Join(context.Background(), 1, 2, "")
+	assert.Equal(t, ErrApprovalRequired, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RequestJoin records a pending request
+func TestEventStore_RequestJoin(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, visibility, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "visibility", "event_owner"}).AddRow(EventStatusScheduled, VisibilityPublic, 1))
+	mock.ExpectQuery(`SELECT\s+EXISTS\(SELECT 1 FROM event_participants .* EXISTS\(SELECT 1 FROM event_waitlist .* EXISTS\(SELECT 1 FROM event_join_requests .* status = 'rejected' AND responded_at > \$3`).
+		WithArgs(int64(1), int64(2), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "requested", "declined"}).AddRow(false, false, false, false))
+	mock.ExpectQuery(`INSERT INTO event_join_requests \(event_id, user_id\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, time.Now()))
+	mock.ExpectCommit()
+
+	request, err := store.RequestJoin(context.Background(), 1, 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), request.ID)
+	assert.Equal(t, JoinRequestPending, request.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RequestJoin rejects a second pending request
+func TestEventStore_RequestJoin_AlreadyRequested(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, visibility, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "visibility", "event_owner"}).AddRow(EventStatusScheduled, VisibilityPublic, 1))
+	mock.ExpectQuery(`SELECT\s+EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "requested", "declined"}).AddRow(false, false, true, false))
+	mock.ExpectRollback()
+
+	_, err := store.RequestJoin(context.Background(), 1, 2, "")
+	assert.Equal(t, ErrAlreadyRequested, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RequestJoin turns away players who are already on the waitlist or
+// were declined recently
+func TestEventStore_RequestJoin_Refused(t *testing.T) {
+	tests := []struct {
+		name       string
+		waitlisted bool
+		declined   bool
+		expected   error
+	}{
+		{name: "waitlisted", waitlisted: true, expected: ErrAlreadyWaitlisted},
+		{name: "declined recently", declined: true, expected: ErrJoinRequestDeclined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			defer db.Close()
+
+			store := &EventStore{db: db}
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT status, visibility, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+				WithArgs(int64(1)).
+				WillReturnRows(sqlmock.NewRows([]string{"status", "visibility", "event_owner"}).AddRow(EventStatusScheduled, VisibilityPublic, 1))
+			mock.ExpectQuery(`SELECT\s+EXISTS\(SELECT 1 FROM event_participants`).
+				WithArgs(int64(1), int64(2), sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "requested", "declined"}).AddRow(false, tt.waitlisted, false, tt.declined))
+			mock.ExpectRollback()
+
+			_, err := store.RequestJoin(context.Background(), 1, 2, "")
+			assert.Equal(t, tt.expected, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func expectJoinRequestLock(mock sqlmock.Sqlmock, maxPlayers int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status"}).AddRow(maxPlayers, EventStatusScheduled))
+	mock.ExpectQuery(`SELECT user_id, created_at FROM event_join_requests\s+WHERE id = \$1 AND event_id = \$2 AND status = 'pending'\s+FOR UPDATE`).
+		WithArgs(int64(4), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "created_at"}).AddRow(2, time.Now()))
+}
+
+// Test RespondJoinRequest adds the player on approval
+func TestEventStore_RespondJoinRequest_Approve(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinRequestLock(mock, 10)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM event_participants WHERE event_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)\s+ON CONFLICT`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE events\s+SET is_full`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE event_join_requests SET status = \$1, responded_at = \$2, responded_by = \$3 WHERE id = \$4`).
+		WithArgs(JoinRequestApproved, sqlmock.AnyArg(), int64(1), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	request, err := store.RespondJoinRequest(context.Background(), 1, 4, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, JoinRequestApproved, request.Status)
+	assert.Equal(t, int64(2), request.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RespondJoinRequest leaves the request pending when the event is full
+func TestEventStore_RespondJoinRequest_Full(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinRequestLock(mock, 3)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM event_participants WHERE event_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectRollback()
+
+	_, err := store.RespondJoinRequest(context.Background(), 1, 4, 1, true)
+	assert.Equal(t, ErrEventFull, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RespondJoinRequest doesn't touch the participants on rejection
+func TestEventStore_RespondJoinRequest_Reject(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectJoinRequestLock(mock, 3)
+	mock.ExpectExec(`UPDATE event_join_requests SET status = \$1`).
+		WithArgs(JoinRequestRejected, sqlmock.AnyArg(), int64(1), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	request, err := store.RespondJoinRequest(context.Background(), 1, 4, 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, JoinRequestRejected, request.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RespondJoinRequest with a request that was already answered
+func TestEventStore_RespondJoinRequest_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status"}).AddRow(10, EventStatusScheduled))
+	mock.ExpectQuery(`SELECT user_id, created_at FROM event_join_requests`).
+		WithArgs(int64(4), int64(1)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := store.RespondJoinRequest(context.Background(), 1, 4, 1, true)
+	assert.Equal(t, ErrJoinRequestNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
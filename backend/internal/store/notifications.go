@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	NotificationEventJoined      = "event_joined"
+	NotificationEventLeft        = "event_left"
+	NotificationEventUpdated     = "event_updated"
+	NotificationEventCancelled   = "event_cancelled"
+	NotificationEventRemoved     = "event_removed"
+	NotificationRemovedFromEvent = "removed_from_event"
+	NotificationOrganizerAdded   = "organizer_added"
+	NotificationTransferProposed = "transfer_proposed"
+	NotificationTransferAccepted = "transfer_accepted"
+	NotificationTransferDeclined = "transfer_declined"
+	NotificationJoinRequested    = "join_requested"
+	NotificationJoinApproved     = "join_approved"
+	NotificationJoinRejected     = "join_rejected"
+)
+
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	EventID   *int64     `json:"event_id,omitempty"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPage is one page of a user's notifications, newest first.
+type NotificationPage struct {
+	Notifications []*Notification
+	NextCursor    string
+	Total         *int
+}
+
+const notificationColumns = `id, user_id, event_id, type, message, read_at, created_at`
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// Create stores the notifications in a single transaction.
+func (s *NotificationStore) Create(ctx context.Context, notifications ...*Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, event_id, type, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		for _, n := range notifications {
+			err := tx.QueryRowContext(ctx, query, n.UserID, n.EventID, n.Type, n.Message).Scan(&n.ID, &n.CreatedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByUser returns one page of the user's notifications, newest first.
+func (s *NotificationStore) GetByUser(ctx context.Context, userID int64, unreadOnly bool, pageQuery PageQuery) (*NotificationPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	page := &NotificationPage{Notifications: []*Notification{}}
+
+	conditions := `user_id = $1`
+	if unreadOnly {
+		conditions += ` AND read_at IS NULL`
+	}
+	args := []interface{}{userID}
+
+	if pageQuery.IncludeTotal {
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE `+conditions, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	if pageQuery.Cursor != "" {
+		before, err := decodeIDCursor(pageQuery.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions += ` AND id < $2`
+		args = append(args, before)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := pageQuery.limit()
+	query := fmt.Sprintf(`SELECT %s FROM notifications WHERE %s ORDER BY id DESC LIMIT $%d`, notificationColumns, conditions, len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page.Notifications, err = scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Notifications) > limit {
+		page.Notifications = page.Notifications[:limit]
+		page.NextCursor = encodeIDCursor(page.Notifications[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// MarkRead marks the given notifications of the user as read, or all of them
+// when ids is empty. Ids of other users' notifications are ignored.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID int64, ids []int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	query := `UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL`
+	args := []interface{}{userID, time.Now()}
+	if len(ids) > 0 {
+		query += ` AND id = ANY($3)`
+		args = append(args, pq.Array(ids))
+	}
+
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// UnreadCount returns how many notifications the user hasn't read yet.
+func (s *NotificationStore) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// notifyEventCancelled tells every participant of the events, except their
+// owner, that they have been cancelled.
+func notifyEventCancelled(ctx context.Context, tx *sql.Tx, eventIDs []int64, reason string) ([]*Notification, error) {
+	query := `
+		INSERT INTO notifications (user_id, event_id, type, message)
+		SELECT ep.user_id, e.id, $2, '"' || COALESCE(NULLIF(e.title, ''), e.sport) || '" has been cancelled' || COALESCE(': ' || NULLIF($3, ''), '')
+		FROM events e
+		JOIN event_participants ep ON ep.event_id = e.id
+		WHERE e.id = ANY($1) AND ep.user_id <> e.event_owner
+		RETURNING ` + notificationColumns
+
+	rows, err := tx.QueryContext(ctx, query, pq.Array(eventIDs), NotificationEventCancelled, reason)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+func scanNotifications(rows *sql.Rows) ([]*Notification, error) {
+	notifications := []*Notification{}
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.EventID, &n.Type, &n.Message, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, &n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
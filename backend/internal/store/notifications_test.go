@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var notificationTestColumns = []string{"id", "user_id", "event_id", "type", "message", "read_at", "created_at"}
+
+// Test Create stores every notification in one transaction
+func TestNotificationStore_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &NotificationStore{db: db}
+	eventID := int64(3)
+	notifications := []*Notification{
+		{UserID: 1, EventID: &eventID, Type: NotificationEventUpdated, Message: "updated"},
+		{UserID: 2, EventID: &eventID, Type: NotificationEventUpdated, Message: "updated"},
+	}
+
+	mock.ExpectBegin()
+	for i, n := range notifications {
+		mock.ExpectQuery(`INSERT INTO notifications \(user_id, event_id, type, message\) VALUES \(\$1, \$2, \$3, \$4\)`).
+			WithArgs(n.UserID, &eventID, NotificationEventUpdated, "updated").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(i+1, time.Now()))
+	}
+	mock.ExpectCommit()
+
+	err := store.Create(context.Background(), notifications...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), notifications[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetByUser pages through the notifications newest first
+func TestNotificationStore_GetByUser(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &NotificationStore{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM notifications WHERE user_id = \$1 ORDER BY id DESC LIMIT \$2`).
+		WithArgs(int64(1), 3).
+		WillReturnRows(sqlmock.NewRows(notificationTestColumns).
+			AddRow(9, 1, 3, NotificationEventJoined, "joined", nil, now).
+			AddRow(8, 1, 3, NotificationEventJoined, "joined", now, now).
+			AddRow(7, 1, nil, NotificationEventJoined, "joined", nil, now))
+
+	page, err := store.GetByUser(context.Background(), 1, false, PageQuery{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Notifications, 2)
+	assert.NotNil(t, page.Notifications[1].ReadAt)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// The next page starts after notification 8
+	mock.ExpectQuery(`SELECT .* FROM notifications WHERE user_id = \$1 AND read_at IS NULL AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+		WithArgs(int64(1), int64(8), 3).
+		WillReturnRows(sqlmock.NewRows(notificationTestColumns).AddRow(7, 1, nil, NotificationEventJoined, "joined", nil, now))
+
+	page, err = store.GetByUser(context.Background(), 1, true, PageQuery{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Notifications, 1)
+	assert.Nil(t, page.Notifications[0].EventID)
+	assert.Empty(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetByUser rejects cursors it didn't issue
+func TestNotificationStore_GetByUser_InvalidCursor(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &NotificationStore{db: db}
+
+	_, err := store.GetByUser(context.Background(), 1, false, PageQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test MarkRead only touches the given notifications of the user
+func TestNotificationStore_MarkRead(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &NotificationStore{db: db}
+
+	mock.ExpectExec(`UPDATE notifications SET read_at = \$2 WHERE user_id = \$1 AND read_at IS NULL AND id = ANY\(\$3\)`).
+		WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE notifications SET read_at = \$2 WHERE user_id = \$1 AND read_at IS NULL$`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	assert.NoError(t, store.MarkRead(context.Background(), 1, []int64{4, 5}))
+	assert.NoError(t, store.MarkRead(context.Background(), 1, nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
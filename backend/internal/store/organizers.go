@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AddOrganizer makes a participant of the event one of its co-organizers.
+// Adding an organizer twice is not an error. The participant row is locked so
+// a concurrent Leave either waits and removes the new organizer with the
+// participant, or wins and leaves nobody to add.
+func (s *EventStore) AddOrganizer(ctx context.Context, eventID, userID, grantedBy int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var added bool
+	err := s.db.QueryRowContext(ctx, `
+		WITH participant AS (
+			SELECT event_id, user_id FROM event_participants
+			WHERE event_id = $1 AND user_id = $2
+			FOR UPDATE
+		), inserted AS (
+			INSERT INTO event_organizers (event_id, user_id, granted_by)
+			SELECT event_id, user_id, $3 FROM participant
+			ON CONFLICT (event_id, user_id) DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM participant)`, eventID, userID, grantedBy).Scan(&added)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrNotJoined
+	}
+	return nil
+}
+
+// RemoveOrganizer takes the co-organizer rights of the user away, if they
+// had any.
+func (s *EventStore) RemoveOrganizer(ctx context.Context, eventID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		return removeOrganizer(ctx, tx, eventID, userID)
+	})
+}
+
+// getOrganizers returns the user ids of the event's co-organizers, in the
+// order they were added.
+func (s *EventStore) getOrganizers(ctx context.Context, eventID int64) ([]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id FROM event_organizers WHERE event_id = $1 ORDER BY created_at, user_id`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizers := []int64{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		organizers = append(organizers, userID)
+	}
+
+	return organizers, rows.Err()
+}
+
+// removeOrganizer drops the co-organizer rights of the user, e.g. when they
+// leave the event.
+func removeOrganizer(ctx context.Context, tx *sql.Tx, eventID, userID int64) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM event_organizers WHERE event_id = $1 AND user_id = $2`, eventID, userID)
+	return err
+}
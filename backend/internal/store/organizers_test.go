@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test AddOrganizer grants the rights to a participant
+func TestEventStore_AddOrganizer(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectQuery(`FROM event_participants WHERE event_id = \$1 AND user_id = \$2 FOR UPDATE .* INSERT INTO event_organizers \(event_id, user_id, granted_by\)`).
+		WithArgs(int64(1), int64(2), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err := store.AddOrganizer(context.Background(), 1, 2, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test AddOrganizer for a user that hasn't joined the event
+func TestEventStore_AddOrganizer_NotJoined(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectQuery(`INSERT INTO event_organizers`).
+		WithArgs(int64(1), int64(2), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	err := store.AddOrganizer(context.Background(), 1, 2, 3)
+	assert.Equal(t, ErrNotJoined, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
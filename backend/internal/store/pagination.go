@@ -0,0 +1,153 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultEventPageSize = 20
+	MaxEventPageSize     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// cursorTimeLayout matches the precision of Postgres TIMESTAMP columns and has
+// no zone, so the value compares the same regardless of the session time zone.
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// PageQuery selects one page of a keyset paginated listing.
+type PageQuery struct {
+	Limit        int
+	Cursor       string // opaque, taken from EventPage.NextCursor
+	IncludeTotal bool
+}
+
+// EventPage is one page of events. NextCursor is empty on the last page and
+// Total is only set when it was asked for.
+type EventPage struct {
+	Events     []*Event
+	NextCursor string
+	Total      *int
+}
+
+func (p PageQuery) limit() int {
+	if p.Limit <= 0 {
+		return DefaultEventPageSize
+	}
+	if p.Limit > MaxEventPageSize {
+		return MaxEventPageSize
+	}
+	return p.Limit
+}
+
+// sortKey describes a column events can be ordered and paginated by.
+type sortKey struct {
+	column string // column of the listing subquery
+	cast   string // type the cursor value is cast to in SQL
+	value  func(*Event) string
+	valid  func(string) bool
+}
+
+var eventSortKeys = map[string]sortKey{
+	"created_at": {
+		column: "created_at",
+		cast:   "timestamp",
+		value:  func(e *Event) string { return e.CreatedAt.Format(cursorTimeLayout) },
+		valid:  isCursorTime,
+	},
+	"event_datetime": {
+		column: "event_datetime",
+		cast:   "timestamp",
+		value:  func(e *Event) string { return e.EventDateTime.Format(cursorTimeLayout) },
+		valid:  isCursorTime,
+	},
+	"registered_count": {
+		column: "registered_count",
+		cast:   "bigint",
+		value:  func(e *Event) string { return strconv.Itoa(e.RegisteredCount) },
+		valid: func(v string) bool {
+			_, err := strconv.ParseInt(v, 10, 64)
+			return err == nil
+		},
+	},
+	"distance": {
+		column: "distance_km",
+		cast:   "float8",
+		value: func(e *Event) string {
+			if e.DistanceKm == nil {
+				return "0"
+			}
+			return strconv.FormatFloat(*e.DistanceKm, 'g', -1, 64)
+		},
+		valid: func(v string) bool {
+			_, err := strconv.ParseFloat(v, 64)
+			return err == nil
+		},
+	},
+}
+
+func isCursorTime(v string) bool {
+	_, err := time.Parse(cursorTimeLayout, v)
+	return err == nil
+}
+
+// eventCursor is the position after the last event of a page. It remembers
+// the sort it was issued for so it can't be replayed against another order.
+type eventCursor struct {
+	SortBy string `json:"s"`
+	Order  string `json:"o"`
+	Value  string `json:"v"`
+	ID     int64  `json:"id"`
+}
+
+func encodeEventCursor(sortBy, order string, e *Event) string {
+	b, _ := json.Marshal(eventCursor{
+		SortBy: sortBy,
+		Order:  order,
+		Value:  eventSortKeys[sortBy].value(e),
+		ID:     e.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeEventCursor(cursor, sortBy, order string) (*eventCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c eventCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != sortBy || c.Order != order || !eventSortKeys[sortBy].valid(c.Value) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// encodeIDCursor is the cursor of listings ordered by id alone.
+func encodeIDCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeIDCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var filteredEventColumns = []string{
+	"id", "event_owner", "sport", "event_datetime", "max_players",
+	"location_name", "latitude", "longitude", "description",
+	"title", "is_full", "status", "cancellation_reason", "cancelled_at",
+	"created_at", "updated_at", "registered_count", "distance_km",
+}
+
+func filteredEventRow(rows *sqlmock.Rows, id int64, createdAt time.Time) *sqlmock.Rows {
+	return rows.AddRow(id, 1, "Football", createdAt, 10, "Central Park", 40.7829, -73.9654, "", "Test", false, EventStatusScheduled, nil, nil, createdAt, createdAt, 0, nil)
+}
+
+// Test the cursor of a page picks up right after its last event
+func TestEventStore_GetAllWithFilter_Cursor(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	base := time.Date(2025, 3, 1, 10, 0, 0, 123456000, time.UTC)
+
+	// First page, one row more than asked for
+	rows := sqlmock.NewRows(filteredEventColumns)
+	for i := int64(1); i <= 3; i++ {
+		filteredEventRow(rows, i, base.Add(time.Duration(i)*time.Hour))
+	}
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM \(.*GROUP BY e.id \) ev`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`ORDER BY ev.created_at ASC, ev.id ASC LIMIT \$3`).
+		WithArgs(EventStatusCancelled, VisibilityPublic, 3).
+		WillReturnRows(rows)
+
+	page, err := store.GetAllWithFilter(context.Background(), &EventFilter{
+		PageQuery: PageQuery{Limit: 2, IncludeTotal: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, 5, *page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// Second page continues after event 2
+	rows = sqlmock.NewRows(filteredEventColumns)
+	filteredEventRow(rows, 3, base.Add(3*time.Hour))
+	mock.ExpectQuery(`WHERE \(ev.created_at, ev.id\) > \(\$3::timestamp, \$4\) ORDER BY ev.created_at ASC, ev.id ASC LIMIT \$5`).
+		WithArgs(EventStatusCancelled, VisibilityPublic, "2025-03-01 12:00:00.123456", int64(2), 3).
+		WillReturnRows(rows)
+
+	page, err = store.GetAllWithFilter(context.Background(), &EventFilter{
+		PageQuery: PageQuery{Limit: 2, Cursor: page.NextCursor},
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Nil(t, page.Total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test a cursor is rejected when replayed against another sort
+func TestEventStore_GetAllWithFilter_InvalidCursor(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	cursor := encodeEventCursor("created_at", "ASC", &Event{ID: 1, CreatedAt: time.Now()})
+
+	tests := []struct {
+		name   string
+		filter *EventFilter
+	}{
+		{
+			name:   "garbage",
+			filter: &EventFilter{PageQuery: PageQuery{Cursor: "not-a-cursor"}},
+		},
+		{
+			name:   "other sort column",
+			filter: &EventFilter{SortBy: "event_datetime", PageQuery: PageQuery{Cursor: cursor}},
+		},
+		{
+			name:   "other direction",
+			filter: &EventFilter{Order: "desc", PageQuery: PageQuery{Cursor: cursor}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.GetAllWithFilter(context.Background(), tt.filter)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test GetAllSimple loads the participants of the whole page in one query
+func TestEventStore_GetAllSimple(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	now := time.Now()
+
+	columns := []string{
+		"id", "event_owner", "sport", "event_datetime", "max_players", "location_name",
+		"latitude", "longitude", "description", "title", "is_full", "status", "created_at", "updated_at",
+		"first_name", "last_name", "email",
+	}
+	mock.ExpectQuery(`FROM events e .* WHERE e.status <> \$1 AND e.visibility = \$2 ORDER BY e.created_at DESC, e.id DESC LIMIT \$3`).
+		WithArgs(EventStatusCancelled, VisibilityPublic, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(2, 1, "Tennis", now, 4, "Brooklyn", 40.6782, -73.9442, "", "Newer", false, EventStatusScheduled, now, now, "Owner", "User", "owner@example.com").
+			AddRow(1, 1, "Football", now, 10, "Central Park", 40.7829, -73.9654, "", "Older", false, EventStatusScheduled, now, now, "Owner", "User", "owner@example.com"))
+	mock.ExpectQuery(`FROM event_participants ep .* WHERE ep.event_id = ANY\(\$1\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "user_id", "joined_at", "first_name", "last_name"}).
+			AddRow(1, 2, 2, now, "Test", "User").
+			AddRow(2, 2, 3, now, "Other", "User"))
+
+	page, err := store.GetAllSimple(context.Background(), PageQuery{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, int64(2), page.Events[0].ID)
+	assert.Len(t, page.Events[0].Participants, 2)
+	assert.Equal(t, 2, page.Events[0].RegisteredCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("reset token is invalid or has expired")
+)
+
+type PasswordResetStore struct {
+	db *sql.DB
+}
+
+// Create stores a new reset token for the user. Only the hash of the token is
+// persisted so a database leak cannot be used to take over accounts.
+func (s *PasswordResetStore) Create(ctx context.Context, userID int64, tokenHash string, exp time.Duration) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tokenHash, time.Now().Add(exp))
+	return err
+}
+
+// Reset consumes the token and replaces the password of its user with the one
+// set on user. Every other outstanding reset token of that user is burned, its
+// refresh tokens are revoked and the token version is bumped so previously
+// issued JWTs stop working. Since the token proves ownership of the mailbox,
+// the account is activated as well. On success user is filled with the
+// account the token belonged to.
+func (s *PasswordResetStore) Reset(ctx context.Context, tokenHash string, user *User) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT user_id
+			FROM password_reset_tokens
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+			FOR UPDATE`
+
+		err := tx.QueryRowContext(ctx, query, tokenHash, time.Now()).Scan(&user.ID)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrInvalidResetToken
+			default:
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE password_reset_tokens
+			SET used_at = $2
+			WHERE user_id = $1 AND used_at IS NULL`, user.ID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := revokeUserRefreshTokens(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE users
+			SET password = $1, token_version = token_version + 1, is_active = TRUE, updated_at = NOW()
+			WHERE id = $2
+			RETURNING email, token_version`
+
+		err = tx.QueryRowContext(ctx, query, user.Password.hash, user.ID).Scan(
+			&user.Email,
+			&user.TokenVersion,
+		)
+		if err != nil {
+			return err
+		}
+		user.IsActive = true
+
+		return nil
+	})
+}
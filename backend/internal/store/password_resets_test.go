@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test Create Reset Token
+func TestPasswordResetStore_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &PasswordResetStore{db: db}
+
+	mock.ExpectExec(`INSERT INTO password_reset_tokens \(user_id, token_hash, expires_at\)`).
+		WithArgs(int64(1), "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := store.Create(context.Background(), 1, "hash", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Reset consumes the token and bumps the token version
+func TestPasswordResetStore_Reset(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &PasswordResetStore{db: db}
+	user := &User{}
+	assert.NoError(t, user.Password.Set("newsecurepassword"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM password_reset_tokens WHERE token_hash = \$1 AND used_at IS NULL AND expires_at > \$2 FOR UPDATE`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectExec(`UPDATE password_reset_tokens SET used_at = \$2 WHERE user_id = \$1 AND used_at IS NULL`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = \$2 WHERE user_id = \$1 AND revoked_at IS NULL`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`UPDATE users SET password = \$1, token_version = token_version \+ 1`).
+		WithArgs(user.Password.hash, int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "token_version"}).AddRow("test@example.com", 1))
+	mock.ExpectCommit()
+
+	err := store.Reset(context.Background(), "hash", user)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.Equal(t, "test@example.com", user.Email)
+	assert.Equal(t, 1, user.TokenVersion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Reset with an unknown, used or expired token
+func TestPasswordResetStore_Reset_InvalidToken(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &PasswordResetStore{db: db}
+	user := &User{}
+	assert.NoError(t, user.Password.Set("newsecurepassword"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT user_id FROM password_reset_tokens`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := store.Reset(context.Background(), "hash", user)
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
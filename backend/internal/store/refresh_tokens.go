@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or has expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// RefreshToken is a long lived, single-use token that is exchanged for a new
+// access token. Every token obtained by rotating another one shares its
+// family, which lets us revoke a whole login session at once.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokenStore struct {
+	db *sql.DB
+}
+
+// Create stores the first token of a new family.
+func (s *RefreshTokenStore) Create(ctx context.Context, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, family_id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.CreatedAt,
+	)
+}
+
+// Rotate revokes the token matching oldHash and stores next in its place,
+// copying over the user and family. Presenting a token that was already
+// rotated means it leaked, so the whole family is revoked and
+// ErrRefreshTokenReused is returned.
+func (s *RefreshTokenStore) Rotate(ctx context.Context, oldHash string, next *RefreshToken) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	reused := false
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, user_id, family_id, expires_at, revoked_at
+			FROM refresh_tokens
+			WHERE token_hash = $1
+			FOR UPDATE`
+
+		var current RefreshToken
+		err := tx.QueryRowContext(ctx, query, oldHash).Scan(
+			&current.ID,
+			&current.UserID,
+			&current.FamilyID,
+			&current.ExpiresAt,
+			&current.RevokedAt,
+		)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrInvalidRefreshToken
+			default:
+				return err
+			}
+		}
+
+		if current.RevokedAt != nil {
+			// The revocation has to be committed, so report the reuse only
+			// after the transaction is done
+			reused = true
+			return revokeFamily(ctx, tx, current.FamilyID)
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = $2 WHERE id = $1`, current.ID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+
+		query = `
+			INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at`
+
+		return tx.QueryRowContext(ctx, query, next.UserID, next.FamilyID, next.TokenHash, next.ExpiresAt).Scan(
+			&next.ID,
+			&next.CreatedAt,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrRefreshTokenReused
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes the token matching tokenHash and every token rotated
+// from the same login. Unknown tokens are ignored.
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var familyID string
+		err := tx.QueryRowContext(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`, tokenHash).Scan(&familyID)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		return revokeFamily(ctx, tx, familyID)
+	})
+}
+
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE family_id = $1 AND revoked_at IS NULL`, familyID, time.Now())
+	return err
+}
+
+func revokeUserRefreshTokens(ctx context.Context, tx *sql.Tx, userID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID, time.Now())
+	return err
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var refreshTokenColumns = []string{"id", "user_id", "family_id", "expires_at", "revoked_at"}
+
+// Test Create Refresh Token starts a new family
+func TestRefreshTokenStore_Create(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &RefreshTokenStore{db: db}
+	token := &RefreshToken{UserID: 1, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectQuery(`INSERT INTO refresh_tokens \(user_id, token_hash, expires_at\)`).
+		WithArgs(int64(1), "hash", token.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "family_id", "created_at"}).AddRow(1, "family", time.Now()))
+
+	err := store.Create(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), token.ID)
+	assert.Equal(t, "family", token.FamilyID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Rotate revokes the old token and keeps the family
+func TestRefreshTokenStore_Rotate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &RefreshTokenStore{db: db}
+	next := &RefreshToken{TokenHash: "new", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = \$1 FOR UPDATE`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(1, 7, "family", time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = \$2 WHERE id = \$1`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO refresh_tokens \(user_id, family_id, token_hash, expires_at\)`).
+		WithArgs(int64(7), "family", "new", next.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+	mock.ExpectCommit()
+
+	err := store.Rotate(context.Background(), "old", next)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), next.ID)
+	assert.Equal(t, int64(7), next.UserID)
+	assert.Equal(t, "family", next.FamilyID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Rotate with a replayed token revokes the whole family and commits
+func TestRefreshTokenStore_Rotate_Reused(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &RefreshTokenStore{db: db}
+	next := &RefreshToken{TokenHash: "new", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(1, 7, "family", time.Now().Add(time.Hour), time.Now()))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = \$2 WHERE family_id = \$1 AND revoked_at IS NULL`).
+		WithArgs("family", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.Rotate(context.Background(), "old", next)
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Zero(t, next.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Rotate with an expired token
+func TestRefreshTokenStore_Rotate_Expired(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &RefreshTokenStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, family_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows(refreshTokenColumns).AddRow(1, 7, "family", time.Now().Add(-time.Hour), nil))
+	mock.ExpectRollback()
+
+	err := store.Rotate(context.Background(), "old", &RefreshToken{TokenHash: "new"})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RevokeFamily ignores unknown tokens
+func TestRefreshTokenStore_RevokeFamily_Unknown(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &RefreshTokenStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT family_id FROM refresh_tokens WHERE token_hash = \$1`).
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectCommit()
+
+	err := store.RevokeFamily(context.Background(), "unknown")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const NotificationEventReminder = "event_reminder"
+
+// ReminderStore records which reminders have been sent, so every participant
+// gets each reminder of an event once even across restarts.
+type ReminderStore struct {
+	db *sql.DB
+}
+
+// SendDue creates a reminder notification for every participant of the
+// scheduled events starting within lead of now that hasn't received it yet,
+// and returns them. Participants who already got a reminder with a shorter
+// lead are skipped, so someone joining an hour before kickoff isn't told the
+// event is a day away.
+func (s *ReminderStore) SendDue(ctx context.Context, now time.Time, lead time.Duration) ([]*Notification, error) {
+	query := `
+		WITH due AS (
+			INSERT INTO event_reminders (event_id, user_id, lead_minutes)
+			SELECT ep.event_id, ep.user_id, $3
+			FROM event_participants ep
+			JOIN events e ON e.id = ep.event_id
+			WHERE e.status = $4 AND e.event_datetime > $1 AND e.event_datetime <= $2
+			AND NOT EXISTS (
+				SELECT 1 FROM event_reminders r
+				WHERE r.event_id = ep.event_id AND r.user_id = ep.user_id AND r.lead_minutes < $3
+			)
+			ON CONFLICT DO NOTHING
+			RETURNING event_id, user_id
+		)
+		INSERT INTO notifications (user_id, event_id, type, message)
+		SELECT due.user_id, e.id, $5, '"' || COALESCE(NULLIF(e.title, ''), e.sport) || '" starts in ' || $6
+		FROM due
+		JOIN events e ON e.id = due.event_id
+		RETURNING ` + notificationColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query,
+		now,
+		now.Add(lead),
+		int(lead.Minutes()),
+		EventStatusScheduled,
+		NotificationEventReminder,
+		formatLead(lead),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// formatLead spells out a lead time for reminder messages, e.g. "24 hours".
+func formatLead(lead time.Duration) string {
+	if lead >= time.Hour && lead%time.Hour == 0 {
+		return plural(int(lead/time.Hour), "hour")
+	}
+	return plural(int(lead.Minutes()), "minute")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
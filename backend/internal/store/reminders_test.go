@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test SendDue records the reminders and returns their notifications
+func TestReminderStore_SendDue(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &ReminderStore{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery(`WITH due AS \( INSERT INTO event_reminders \(event_id, user_id, lead_minutes\) .* ON CONFLICT DO NOTHING RETURNING event_id, user_id \) INSERT INTO notifications .* COALESCE\(NULLIF\(e.title, ''\), e.sport\)`).
+		WithArgs(now, now.Add(time.Hour), 60, EventStatusScheduled, NotificationEventReminder, "1 hour").
+		WillReturnRows(sqlmock.NewRows(notificationTestColumns).
+			AddRow(1, 2, 7, NotificationEventReminder, `"Morning Soccer" starts in 1 hour`, nil, now).
+			AddRow(2, 3, 7, NotificationEventReminder, `"Morning Soccer" starts in 1 hour`, nil, now))
+
+	notifications, err := store.SendDue(context.Background(), now, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 2)
+	assert.Equal(t, int64(2), notifications[0].UserID)
+	assert.Equal(t, int64(7), *notifications[0].EventID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFormatLead(t *testing.T) {
+	assert.Equal(t, "24 hours", formatLead(24*time.Hour))
+	assert.Equal(t, "1 hour", formatLead(time.Hour))
+	assert.Equal(t, "30 minutes", formatLead(30*time.Minute))
+	assert.Equal(t, "90 minutes", formatLead(90*time.Minute))
+}
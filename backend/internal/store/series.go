@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	FrequencyWeekly   = "weekly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyMonthly  = "monthly"
+
+	// MaxSeriesOccurrences caps how many events a single series materializes.
+	MaxSeriesOccurrences = 52
+)
+
+var (
+	ErrSeriesNotFound     = errors.New("event series not found")
+	ErrNotInSeries        = errors.New("event is not part of a series")
+	ErrInvalidRecurrence  = errors.New("recurrence needs a frequency and either until or count")
+	ErrTooManyOccurrences = errors.New("recurrence produces too many occurrences")
+)
+
+// EventSeries is a recurrence rule in the spirit of an iCalendar RRULE. Every
+// occurrence is materialized as a regular event pointing back at the series.
+type EventSeries struct {
+	ID         int64      `json:"id"`
+	EventOwner int64      `json:"event_owner"`
+	Frequency  string     `json:"frequency"`
+	StartsAt   time.Time  `json:"starts_at"`
+	Until      *time.Time `json:"until,omitempty"`
+	Count      *int       `json:"count,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Events     []*Event   `json:"events"`
+}
+
+// Occurrences expands the recurrence into the start time of every event of
+// the series. Monthly occurrences on a day the month doesn't have are skipped,
+// like RRULE does.
+func (s *EventSeries) Occurrences() ([]time.Time, error) {
+	if (s.Until == nil) == (s.Count == nil) {
+		return nil, ErrInvalidRecurrence
+	}
+	if s.Count != nil && *s.Count <= 0 {
+		return nil, ErrInvalidRecurrence
+	}
+	if s.Count != nil && *s.Count > MaxSeriesOccurrences {
+		return nil, ErrTooManyOccurrences
+	}
+	if s.Until != nil && s.Until.Before(s.StartsAt) {
+		return nil, ErrInvalidRecurrence
+	}
+
+	var next func(i int) time.Time
+	switch s.Frequency {
+	case FrequencyWeekly:
+		next = func(i int) time.Time { return s.StartsAt.AddDate(0, 0, 7*i) }
+	case FrequencyBiweekly:
+		next = func(i int) time.Time { return s.StartsAt.AddDate(0, 0, 14*i) }
+	case FrequencyMonthly:
+		next = func(i int) time.Time { return s.StartsAt.AddDate(0, i, 0) }
+	default:
+		return nil, ErrInvalidRecurrence
+	}
+
+	var occurrences []time.Time
+	for i := 0; ; i++ {
+		t := next(i)
+		if s.Until != nil && t.After(*s.Until) {
+			break
+		}
+		if s.Frequency == FrequencyMonthly && t.Day() != s.StartsAt.Day() {
+			continue
+		}
+		if len(occurrences) == MaxSeriesOccurrences {
+			return nil, ErrTooManyOccurrences
+		}
+		occurrences = append(occurrences, t)
+		if s.Count != nil && len(occurrences) == *s.Count {
+			break
+		}
+	}
+	return occurrences, nil
+}
+
+// CreateSeries stores the series and materializes one event per occurrence,
+// copying every other field from template.
+func (s *EventStore) CreateSeries(ctx context.Context, series *EventSeries, template *Event) error {
+	occurrences, err := series.Occurrences()
+	if err != nil {
+		return err
+	}
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			INSERT INTO event_series (event_owner, frequency, starts_at, until, count)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, updated_at`
+
+		err := tx.QueryRowContext(ctx, query,
+			series.EventOwner,
+			series.Frequency,
+			series.StartsAt,
+			series.Until,
+			series.Count,
+		).Scan(&series.ID, &series.CreatedAt, &series.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO events (
+				event_owner, sport, event_datetime, max_players,
+				location_name, latitude, longitude, description,
+				title, is_full, series_id, visibility, requires_approval
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, FALSE, $10, $11, $12)
+			RETURNING id, created_at, updated_at`
+
+		if template.Visibility == "" {
+			template.Visibility = VisibilityPublic
+		}
+
+		series.Events = make([]*Event, 0, len(occurrences))
+		for _, at := range occurrences {
+			event := *template
+			event.EventOwner = series.EventOwner
+			event.EventDateTime = at
+			event.SeriesID = &series.ID
+			event.Participants = []EventParticipant{}
+
+			err := tx.QueryRowContext(ctx, query,
+				event.EventOwner,
+				event.Sport,
+				event.EventDateTime,
+				event.MaxPlayers,
+				event.LocationName,
+				event.Latitude,
+				event.Longitude,
+				event.Description,
+				event.Title,
+				series.ID,
+				event.Visibility,
+				event.RequiresApproval,
+			).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			series.Events = append(series.Events, &event)
+		}
+
+		return nil
+	})
+}
+
+// GetSeries returns the series with its occurrences in chronological order.
+func (s *EventStore) GetSeries(ctx context.Context, seriesID int64) (*EventSeries, error) {
+	query := `
+		SELECT id, event_owner, frequency, starts_at, until, count, created_at, updated_at
+		FROM event_series
+		WHERE id = $1`
+
+	series := &EventSeries{}
+	err := s.db.QueryRowContext(ctx, query, seriesID).Scan(
+		&series.ID,
+		&series.EventOwner,
+		&series.Frequency,
+		&series.StartsAt,
+		&series.Until,
+		&series.Count,
+		&series.CreatedAt,
+		&series.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSeriesNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT e.id, e.event_owner, e.sport, e.event_datetime, e.max_players,
+		       e.location_name, e.latitude, e.longitude, e.description,
+		       e.title, e.is_full, e.status, e.visibility, e.requires_approval,
+		       e.cancellation_reason, e.cancelled_at, e.created_at, e.updated_at, e.series_id,
+		       COUNT(ep.id) AS registered_count
+		FROM events e
+		LEFT JOIN event_participants ep ON e.id = ep.event_id
+		WHERE e.series_id = $1
+		GROUP BY e.id
+		ORDER BY e.event_datetime ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series.Events = []*Event{}
+	for rows.Next() {
+		var e Event
+		err := rows.Scan(
+			&e.ID, &e.EventOwner, &e.Sport, &e.EventDateTime, &e.MaxPlayers,
+			&e.LocationName, &e.Latitude, &e.Longitude, &e.Description,
+			&e.Title, &e.IsFull, &e.Status, &e.Visibility, &e.RequiresApproval,
+			&e.CancellationReason, &e.CancelledAt, &e.CreatedAt, &e.UpdatedAt, &e.SeriesID, &e.RegisteredCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		series.Events = append(series.Events, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// UpdateFollowing copies the fields of event onto it and every later
+// scheduled occurrence of its series, moving all of them by shift. Players on
+// their waitlists move up into the spots a higher max_players opens.
+func (s *EventStore) UpdateFollowing(ctx context.Context, event *Event, shift time.Duration) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		seriesID, from, err := lockOccurrence(ctx, tx, event.ID)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE events
+			SET
+				sport = $1,
+				max_players = $2,
+				location_name = $3,
+				latitude = $4,
+				longitude = $5,
+				description = $6,
+				title = $7,
+				event_datetime = event_datetime + make_interval(secs => $8),
+				is_full = (SELECT COUNT(*) FROM event_participants ep WHERE ep.event_id = events.id) >= $2,
+				updated_at = $9,
+				visibility = $13,
+				requires_approval = $14
+			WHERE series_id = $10 AND event_datetime >= $11 AND status = $12
+			RETURNING id`
+
+		rows, err := tx.QueryContext(ctx, query,
+			event.Sport,
+			event.MaxPlayers,
+			event.LocationName,
+			event.Latitude,
+			event.Longitude,
+			event.Description,
+			event.Title,
+			shift.Seconds(),
+			time.Now(),
+			seriesID,
+			from,
+			EventStatusScheduled,
+			event.Visibility,
+			event.RequiresApproval,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var updated []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			updated = append(updated, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Raising max_players frees spots for whoever is waiting
+		for _, id := range updated {
+			promoted, err := promoteFromWaitlist(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if len(promoted) == 0 {
+				continue
+			}
+			_, err = tx.ExecContext(ctx, `
+				UPDATE events
+				SET is_full = (SELECT COUNT(*) >= max_players FROM event_participants WHERE event_id = $1)
+				WHERE id = $1`, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CancelFollowing cancels the occurrence and every later scheduled one of its
+// series, notifies their participants and ends the series right before it. It
+// returns the notifications it created.
+func (s *EventStore) CancelFollowing(ctx context.Context, eventID int64, reason string) ([]*Notification, error) {
+	var notifications []*Notification
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		seriesID, from, err := lockOccurrence(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE events
+			SET status = $3, cancellation_reason = NULLIF($4, ''), cancelled_at = $5, updated_at = $5
+			WHERE series_id = $1 AND event_datetime >= $2 AND status = $6
+			RETURNING id`, seriesID, from, EventStatusCancelled, reason, now, EventStatusScheduled)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var cancelled []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			cancelled = append(cancelled, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(cancelled) == 0 {
+			return ErrEventNotScheduled
+		}
+
+		notifications, err = notifyEventCancelled(ctx, tx, cancelled, reason)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE event_series
+			SET until = $2, count = NULL, updated_at = $3
+			WHERE id = $1`, seriesID, from.Add(-time.Second), now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// lockOccurrence locks the event and returns its series and start time.
+func lockOccurrence(ctx context.Context, tx *sql.Tx, eventID int64) (int64, time.Time, error) {
+	var seriesID sql.NullInt64
+	var at time.Time
+
+	err := tx.QueryRowContext(ctx, `SELECT series_id, event_datetime FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&seriesID, &at)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return 0, at, ErrEventNotFound
+		default:
+			return 0, at, err
+		}
+	}
+	if !seriesID.Valid {
+		return 0, at, ErrNotInSeries
+	}
+
+	return seriesID.Int64, at, nil
+}
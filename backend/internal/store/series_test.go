@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSeries_Occurrences(t *testing.T) {
+	start := time.Date(2025, 1, 7, 18, 0, 0, 0, time.UTC) // a Tuesday
+	count := func(n int) *int { return &n }
+	until := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name    string
+		series  EventSeries
+		want    []time.Time
+		wantErr error
+	}{
+		{
+			name:   "weekly with count",
+			series: EventSeries{Frequency: FrequencyWeekly, StartsAt: start, Count: count(3)},
+			want:   []time.Time{start, start.AddDate(0, 0, 7), start.AddDate(0, 0, 14)},
+		},
+		{
+			name:   "biweekly until is inclusive",
+			series: EventSeries{Frequency: FrequencyBiweekly, StartsAt: start, Until: until(start.AddDate(0, 0, 28))},
+			want:   []time.Time{start, start.AddDate(0, 0, 14), start.AddDate(0, 0, 28)},
+		},
+		{
+			name: "monthly skips months without the day",
+			series: EventSeries{
+				Frequency: FrequencyMonthly,
+				StartsAt:  time.Date(2025, 1, 31, 18, 0, 0, 0, time.UTC),
+				Count:     count(3),
+			},
+			want: []time.Time{
+				time.Date(2025, 1, 31, 18, 0, 0, 0, time.UTC),
+				time.Date(2025, 3, 31, 18, 0, 0, 0, time.UTC),
+				time.Date(2025, 5, 31, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "needs until or count",
+			series:  EventSeries{Frequency: FrequencyWeekly, StartsAt: start},
+			wantErr: ErrInvalidRecurrence,
+		},
+		{
+			name:    "not both until and count",
+			series:  EventSeries{Frequency: FrequencyWeekly, StartsAt: start, Count: count(2), Until: until(start)},
+			wantErr: ErrInvalidRecurrence,
+		},
+		{
+			name:    "unknown frequency",
+			series:  EventSeries{Frequency: "daily", StartsAt: start, Count: count(2)},
+			wantErr: ErrInvalidRecurrence,
+		},
+		{
+			name:    "zero count",
+			series:  EventSeries{Frequency: FrequencyWeekly, StartsAt: start, Count: count(0)},
+			wantErr: ErrInvalidRecurrence,
+		},
+		{
+			name:    "until before the start",
+			series:  EventSeries{Frequency: FrequencyWeekly, StartsAt: start, Until: until(start.AddDate(0, 0, -1))},
+			wantErr: ErrInvalidRecurrence,
+		},
+		{
+			name:    "too many occurrences",
+			series:  EventSeries{Frequency: FrequencyWeekly, StartsAt: start, Until: until(start.AddDate(2, 0, 0))},
+			wantErr: ErrTooManyOccurrences,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.series.Occurrences()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Test CreateSeries materializes one event per occurrence
+func TestEventStore_CreateSeries(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	start := time.Date(2025, 1, 7, 18, 0, 0, 0, time.UTC)
+	count := 2
+	series := &EventSeries{EventOwner: 1, Frequency: FrequencyWeekly, StartsAt: start, Count: &count}
+	template := &Event{Sport: "Football", MaxPlayers: 10, LocationName: "Central Park", Title: "Tuesday pickup"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO event_series \(event_owner, frequency, starts_at, until, count\)`).
+		WithArgs(int64(1), FrequencyWeekly, start, nil, &count).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, time.Now(), time.Now()))
+	for i, at := range []time.Time{start, start.AddDate(0, 0, 7)} {
+		mock.ExpectQuery(`INSERT INTO events \(.*series_id`).
+			WithArgs(int64(1), "Football", at, 10, "Central Park", 0.0, 0.0, "", "Tuesday pickup", int64(4), VisibilityPublic, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(i+10, time.Now(), time.Now()))
+	}
+	mock.ExpectCommit()
+
+	err := store.CreateSeries(context.Background(), series, template)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), series.ID)
+	require.Len(t, series.Events, 2)
+	assert.Equal(t, int64(11), series.Events[1].ID)
+	assert.Equal(t, int64(4), *series.Events[1].SeriesID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test UpdateFollowing moves this and every later occurrence and fills the
+// spots it opens from the waitlists
+func TestEventStore_UpdateFollowing(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	from := time.Date(2025, 1, 14, 18, 0, 0, 0, time.UTC)
+	event := &Event{ID: 2, Sport: "Football", MaxPlayers: 12, LocationName: "Central Park", Title: "Tuesday pickup", Visibility: VisibilityPrivate, RequiresApproval: true}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT series_id, event_datetime FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "event_datetime"}).AddRow(4, from))
+	mock.ExpectQuery(`UPDATE events SET .* event_datetime = event_datetime \+ make_interval\(secs => \$8\).* WHERE series_id = \$10 AND event_datetime >= \$11 AND status = \$12 RETURNING id`).
+		WithArgs("Football", 12, "Central Park", 0.0, 0.0, "", "Tuesday pickup", 3600.0, sqlmock.AnyArg(), int64(4), from, EventStatusScheduled, VisibilityPrivate, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+
+	// Occurrence 2 had user 5 waiting for the spot it now has, 3 nobody
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(2), int64(5)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE event_waitlist SET position = position - 1`).
+		WithArgs(int64(2), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(false))
+	mock.ExpectExec(`UPDATE events SET is_full`).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(3)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectCommit()
+
+	err := store.UpdateFollowing(context.Background(), event, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CancelFollowing cancels the rest of the series and ends it before the occurrence
+func TestEventStore_CancelFollowing(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+	from := time.Date(2025, 1, 14, 18, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT series_id, event_datetime FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "event_datetime"}).AddRow(4, from))
+	mock.ExpectQuery(`UPDATE events SET status = \$3, .* WHERE series_id = \$1 AND event_datetime >= \$2 AND status = \$6 RETURNING id`).
+		WithArgs(int64(4), from, EventStatusCancelled, "Pitch closed", sqlmock.AnyArg(), EventStatusScheduled).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO notifications`).
+		WithArgs(sqlmock.AnyArg(), NotificationEventCancelled, "Pitch closed").
+		WillReturnRows(sqlmock.NewRows(notificationTestColumns).
+			AddRow(1, 5, 2, NotificationEventCancelled, `"Tuesday pickup" has been cancelled: Pitch closed`, nil, time.Now()))
+	mock.ExpectExec(`UPDATE event_series SET until = \$2, count = NULL`).
+		WithArgs(int64(4), from.Add(-time.Second), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	notifications, err := store.CancelFollowing(context.Background(), 2, "Pitch closed")
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CancelFollowing on a standalone event
+func TestEventStore_CancelFollowing_NotInSeries(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT series_id, event_datetime FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"series_id", "event_datetime"}).AddRow(nil, time.Now()))
+	mock.ExpectRollback()
+
+	_, err := store.CancelFollowing(context.Background(), 2, "")
+	assert.ErrorIs(t, err, ErrNotInSeries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -18,7 +18,14 @@ type Storage struct {
 		GetByID(context.Context, int64) (*User, error)
 		GetByEmail(context.Context, string) (*User, error)
 		Create(context.Context, *User) error
+		CreateAndInvite(ctx context.Context, user *User, tokenHash string, invitationExp time.Duration) error
+		Reinvite(ctx context.Context, userID int64, tokenHash string, invitationExp time.Duration) error
+		Activate(ctx context.Context, tokenHash string) (*User, error)
+		Delete(context.Context, int64) error
 		CreateOrUpdateGoogleUser(ctx context.Context, googleID, email, name string) (*User, bool, error)
+		List(context.Context, PageQuery) (*UserPage, error)
+		Deactivate(context.Context, int64) error
+		SetRole(ctx context.Context, userID int64, role string) error
 	}
 	Profile interface {
 		GetByEmail(context.Context, string) (*Profile, error)
@@ -30,18 +37,79 @@ type Storage struct {
 		GetByID(context.Context, int64) (*Event, error)
 		Update(context.Context, *Event) error
 		Delete(context.Context, int64) error
-		Join(context.Context, int64, int64) error
+		Cancel(context.Context, int64, string) ([]*Notification, error)
+		CompletePast(context.Context, time.Time) (int64, error)
+		Join(ctx context.Context, eventID, userID int64, inviteHash string) error
 		Leave(context.Context, int64, int64) error
-		GetAllWithFilter(context.Context, *EventFilter) ([]*Event, error)
-		GetAllSimple(ctx context.Context) ([]*Event, error)
+		JoinWaitlist(ctx context.Context, eventID, userID int64, inviteHash string) (*WaitlistEntry, error)
+		LeaveWaitlist(context.Context, int64, int64) error
+		GetWaitlist(context.Context, int64) ([]WaitlistEntry, error)
+		CreateSeries(context.Context, *EventSeries, *Event) error
+		GetSeries(context.Context, int64) (*EventSeries, error)
+		UpdateFollowing(context.Context, *Event, time.Duration) error
+		CancelFollowing(context.Context, int64, string) ([]*Notification, error)
+		GetAllWithFilter(context.Context, *EventFilter) (*EventPage, error)
+		GetAllSimple(context.Context, PageQuery) (*EventPage, error)
+		GetByUser(context.Context, int64) ([]*Event, error)
+		AddOrganizer(ctx context.Context, eventID, userID, grantedBy int64) error
+		RemoveOrganizer(ctx context.Context, eventID, userID int64) error
+		ProposeTransfer(context.Context, *EventTransfer) error
+		GetPendingTransfer(context.Context, int64) (*EventTransfer, error)
+		RespondTransfer(ctx context.Context, eventID, userID int64, accept bool) (*EventTransfer, error)
+		CancelTransfer(context.Context, int64) error
+		CreateInvite(ctx context.Context, invite *EventInvite, tokenHash string) error
+		CheckInvite(ctx context.Context, eventID int64, tokenHash string) error
+		RequestJoin(ctx context.Context, eventID, userID int64, inviteHash string) (*JoinRequest, error)
+		GetJoinRequests(context.Context, int64) ([]JoinRequest, error)
+		RespondJoinRequest(ctx context.Context, eventID, requestID, respondedBy int64, approve bool) (*JoinRequest, error)
+	}
+	PasswordResets interface {
+		Create(ctx context.Context, userID int64, tokenHash string, exp time.Duration) error
+		Reset(ctx context.Context, tokenHash string, user *User) error
+	}
+	RefreshTokens interface {
+		Create(context.Context, *RefreshToken) error
+		Rotate(ctx context.Context, oldHash string, next *RefreshToken) error
+		RevokeFamily(ctx context.Context, tokenHash string) error
+	}
+	Chat interface {
+		Create(context.Context, *ChatMessage) error
+		GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*ChatMessage, error)
+		GetByID(context.Context, int64) (*ChatMessage, error)
+		Edit(ctx context.Context, msg *ChatMessage, actorID int64) error
+		Delete(ctx context.Context, msg *ChatMessage, actorID int64) error
+		Mute(ctx context.Context, eventID, userID, actorID int64, until time.Time) error
+		Unmute(ctx context.Context, eventID, userID, actorID int64) error
+		Kick(ctx context.Context, eventID, userID, actorID int64, until time.Time) error
+		IsKicked(ctx context.Context, eventID, userID int64) (bool, error)
+	}
+	Notifications interface {
+		Create(context.Context, ...*Notification) error
+		GetByUser(ctx context.Context, userID int64, unreadOnly bool, pageQuery PageQuery) (*NotificationPage, error)
+		MarkRead(ctx context.Context, userID int64, ids []int64) error
+		UnreadCount(ctx context.Context, userID int64) (int, error)
+	}
+	Reminders interface {
+		SendDue(ctx context.Context, now time.Time, lead time.Duration) ([]*Notification, error)
+	}
+	CalendarFeeds interface {
+		Rotate(ctx context.Context, userID int64, tokenHash string) error
+		Revoke(ctx context.Context, userID int64) error
+		GetUserID(ctx context.Context, tokenHash string) (int64, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Users:   &UserStore{db},
-		Profile: &ProfileStore{db},
-		Events:  &EventStore{db},
+		Users:          &UserStore{db},
+		Profile:        &ProfileStore{db},
+		Events:         &EventStore{db},
+		Chat:           &ChatStore{db},
+		PasswordResets: &PasswordResetStore{db},
+		RefreshTokens:  &RefreshTokenStore{db},
+		CalendarFeeds:  &CalendarFeedStore{db},
+		Notifications:  &NotificationStore{db},
+		Reminders:      &ReminderStore{db},
 	}
 }
 
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTransferNotFound = errors.New("no pending ownership transfer for this event")
+)
+
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+// EventTransfer is an owner's offer to hand their event over to one of its
+// participants. The owner only changes once the recipient accepts.
+type EventTransfer struct {
+	ID          int64      `json:"id"`
+	EventID     int64      `json:"event_id"`
+	FromUserID  int64      `json:"from_user_id"`
+	ToUserID    int64      `json:"to_user_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// ProposeTransfer offers the event to a participant on behalf of its owner.
+// It replaces the event's pending transfer, if there is one. The event is
+// locked so concurrent offers replace each other instead of failing.
+func (s *EventStore) ProposeTransfer(ctx context.Context, transfer *EventTransfer) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var owner int64
+		err := tx.QueryRowContext(ctx, `SELECT event_owner FROM events WHERE id = $1 FOR UPDATE`, transfer.EventID).Scan(&owner)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+		// The event may have changed hands since the caller checked
+		if owner != transfer.FromUserID {
+			return ErrForbidden
+		}
+
+		var joined bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2)`,
+			transfer.EventID, transfer.ToUserID).Scan(&joined)
+		if err != nil {
+			return err
+		}
+		if !joined {
+			return ErrNotJoined
+		}
+
+		if err := cancelTransfer(ctx, tx, transfer.EventID); err != nil {
+			return err
+		}
+
+		transfer.Status = TransferPending
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO event_transfers (event_id, from_user_id, to_user_id)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at`,
+			transfer.EventID, transfer.FromUserID, transfer.ToUserID).Scan(&transfer.ID, &transfer.CreatedAt)
+	})
+}
+
+// GetPendingTransfer returns the transfer of the event that is still waiting
+// for an answer.
+func (s *EventStore) GetPendingTransfer(ctx context.Context, eventID int64) (*EventTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	transfer := &EventTransfer{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, event_id, from_user_id, to_user_id, status, created_at, responded_at
+		FROM event_transfers
+		WHERE event_id = $1 AND status = 'pending'`, eventID).Scan(
+		&transfer.ID,
+		&transfer.EventID,
+		&transfer.FromUserID,
+		&transfer.ToUserID,
+		&transfer.Status,
+		&transfer.CreatedAt,
+		&transfer.RespondedAt,
+	)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrTransferNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return transfer, nil
+}
+
+// RespondTransfer answers the pending transfer of the event offered to the
+// user. Accepting makes them the owner, in the same transaction, as long as
+// they are still a participant and the owner hasn't changed in the meantime.
+// Otherwise the transfer is cancelled and ErrTransferNotFound returned.
+func (s *EventStore) RespondTransfer(ctx context.Context, eventID, userID int64, accept bool) (*EventTransfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	transfer := &EventTransfer{}
+	var stale bool
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the event before the transfer, in the same order as
+		// ProposeTransfer and Join, so they can't deadlock each other
+		var id int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&id)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrTransferNotFound
+			default:
+				return err
+			}
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			SELECT id, event_id, from_user_id, to_user_id, created_at
+			FROM event_transfers
+			WHERE event_id = $1 AND to_user_id = $2 AND status = 'pending'
+			FOR UPDATE`, eventID, userID).Scan(
+			&transfer.ID,
+			&transfer.EventID,
+			&transfer.FromUserID,
+			&transfer.ToUserID,
+			&transfer.CreatedAt,
+		)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrTransferNotFound
+			default:
+				return err
+			}
+		}
+
+		transfer.Status = TransferDeclined
+		if accept {
+			res, err := tx.ExecContext(ctx, `
+				UPDATE events SET event_owner = $1, updated_at = NOW()
+				WHERE id = $2 AND event_owner = $3
+				AND EXISTS (SELECT 1 FROM event_participants WHERE event_id = $2 AND user_id = $1)`,
+				transfer.ToUserID, eventID, transfer.FromUserID)
+			if err != nil {
+				return err
+			}
+			if affected, _ := res.RowsAffected(); affected == 0 {
+				stale = true
+				transfer.Status = TransferCancelled
+			} else {
+				transfer.Status = TransferAccepted
+				// Owners have every right already
+				if err := removeOrganizer(ctx, tx, eventID, transfer.ToUserID); err != nil {
+					return err
+				}
+			}
+		}
+
+		now := time.Now()
+		transfer.RespondedAt = &now
+		_, err = tx.ExecContext(ctx, `UPDATE event_transfers SET status = $1, responded_at = $2 WHERE id = $3`,
+			transfer.Status, now, transfer.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		return nil, ErrTransferNotFound
+	}
+
+	return transfer, nil
+}
+
+// CancelTransfer withdraws the pending transfer of the event.
+func (s *EventStore) CancelTransfer(ctx context.Context, eventID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE event_transfers SET status = 'cancelled', responded_at = NOW()
+		WHERE event_id = $1 AND status = 'pending'`, eventID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return ErrTransferNotFound
+	}
+	return nil
+}
+
+// cancelTransfer cancels the pending transfer of the event, if there is one.
+func cancelTransfer(ctx context.Context, tx *sql.Tx, eventID int64) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE event_transfers SET status = 'cancelled', responded_at = NOW()
+		WHERE event_id = $1 AND status = 'pending'`, eventID)
+	return err
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test ProposeTransfer replaces the pending offer with a new one
+func TestEventStore_ProposeTransfer(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectTransferLock(mock, 2)
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants WHERE event_id = \$1 AND user_id = \$2\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`UPDATE event_transfers SET status = 'cancelled'`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO event_transfers \(event_id, from_user_id, to_user_id\)`).
+		WithArgs(int64(1), int64(2), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	mock.ExpectCommit()
+
+	transfer := &EventTransfer{EventID: 1, FromUserID: 2, ToUserID: 3}
+	err := store.ProposeTransfer(context.Background(), transfer)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), transfer.ID)
+	assert.Equal(t, TransferPending, transfer.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test ProposeTransfer to a user that hasn't joined the event
+func TestEventStore_ProposeTransfer_NotJoined(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectTransferLock(mock, 2)
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	err := store.ProposeTransfer(context.Background(), &EventTransfer{EventID: 1, FromUserID: 2, ToUserID: 3})
+	assert.Equal(t, ErrNotJoined, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test ProposeTransfer from an owner who just handed the event over
+func TestEventStore_ProposeTransfer_NotOwner(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	expectTransferLock(mock, 3)
+	mock.ExpectRollback()
+
+	err := store.ProposeTransfer(context.Background(), &EventTransfer{EventID: 1, FromUserID: 2, ToUserID: 3})
+	assert.Equal(t, ErrForbidden, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectTransferLock(mock sqlmock.Sqlmock, owner int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"event_owner"}).AddRow(owner))
+}
+
+// Test RespondTransfer hands the event over on acceptance
+func TestEventStore_RespondTransfer_Accept(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT id, event_id, from_user_id, to_user_id, created_at FROM event_transfers .* FOR UPDATE`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "from_user_id", "to_user_id", "created_at"}).
+			AddRow(7, 1, 2, 3, time.Now()))
+	mock.ExpectExec(`UPDATE events SET event_owner = \$1`).
+		WithArgs(int64(3), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM event_organizers`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE event_transfers SET status = \$1, responded_at = \$2 WHERE id = \$3`).
+		WithArgs(TransferAccepted, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	transfer, err := store.RespondTransfer(context.Background(), 1, 3, true)
+	assert.NoError(t, err)
+	assert.Equal(t, TransferAccepted, transfer.Status)
+	assert.Equal(t, int64(2), transfer.FromUserID)
+	assert.NotNil(t, transfer.RespondedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RespondTransfer cancels the offer when the owner changed or the
+// recipient left in the meantime
+func TestEventStore_RespondTransfer_Stale(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT id, event_id, from_user_id, to_user_id, created_at FROM event_transfers`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "from_user_id", "to_user_id", "created_at"}).
+			AddRow(7, 1, 2, 3, time.Now()))
+	mock.ExpectExec(`UPDATE events SET event_owner = \$1`).
+		WithArgs(int64(3), int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE event_transfers SET status = \$1`).
+		WithArgs(TransferCancelled, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err := store.RespondTransfer(context.Background(), 1, 3, true)
+	assert.Equal(t, ErrTransferNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test RespondTransfer leaves the owner alone on decline
+func TestEventStore_RespondTransfer_Decline(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT id, event_id, from_user_id, to_user_id, created_at FROM event_transfers`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id", "from_user_id", "to_user_id", "created_at"}).
+			AddRow(7, 1, 2, 3, time.Now()))
+	mock.ExpectExec(`UPDATE event_transfers SET status = \$1`).
+		WithArgs(TransferDeclined, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	transfer, err := store.RespondTransfer(context.Background(), 1, 3, false)
+	assert.NoError(t, err)
+	assert.Equal(t, TransferDeclined, transfer.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test CancelTransfer without a pending offer
+func TestEventStore_CancelTransfer_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectExec(`UPDATE event_transfers SET status = 'cancelled'`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := store.CancelTransfer(context.Background(), 1)
+	assert.Equal(t, ErrTransferNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
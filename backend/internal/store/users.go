@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -13,16 +14,53 @@ var (
 	ErrDuplicateEmail    = errors.New("a user with that email already exists")
 	ErrDuplicateUsername = errors.New("a user with that username already exists")
 	ErrEmailDoesNotExist = errors.New("a user with that email does not exist")
+	ErrInvalidInvitation = errors.New("verification token is invalid or has expired")
+	ErrInvalidRole       = errors.New("invalid role")
 )
 
+// Roles, from least to most privileged. Each role can do everything the ones
+// before it can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
 type User struct {
-	ID        int64    `json:"id"`
-	Email     string   `json:"email"`
-	Password  password `json:"-"` //makes sure we don't send password in responses
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
-	GoogleID  string   `json:"google_id"`
-	Name      string   `json:"name"`
+	ID           int64    `json:"id"`
+	Email        string   `json:"email"`
+	Password     password `json:"-"` //makes sure we don't send password in responses
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+	GoogleID     string   `json:"google_id"`
+	Name         string   `json:"name"`
+	TokenVersion int      `json:"-"` // bumped to revoke every JWT issued before
+	IsActive     bool     `json:"is_active"`
+	Role         string   `json:"role"`
+}
+
+// HasRole reports whether the user has the role or a more privileged one.
+func (u *User) HasRole(role string) bool {
+	return roleRanks[u.Role] >= roleRanks[role] && roleRanks[role] > 0
+}
+
+// UserPage is one page of users, newest first.
+type UserPage struct {
+	Users      []*User
+	NextCursor string
+	Total      *int
 }
 
 type password struct {
@@ -55,20 +93,76 @@ type UserStore struct {
 }
 
 func (s *UserStore) Create(ctx context.Context, user *User) error {
-	query := `
-		INSERT INTO users (email, password) VALUES 
-    ($1, $2)
-    RETURNING id, created_at
-	`
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
 
+	return s.create(ctx, s.db.QueryRowContext, user)
+}
+
+// CreateAndInvite creates an inactive user together with the invitation that
+// activates it, so a user never exists without a way to verify the account.
+func (s *UserStore) CreateAndInvite(ctx context.Context, user *User, tokenHash string, invitationExp time.Duration) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
+	user.IsActive = false
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		if err := s.create(ctx, tx.QueryRowContext, user); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO user_invitations (token, user_id, expiry) VALUES ($1, $2, $3)`
+		_, err := tx.ExecContext(ctx, query, tokenHash, user.ID, time.Now().Add(invitationExp))
+		return err
+	})
+}
+
+// Reinvite replaces the invitations of an inactive user with a new one, so
+// only the latest verification link works. It returns ErrNotFound if the user
+// doesn't exist or is already active.
+func (s *UserStore) Reinvite(ctx context.Context, userID int64, tokenHash string, invitationExp time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		var active bool
+		err := tx.QueryRowContext(ctx, `SELECT is_active FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&active)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		if active {
+			return ErrNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_invitations WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO user_invitations (token, user_id, expiry) VALUES ($1, $2, $3)`
+		_, err = tx.ExecContext(ctx, query, tokenHash, userID, time.Now().Add(invitationExp))
+		return err
+	})
+}
+
+func (s *UserStore) create(ctx context.Context, queryRow func(context.Context, string, ...any) *sql.Row, user *User) error {
+	query := `
+		INSERT INTO users (email, password, is_active) VALUES 
+    ($1, $2, $3)
+    RETURNING id, created_at
+	`
+
+	err := queryRow(
 		ctx,
 		query,
 		user.Email,
 		user.Password.hash,
+		user.IsActive,
 	).Scan(
 		&user.ID,
 		&user.CreatedAt,
@@ -81,12 +175,68 @@ func (s *UserStore) Create(ctx context.Context, user *User) error {
 			return errors.New(err.Error())
 		}
 	}
+
+	// New users always start with the column default
+	user.Role = RoleUser
 	return nil
 }
 
+// Activate marks the user owning the invitation token as active and removes
+// all of its invitations.
+func (s *UserStore) Activate(ctx context.Context, tokenHash string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	user := &User{}
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT u.id, u.email, u.created_at, u.token_version, u.role
+			FROM users u
+			JOIN user_invitations ui ON u.id = ui.user_id
+			WHERE ui.token = $1 AND ui.expiry > $2`
+
+		err := tx.QueryRowContext(ctx, query, tokenHash, time.Now()).Scan(
+			&user.ID,
+			&user.Email,
+			&user.CreatedAt,
+			&user.TokenVersion,
+			&user.Role,
+		)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrInvalidInvitation
+			default:
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active = TRUE, updated_at = NOW() WHERE id = $1`, user.ID); err != nil {
+			return err
+		}
+		user.IsActive = true
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM user_invitations WHERE user_id = $1`, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *UserStore) Delete(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	return err
+}
+
 func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 	query := `
-		SELECT users.id, email, password, created_at
+		SELECT users.id, email, password, created_at, token_version, is_active, role
 		FROM users
 		WHERE users.id = $1
 	`
@@ -104,6 +254,9 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.CreatedAt,
+		&user.TokenVersion,
+		&user.IsActive,
+		&user.Role,
 	)
 	if err != nil {
 		switch err {
@@ -119,7 +272,7 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*User, error) {
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, email, password, created_at, COALESCE(google_id, '') FROM users
+		SELECT id, email, password, created_at, COALESCE(google_id, ''), token_version, is_active, role FROM users
 		WHERE email = $1
 	`
 
@@ -133,6 +286,9 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.Password.hash,
 		&user.CreatedAt,
 		&user.GoogleID,
+		&user.TokenVersion,
+		&user.IsActive,
+		&user.Role,
 	)
 	if err != nil {
 		switch err {
@@ -163,12 +319,12 @@ func (s *UserStore) CreateOrUpdateGoogleUser(ctx context.Context, googleID, emai
 		// Update existing user with Google ID
 		query := `
 			UPDATE users 
-			SET google_id = $1, updated_at = NOW()
+			SET google_id = $1, is_active = TRUE, updated_at = NOW()
 			WHERE id = $2
-			RETURNING id, email, name, google_id, created_at, updated_at`
+			RETURNING id, email, name, google_id, created_at, updated_at, token_version, is_active, role`
 
 		err = s.db.QueryRowContext(ctx, query, googleID, user.ID).Scan(
-			&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt,
+			&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt, &user.TokenVersion, &user.IsActive, &user.Role,
 		)
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to update user with Google ID: %v", err)
@@ -180,11 +336,11 @@ func (s *UserStore) CreateOrUpdateGoogleUser(ctx context.Context, googleID, emai
 	query := `
 		INSERT INTO users (email, name, google_id)
 		VALUES ($1, $2, $3)
-		RETURNING id, email, name, google_id, created_at, updated_at`
+		RETURNING id, email, name, google_id, created_at, updated_at, token_version, is_active, role`
 
 	user = &User{}
 	err = s.db.QueryRowContext(ctx, query, email, name, googleID).Scan(
-		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt, &user.TokenVersion, &user.IsActive, &user.Role,
 	)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to create user: %v", err)
@@ -196,13 +352,13 @@ func (s *UserStore) CreateOrUpdateGoogleUser(ctx context.Context, googleID, emai
 // GetByGoogleID retrieves a user by their Google ID
 func (s *UserStore) GetByGoogleID(ctx context.Context, googleID string) (*User, error) {
 	query := `
-		SELECT id, email, name, google_id, created_at, updated_at
+		SELECT id, email, name, google_id, created_at, updated_at, token_version, is_active, role
 		FROM users
 		WHERE google_id = $1`
 
 	var user User
 	err := s.db.QueryRowContext(ctx, query, googleID).Scan(
-		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.GoogleID, &user.CreatedAt, &user.UpdatedAt, &user.TokenVersion, &user.IsActive, &user.Role,
 	)
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -213,3 +369,111 @@ func (s *UserStore) GetByGoogleID(ctx context.Context, googleID string) (*User,
 
 	return &user, nil
 }
+
+// List returns one page of every user, newest first.
+func (s *UserStore) List(ctx context.Context, pageQuery PageQuery) (*UserPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	page := &UserPage{Users: []*User{}}
+
+	if pageQuery.IncludeTotal {
+		var total int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	var before int64
+	if pageQuery.Cursor != "" {
+		var err error
+		before, err = decodeIDCursor(pageQuery.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := pageQuery.limit()
+	query := `
+		SELECT id, email, COALESCE(name, ''), created_at, is_active, role
+		FROM users
+		WHERE $1 = 0 OR id < $1
+		ORDER BY id DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &User{}
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.IsActive, &user.Role); err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > limit {
+		page.Users = page.Users[:limit]
+		page.NextCursor = encodeIDCursor(page.Users[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// Deactivate disables the account and signs the user out everywhere, by
+// revoking their access and refresh tokens.
+func (s *UserStore) Deactivate(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE users
+			SET is_active = FALSE, token_version = token_version + 1, updated_at = NOW()
+			WHERE id = $1`, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+
+		return revokeUserRefreshTokens(ctx, tx, userID)
+	})
+}
+
+// SetRole changes the user's role. Access tokens carry the role, so the ones
+// already issued are revoked; refresh tokens keep working and pick up the
+// new role.
+func (s *UserStore) SetRole(ctx context.Context, userID int64, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET role = $2, token_version = token_version + 1, updated_at = NOW()
+		WHERE id = $1`, userID, role)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
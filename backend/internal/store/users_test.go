@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -26,9 +27,9 @@ func TestUserStore_Create(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Mock DB response
-	query := `INSERT INTO users \(email, password\) VALUES \(\$1, \$2\) RETURNING id, created_at`
+	query := `INSERT INTO users \(email, password, is_active\) VALUES \(\$1, \$2, \$3\) RETURNING id, created_at`
 	mock.ExpectQuery(query).
-		WithArgs(user.Email, user.Password.hash).
+		WithArgs(user.Email, user.Password.hash, false).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
 			AddRow(1, "2025-03-01"))
 
@@ -54,8 +55,8 @@ func TestUserStore_Create_DuplicateEmail(t *testing.T) {
 	err := user.Password.Set("securepassword")
 	assert.NoError(t, err)
 
-	mock.ExpectQuery(`INSERT INTO users \(email, password\) VALUES \(\$1, \$2\) RETURNING id, created_at`).
-		WithArgs(user.Email, user.Password.hash).
+	mock.ExpectQuery(`INSERT INTO users \(email, password, is_active\) VALUES \(\$1, \$2, \$3\) RETURNING id, created_at`).
+		WithArgs(user.Email, user.Password.hash, false).
 		WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "users_email_key"`))
 
 	err = store.Create(context.Background(), user)
@@ -70,11 +71,11 @@ func TestUserStore_GetByID(t *testing.T) {
 	store := &UserStore{db: db}
 	userID := int64(1)
 
-	query := `SELECT users.id, email, password, created_at FROM users WHERE users.id = \$1`
+	query := `SELECT users.id, email, password, created_at, token_version, is_active, role FROM users WHERE users.id = \$1`
 	mock.ExpectQuery(query).
 		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at"}).
-			AddRow(1, "test@example.com", []byte("hashedpassword"), "2025-03-01"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at", "token_version", "is_active", "role"}).
+			AddRow(1, "test@example.com", []byte("hashedpassword"), "2025-03-01", 0, true, RoleModerator))
 
 	user, err := store.GetByID(context.Background(), userID)
 	assert.NoError(t, err)
@@ -82,6 +83,7 @@ func TestUserStore_GetByID(t *testing.T) {
 	assert.Equal(t, int64(1), user.ID)
 	assert.Equal(t, "test@example.com", user.Email)
 	assert.Equal(t, "2025-03-01", user.CreatedAt)
+	assert.Equal(t, RoleModerator, user.Role)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -94,7 +96,7 @@ func TestUserStore_GetByID_NotFound(t *testing.T) {
 	store := &UserStore{db: db}
 	userID := int64(99)
 
-	mock.ExpectQuery(`SELECT users.id, email, password, created_at FROM users WHERE users.id = \$1`).
+	mock.ExpectQuery(`SELECT users.id, email, password, created_at, token_version, is_active, role FROM users WHERE users.id = \$1`).
 		WithArgs(userID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -111,11 +113,11 @@ func TestUserStore_GetByEmail(t *testing.T) {
 	store := &UserStore{db: db}
 	email := "test@example.com"
 
-	query := `SELECT id, email, password, created_at, COALESCE\(google_id, ''\) FROM users WHERE email = \$1`
+	query := `SELECT id, email, password, created_at, COALESCE\(google_id, ''\), token_version, is_active, role FROM users WHERE email = \$1`
 	mock.ExpectQuery(query).
 		WithArgs(email).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at", "google_id"}).
-			AddRow(1, email, []byte("hashedpassword"), "2025-03-01", ""))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "created_at", "google_id", "token_version", "is_active", "role"}).
+			AddRow(1, email, []byte("hashedpassword"), "2025-03-01", "", 0, true, RoleUser))
 
 	user, err := store.GetByEmail(context.Background(), email)
 	assert.NoError(t, err)
@@ -136,7 +138,7 @@ func TestUserStore_GetByEmail_NotFound(t *testing.T) {
 	store := &UserStore{db: db}
 	email := "notfound@example.com"
 
-	mock.ExpectQuery(`SELECT id, email, password, created_at, COALESCE\(google_id, ''\) FROM users WHERE email = \$1`).
+	mock.ExpectQuery(`SELECT id, email, password, created_at, COALESCE\(google_id, ''\), token_version, is_active, role FROM users WHERE email = \$1`).
 		WithArgs(email).
 		WillReturnError(sql.ErrNoRows)
 
@@ -144,3 +146,208 @@ func TestUserStore_GetByEmail_NotFound(t *testing.T) {
 	assert.Nil(t, user)
 	assert.ErrorIs(t, err, ErrNotFound)
 }
+
+// Test Create User with Invitation
+func TestUserStore_CreateAndInvite(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+	user := &User{
+		Email:    "test@example.com",
+		IsActive: true, // should be ignored
+	}
+	assert.NoError(t, user.Password.Set("securepassword"))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users \(email, password, is_active\)`).
+		WithArgs(user.Email, user.Password.hash, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, "2025-03-01"))
+	mock.ExpectExec(`INSERT INTO user_invitations \(token, user_id, expiry\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("hash", int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.CreateAndInvite(context.Background(), user, "hash", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.False(t, user.IsActive)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Reinvite replaces the invitations of an inactive user
+func TestUserStore_Reinvite(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT is_active FROM users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(false))
+	mock.ExpectExec(`DELETE FROM user_invitations WHERE user_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_invitations \(token, user_id, expiry\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("hash", int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.Reinvite(context.Background(), 1, "hash", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Reinvite leaves active users alone
+func TestUserStore_Reinvite_Active(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT is_active FROM users WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err := store.Reinvite(context.Background(), 1, "hash", time.Hour)
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Activate User
+func TestUserStore_Activate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT u.id, u.email, u.created_at, u.token_version, u.role FROM users u JOIN user_invitations ui`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "created_at", "token_version", "role"}).
+			AddRow(1, "test@example.com", "2025-03-01", 0, RoleUser))
+	mock.ExpectExec(`UPDATE users SET is_active = TRUE`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_invitations WHERE user_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user, err := store.Activate(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.True(t, user.IsActive)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Activate User - Invalid or Expired Token
+func TestUserStore_Activate_InvalidToken(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT u.id, u.email, u.created_at, u.token_version, u.role FROM users u`).
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	user, err := store.Activate(context.Background(), "hash")
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, ErrInvalidInvitation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test List Users pages newest first
+func TestUserStore_List(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectQuery(`SELECT id, email, COALESCE\(name, ''\), created_at, is_active, role FROM users WHERE \$1 = 0 OR id < \$1 ORDER BY id DESC LIMIT \$2`).
+		WithArgs(int64(0), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "created_at", "is_active", "role"}).
+			AddRow(9, "c@example.com", "", "2025-03-03", true, RoleAdmin).
+			AddRow(8, "b@example.com", "", "2025-03-02", false, RoleUser).
+			AddRow(7, "a@example.com", "", "2025-03-01", true, RoleUser))
+
+	page, err := store.List(context.Background(), PageQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	assert.Equal(t, RoleAdmin, page.Users[0].Role)
+	assert.False(t, page.Users[1].IsActive)
+	assert.Equal(t, encodeIDCursor(8), page.NextCursor)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Deactivate User revokes the user's tokens
+func TestUserStore_Deactivate(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET is_active = FALSE, token_version = token_version \+ 1`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = \$2 WHERE user_id = \$1`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := store.Deactivate(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Deactivate User - Not Found
+func TestUserStore_Deactivate_NotFound(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET is_active = FALSE`).
+		WithArgs(int64(99)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := store.Deactivate(context.Background(), 99)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Set Role revokes the access tokens carrying the old role
+func TestUserStore_SetRole(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &UserStore{db: db}
+
+	mock.ExpectExec(`UPDATE users SET role = \$2, token_version = token_version \+ 1`).
+		WithArgs(int64(1), RoleModerator).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, store.SetRole(context.Background(), 1, RoleModerator))
+	assert.ErrorIs(t, store.SetRole(context.Background(), 1, "superuser"), ErrInvalidRole)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUser_HasRole(t *testing.T) {
+	moderator := &User{Role: RoleModerator}
+
+	assert.True(t, moderator.HasRole(RoleUser))
+	assert.True(t, moderator.HasRole(RoleModerator))
+	assert.False(t, moderator.HasRole(RoleAdmin))
+	assert.False(t, (&User{}).HasRole(RoleUser), "Users without a role have none of the privileges")
+	assert.False(t, moderator.HasRole("superuser"))
+}
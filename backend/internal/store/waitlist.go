@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAlreadyWaitlisted = errors.New("user is already on the waitlist of this event")
+	ErrNotWaitlisted     = errors.New("user is not on the waitlist of this event")
+)
+
+type WaitlistEntry struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	UserID    int64     `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Position  int       `json:"position"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// JoinWaitlist queues the user at the end of the event's waitlist and returns
+// the entry with its position. Like Join, it uses up the invite for unlisted
+// and private events.
+//
+// A spot may have opened since Join found the event full. It goes to the
+// users already waiting first. If one is still left after them, the user takes
+// it instead of queueing, and JoinWaitlist returns a nil entry.
+func (s *EventStore) JoinWaitlist(ctx context.Context, eventID, userID int64, inviteHash string) (*WaitlistEntry, error) {
+	entry := &WaitlistEntry{EventID: eventID, UserID: userID}
+
+	err := withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the event so concurrent joins can't be handed the same position
+		// or the same spot
+		var maxPlayers int
+		var owner int64
+		var status, visibility string
+		var requiresApproval bool
+		err := tx.QueryRowContext(ctx, `SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&maxPlayers, &status, &visibility, &requiresApproval, &owner)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+		if status != EventStatusScheduled {
+			return ErrEventNotScheduled
+		}
+
+		var joined, waitlisted bool
+		var count int
+		err = tx.QueryRowContext(ctx, `
+			SELECT
+				EXISTS(SELECT 1 FROM event_participants WHERE event_id = $1 AND user_id = $2),
+				EXISTS(SELECT 1 FROM event_waitlist WHERE event_id = $1 AND user_id = $2),
+				(SELECT COUNT(*) FROM event_participants WHERE event_id = $1)`,
+			eventID, userID).Scan(&joined, &waitlisted, &count)
+		if err != nil {
+			return err
+		}
+		if joined {
+			return ErrAlreadyJoined
+		}
+		if waitlisted {
+			return ErrAlreadyWaitlisted
+		}
+		if userID != owner {
+			if err := useInvite(ctx, tx, eventID, visibility, inviteHash); err != nil {
+				return err
+			}
+			if requiresApproval {
+				return ErrApprovalRequired
+			}
+		}
+
+		if count < maxPlayers {
+			// Whoever is already waiting gets the free spots first
+			promoted, err := promoteFromWaitlist(ctx, tx, eventID)
+			if err != nil {
+				return err
+			}
+			count += len(promoted)
+			if count < maxPlayers {
+				entry = nil
+				_, err = tx.ExecContext(ctx, `INSERT INTO event_participants (event_id, user_id) VALUES ($1, $2)`, eventID, userID)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = tx.ExecContext(ctx, `
+				UPDATE events
+				SET is_full = (
+					SELECT COUNT(*) >= max_players
+					FROM event_participants
+					WHERE event_id = $1
+				)
+				WHERE id = $1`, eventID)
+			if err != nil || entry == nil {
+				return err
+			}
+		}
+
+		query := `
+			INSERT INTO event_waitlist (event_id, user_id, position)
+			VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM event_waitlist WHERE event_id = $1))
+			RETURNING id, position, joined_at`
+
+		return tx.QueryRowContext(ctx, query, eventID, userID).Scan(
+			&entry.ID,
+			&entry.Position,
+			&entry.JoinedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// LeaveWaitlist removes the user from the event's waitlist and moves everyone
+// behind them up one position.
+func (s *EventStore) LeaveWaitlist(ctx context.Context, eventID, userID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		// Lock the event like JoinWaitlist so positions are renumbered one
+		// change at a time
+		var id int64
+		err := tx.QueryRowContext(ctx, `SELECT id FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&id)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrEventNotFound
+			default:
+				return err
+			}
+		}
+
+		var position int
+		err = tx.QueryRowContext(ctx, `
+			DELETE FROM event_waitlist
+			WHERE event_id = $1 AND user_id = $2
+			RETURNING position`, eventID, userID).Scan(&position)
+		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				return ErrNotWaitlisted
+			default:
+				return err
+			}
+		}
+
+		return shiftWaitlist(ctx, tx, eventID, position)
+	})
+}
+
+// GetWaitlist returns the event's waitlist ordered by position.
+func (s *EventStore) GetWaitlist(ctx context.Context, eventID int64) ([]WaitlistEntry, error) {
+	query := `
+		SELECT ew.id, ew.event_id, ew.user_id, p.first_name, p.last_name, ew.position, ew.joined_at
+		FROM event_waitlist ew
+		JOIN users u ON ew.user_id = u.id
+		JOIN profile p ON u.email = p.email
+		WHERE ew.event_id = $1
+		ORDER BY ew.position ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	waitlist := []WaitlistEntry{}
+	for rows.Next() {
+		var w WaitlistEntry
+		err := rows.Scan(&w.ID, &w.EventID, &w.UserID, &w.FirstName, &w.LastName, &w.Position, &w.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		waitlist = append(waitlist, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return waitlist, nil
+}
+
+// promoteFromWaitlist moves users from the head of the waitlist into the
+// event while it has free spots and returns the ids of the promoted users.
+func promoteFromWaitlist(ctx context.Context, tx *sql.Tx, eventID int64) ([]int64, error) {
+	var promoted []int64
+	for {
+		var hasRoom bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(ep.id) < e.max_players
+			FROM events e
+			LEFT JOIN event_participants ep ON e.id = ep.event_id
+			WHERE e.id = $1
+			GROUP BY e.id`, eventID).Scan(&hasRoom)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRoom {
+			return promoted, nil
+		}
+
+		var userID int64
+		err = tx.QueryRowContext(ctx, `
+			DELETE FROM event_waitlist
+			WHERE id = (
+				SELECT id FROM event_waitlist
+				WHERE event_id = $1
+				ORDER BY position ASC
+				LIMIT 1
+				FOR UPDATE
+			)
+			RETURNING user_id`, eventID).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return promoted, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO event_participants (event_id, user_id) VALUES ($1, $2)`, eventID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := shiftWaitlist(ctx, tx, eventID, 1); err != nil {
+			return nil, err
+		}
+
+		promoted = append(promoted, userID)
+	}
+}
+
+// shiftWaitlist closes the gap left by the entry removed at position.
+func shiftWaitlist(ctx context.Context, tx *sql.Tx, eventID int64, position int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE event_waitlist
+		SET position = position - 1
+		WHERE event_id = $1 AND position > $2`, eventID, position)
+	return err
+}
@@ -0,0 +1,276 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test JoinWaitlist puts the user at the end of the line
+func TestEventStore_JoinWaitlist(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(2, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "count"}).AddRow(false, false, 2))
+	mock.ExpectQuery(`INSERT INTO event_waitlist \(event_id, user_id, position\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "position", "joined_at"}).AddRow(5, 3, time.Now()))
+	mock.ExpectCommit()
+
+	entry, err := store.JoinWaitlist(context.Background(), 1, 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), entry.ID)
+	assert.Equal(t, 3, entry.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test JoinWaitlist lets the user in when a spot opened since Join found the
+// event full
+func TestEventStore_JoinWaitlist_SpotOpened(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(2, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "count"}).AddRow(false, false, 1))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(1)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE events\s+SET is_full`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	entry, err := store.JoinWaitlist(context.Background(), 1, 2, "")
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test JoinWaitlist hands a spot that opened to the head of the waitlist and
+// queues the user behind them
+func TestEventStore_JoinWaitlist_SpotOpenedQueueFirst(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(2, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "count"}).AddRow(false, false, 1))
+
+	// User 3 is first in line and takes the only spot
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE event_waitlist SET position = position - 1`).
+		WithArgs(int64(1), 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(false))
+
+	mock.ExpectExec(`UPDATE events\s+SET is_full`).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO event_waitlist \(event_id, user_id, position\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "position", "joined_at"}).AddRow(6, 1, time.Now()))
+	mock.ExpectCommit()
+
+	entry, err := store.JoinWaitlist(context.Background(), 1, 2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, entry.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test JoinWaitlist rejects users already waiting
+func TestEventStore_JoinWaitlist_AlreadyWaitlisted(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players, status, visibility, requires_approval, event_owner FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players", "status", "visibility", "requires_approval", "event_owner"}).AddRow(2, EventStatusScheduled, VisibilityPublic, false, 1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"joined", "waitlisted", "count"}).AddRow(false, true, 2))
+	mock.ExpectRollback()
+
+	_, err := store.JoinWaitlist(context.Background(), 1, 2, "")
+	assert.ErrorIs(t, err, ErrAlreadyWaitlisted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test LeaveWaitlist moves everyone behind the user up
+func TestEventStore_LeaveWaitlist(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE event_id = \$1 AND user_id = \$2 RETURNING position`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(2))
+	mock.ExpectExec(`UPDATE event_waitlist SET position = position - 1 WHERE event_id = \$1 AND position > \$2`).
+		WithArgs(int64(1), 2).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err := store.LeaveWaitlist(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test LeaveWaitlist for a user that isn't waiting
+func TestEventStore_LeaveWaitlist_NotWaitlisted(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`DELETE FROM event_waitlist`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err := store.LeaveWaitlist(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, ErrNotWaitlisted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Leave promotes the first waitlisted user in the same transaction
+func TestEventStore_Leave_PromotesWaitlist(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM event_participants WHERE event_id = \$1 AND user_id = \$2\)`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec(`DELETE FROM event_participants WHERE event_id = \$1 AND user_id = \$2`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM event_organizers WHERE event_id = \$1 AND user_id = \$2`).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// One spot is free and user 3 is first in line
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE event_waitlist SET position = position - 1`).
+		WithArgs(int64(1), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(false))
+
+	mock.ExpectExec(`UPDATE events SET is_full`).
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := store.Leave(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test Update promotes waitlisted users when max_players is raised
+func TestEventStore_Update_PromotesWaitlist(t *testing.T) {
+	db, mock := setupMockDB(t)
+	defer db.Close()
+
+	store := &EventStore{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT max_players FROM events WHERE id = \$1 FOR UPDATE`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"max_players"}).AddRow(2))
+	mock.ExpectQuery(`UPDATE events SET sport = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+
+	// The new spot goes to user 3, first in line
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(true))
+	mock.ExpectQuery(`DELETE FROM event_waitlist WHERE id = \(`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO event_participants \(event_id, user_id\) VALUES \(\$1, \$2\)`).
+		WithArgs(int64(1), int64(3)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE event_waitlist SET position = position - 1`).
+		WithArgs(int64(1), 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT COUNT\(ep.id\) < e.max_players`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"has_room"}).AddRow(false))
+
+	mock.ExpectQuery(`UPDATE events SET is_full`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"is_full"}).AddRow(true))
+	mock.ExpectCommit()
+
+	event := &Event{ID: 1, MaxPlayers: 3, Participants: []EventParticipant{{UserID: 1}, {UserID: 2}}}
+	err := store.Update(context.Background(), event)
+	assert.NoError(t, err)
+	assert.True(t, event.IsFull)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker fans messages out to the hubs of every API instance, including the
+// one that published them, so clients connected to different instances
+// share the same chat rooms.
+type Broker interface {
+	// Publish sends the payload to every subscriber.
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe returns the payloads published from now on, until ctx is
+	// done.
+	Subscribe(ctx context.Context) (<-chan []byte, error)
+	Close() error
+}
+
+// subscriptionBuffer is how many payloads a subscriber may fall behind before
+// publishers wait for it.
+const subscriptionBuffer = 64
+
+// LocalBroker only reaches the hubs of this process. It is enough when a
+// single instance of the API runs.
+type LocalBroker struct {
+	mu   sync.Mutex
+	subs map[*localSubscription]bool
+}
+
+type localSubscription struct {
+	ch   chan []byte
+	done <-chan struct{}
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{
+		subs: make(map[*localSubscription]bool),
+	}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, payload []byte) error {
+	b.mu.Lock()
+	subs := make([]*localSubscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- payload:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *LocalBroker) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	sub := &localSubscription{
+		ch:   make(chan []byte, subscriptionBuffer),
+		done: ctx.Done(),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = true
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+	}()
+
+	return sub.ch, nil
+}
+
+func (b *LocalBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLocalBroker tests that every subscriber gets what is published until
+// its context is done
+func TestLocalBroker(t *testing.T) {
+	broker := NewLocalBroker()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	sub1, err := broker.Subscribe(ctx1)
+	assert.NoError(t, err)
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	sub2, err := broker.Subscribe(ctx2)
+	assert.NoError(t, err)
+
+	assert.NoError(t, broker.Publish(context.Background(), []byte("first")))
+	assert.Equal(t, []byte("first"), <-sub1)
+	assert.Equal(t, []byte("first"), <-sub2)
+
+	cancel2()
+	assert.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.subs) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, broker.Publish(context.Background(), []byte("second")))
+	assert.Equal(t, []byte("second"), <-sub1)
+	assert.Empty(t, sub2)
+}
+
+// TestHubsShareRooms tests that hubs sharing a broker, as API instances do,
+// deliver each other's chat messages
+func TestHubsShareRooms(t *testing.T) {
+	broker := NewLocalBroker()
+	messageStore := newMemoryMessageStore()
+
+	var hubs []*Hub
+	for i := 0; i < 2; i++ {
+		hub, err := NewHubWithBroker(messageStore, broker)
+		assert.NoError(t, err)
+		hubs = append(hubs, hub)
+	}
+
+	var conns []*websocket.Conn
+	for i, hub := range hubs {
+		go hub.Run()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Fatalf("Failed to upgrade connection: %v", err)
+			}
+			hub.HandleWebSocket(conn, 1, int64(i+1), "user")
+		}))
+		defer server.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to WebSocket server: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	// Give the hubs time to subscribe and register the connections
+	time.Sleep(10 * time.Millisecond)
+
+	err := conns[0].WriteJSON(Message{Content: "Hello from instance 1"})
+	assert.NoError(t, err)
+
+	for _, conn := range conns {
+		received := readEnvelope(t, conn, TypeMessage).Message
+		assert.Equal(t, "Hello from instance 1", received.Content)
+		assert.Equal(t, int64(1), received.UserID)
+	}
+}
+
+// TestPresenceHeartbeat tests that a hub starting after users joined learns
+// about them from the heartbeats of the others
+func TestPresenceHeartbeat(t *testing.T) {
+	broker := NewLocalBroker()
+	messageStore := newMemoryMessageStore()
+
+	early, err := NewHubWithBroker(messageStore, broker)
+	assert.NoError(t, err)
+	early.presenceInterval = 10 * time.Millisecond
+	go early.Run()
+	defer early.Shutdown(context.Background())
+
+	earlyServer, dialEarly := newChatServer(t, early)
+	defer earlyServer.Close()
+	alice := dialEarly("alice")
+	defer alice.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	// Alice's join was published before this hub subscribed
+	late, err := NewHubWithBroker(messageStore, broker)
+	assert.NoError(t, err)
+	go late.Run()
+	defer late.Shutdown(context.Background())
+
+	lateServer, dialLate := newChatServer(t, late)
+	defer lateServer.Close()
+	bob := dialLate("bob")
+	defer bob.Close()
+
+	assert.Empty(t, readEnvelope(t, bob, TypePresence).Users, "Alice joined before this hub started")
+	for {
+		join := readEnvelope(t, bob, TypePresenceJoin)
+		if join.User.UserID == 1 {
+			assert.Equal(t, "alice", join.User.Username)
+			break
+		}
+	}
+}
+
+// TestPresenceExpires tests that the users of a hub that stopped sending
+// heartbeats, as a crashed instance does, go offline
+func TestPresenceExpires(t *testing.T) {
+	broker := NewLocalBroker()
+	hub, err := NewHubWithBroker(newMemoryMessageStore(), broker)
+	assert.NoError(t, err)
+	hub.presenceInterval = 10 * time.Millisecond
+	hub.presenceTTL = 30 * time.Millisecond
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+	alice := dial("alice")
+	defer alice.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	// A single heartbeat from an instance that then crashed
+	heartbeat, err := json.Marshal(brokerMessage{From: "crashed", Presence: &presenceSnapshot{
+		EventID: 1,
+		Members: []presenceMember{{User: PresenceUser{UserID: 2, Username: "bob"}, Conns: 1}},
+	}})
+	assert.NoError(t, err)
+	assert.NoError(t, broker.Publish(context.Background(), heartbeat))
+
+	assert.Equal(t, int64(2), readEnvelope(t, alice, TypePresenceJoin).User.UserID)
+	assert.Equal(t, int64(2), readEnvelope(t, alice, TypePresenceLeave).User.UserID)
+
+	// Alice's own hub keeps vouching for Alice
+	bob := dial("bob")
+	defer bob.Close()
+	assert.Equal(t, []PresenceUser{{UserID: 1, Username: "alice"}}, readEnvelope(t, bob, TypePresence).Users)
+}
+
+// TestPostgresBroker_Publish tests that publishing sends a NOTIFY on the
+// shared channel
+func TestPostgresBroker_Publish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	broker := &PostgresBroker{db: db, channel: PostgresBrokerChannel}
+
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(PostgresBrokerChannel, `{"chat":{}}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, broker.Publish(context.Background(), []byte(`{"chat":{}}`)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresBroker_Publish_TooLarge tests that payloads Postgres would drop
+// are rejected instead
+func TestPostgresBroker_Publish_TooLarge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	broker := &PostgresBroker{db: db, channel: PostgresBrokerChannel}
+
+	err = broker.Publish(context.Background(), []byte(strings.Repeat("a", maxNotifyPayload)))
+	assert.Equal(t, ErrPayloadTooLarge, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestNewHubWithBroker_SubscribeFails tests that a hub isn't created when it
+// can't receive anything from the broker
+func TestNewHubWithBroker_SubscribeFails(t *testing.T) {
+	hub, err := NewHubWithBroker(newMemoryMessageStore(), &failingBroker{})
+	assert.Error(t, err)
+	assert.Nil(t, hub)
+}
+
+type failingBroker struct{ LocalBroker }
+
+func (*failingBroker) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	return nil, errors.New("connection refused")
+}
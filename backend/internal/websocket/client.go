@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is how long a single write to a client may take.
+	writeWait = 10 * time.Second
+
+	// closeWait is how long sending the close frame may take when the hub
+	// shuts down.
+	closeWait = time.Second
+
+	// pongWait is how long a client may go without answering a ping before
+	// its connection is considered dead.
+	pongWait = 60 * time.Second
+
+	// pingPeriod must be shorter than pongWait so pongs arrive in time.
+	pingPeriod = pongWait * 9 / 10
+
+	// maxMessageSize is the largest message a client may send.
+	maxMessageSize = 4096
+
+	// sendBufferSize is how many messages can be queued for a client before
+	// it is considered too slow and dropped.
+	sendBufferSize = 256
+)
+
+// Client is a connection to the chat of an event, or to the notifications of
+// its user when eventID is 0. Only its write pump writes to the connection;
+// the hub queues messages on send.
+type Client struct {
+	conn     *websocket.Conn
+	eventID  int64
+	userID   int64
+	username string
+	send     chan []byte
+
+	// closeMsg is the close frame sent once send is closed, set by the hub
+	// before closing it. Without one an empty close frame is sent.
+	closeMsg []byte
+
+	// While holding, the hub keeps what it delivers in held instead of
+	// queueing it, so nothing published while the history loads is sent
+	// before it or lost. Both are guarded by the hub's mu.
+	holding bool
+	held    [][]byte
+
+	// detached is set once the hub has closed send, guarded by its mu
+	detached bool
+}
+
+func newClient(conn *websocket.Conn, eventID, userID int64, username string) *Client {
+	return &Client{
+		conn:     conn,
+		eventID:  eventID,
+		userID:   userID,
+		username: username,
+		send:     make(chan []byte, sendBufferSize),
+	}
+}
+
+// queue adds the message to the client's buffer, or returns false if the
+// buffer is full.
+func (c *Client) queue(data []byte) bool {
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePump writes the queued messages to the connection and pings the
+// client until send is closed or a write fails.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub removed the client
+				msg := c.closeMsg
+				if msg == nil {
+					msg = []byte{}
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, msg)
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("error: %v", err)
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// keepAlive limits what the client may send and makes reads fail once it
+// stops answering pings.
+func (c *Client) keepAlive() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// PostgresBrokerChannel is the LISTEN/NOTIFY channel the hubs share.
+	PostgresBrokerChannel = "sportify_hub"
+
+	// listenerPingInterval is how often an idle listener checks that its
+	// connection is still alive.
+	listenerPingInterval = 90 * time.Second
+
+	// maxNotifyPayload is the size Postgres requires NOTIFY payloads to stay
+	// under.
+	maxNotifyPayload = 8000
+)
+
+var ErrPayloadTooLarge = errors.New("payload is too large for the broker")
+
+// PostgresBroker shares messages between the API instances through Postgres
+// LISTEN/NOTIFY, so no other service is needed to run several of them.
+// Payloads must stay under Postgres' 8000 byte limit, larger ones are
+// rejected. Messages sent while an instance is reconnecting are lost; chat
+// history stays in the database.
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+	channel  string
+
+	mu   sync.Mutex
+	subs map[chan []byte]bool
+}
+
+// NewPostgresBroker publishes through db and listens on a dedicated
+// connection opened with connStr.
+func NewPostgresBroker(db *sql.DB, connStr, channel string) (*PostgresBroker, error) {
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("broker listener error: %v", err)
+		}
+	})
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		listener: listener,
+		channel:  channel,
+		subs:     make(map[chan []byte]bool),
+	}
+	go b.listen()
+
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, payload []byte) error {
+	if len(payload) >= maxNotifyPayload {
+		return ErrPayloadTooLarge
+	}
+
+	_, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, string(payload))
+	return err
+}
+
+func (b *PostgresBroker) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriptionBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = true
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}()
+
+	return ch, nil
+}
+
+func (b *PostgresBroker) Close() error {
+	return b.listener.Close()
+}
+
+// listen hands every notification to the subscribers until the listener is
+// closed.
+func (b *PostgresBroker) listen() {
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established
+			if n == nil {
+				continue
+			}
+
+			b.mu.Lock()
+			for ch := range b.subs {
+				select {
+				case ch <- []byte(n.Extra):
+				default:
+					log.Printf("broker subscriber is full, dropping message")
+				}
+			}
+			b.mu.Unlock()
+
+		case <-time.After(listenerPingInterval):
+			go b.listener.Ping()
+		}
+	}
+}
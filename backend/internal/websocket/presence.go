@@ -0,0 +1,228 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+const (
+	// presenceInterval is how often a hub tells the others who is connected
+	// to it. Presence that went wrong, because a hub started late or missed
+	// a leave, is put right within one interval.
+	presenceInterval = 30 * time.Second
+
+	// presenceTTL is how long a member stays online without a hub vouching
+	// for them, so the users of a crashed instance go offline.
+	presenceTTL = 3 * presenceInterval
+
+	// presenceBatch is how many members go in one heartbeat, which keeps it
+	// under the payload limit of the Postgres broker.
+	presenceBatch = 50
+)
+
+// member is a user online in a room through one hub, possibly with several
+// connections. seen is when that hub last vouched for them.
+type member struct {
+	user  PresenceUser
+	conns int
+	seen  time.Time
+}
+
+// presenceSnapshot is a hub's heartbeat for one room: the members connected
+// to it and how many connections each has. Large rooms take several.
+type presenceSnapshot struct {
+	EventID int64            `json:"eventId"`
+	Members []presenceMember `json:"members"`
+}
+
+type presenceMember struct {
+	User  PresenceUser `json:"user"`
+	Conns int          `json:"conns"`
+}
+
+// newHubID returns a random ID telling the hubs sharing a broker apart.
+func newHubID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Only happens when the system has no source of randomness
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// track returns the member the hub from has for the user in the room, adding
+// one without connections if there is none, and marks it as just vouched for.
+func (h *Hub) track(from string, eventID int64, user PresenceUser, now time.Time) *member {
+	rooms, ok := h.peers[from]
+	if !ok {
+		rooms = make(map[int64]map[int64]*member)
+		h.peers[from] = rooms
+	}
+	room, ok := rooms[eventID]
+	if !ok {
+		room = make(map[int64]*member)
+		rooms[eventID] = room
+	}
+	m, ok := room[user.UserID]
+	if !ok {
+		m = &member{}
+		room[user.UserID] = m
+	}
+	m.user = user
+	m.seen = now
+	return m
+}
+
+// left records that a connection of the user to the room through the hub
+// from closed, and reports whether the user just went offline.
+func (h *Hub) left(from string, eventID, userID int64) bool {
+	m, ok := h.peers[from][eventID][userID]
+	if !ok {
+		return false
+	}
+	m.conns--
+	if m.conns > 0 {
+		return false
+	}
+
+	h.forget(from, eventID, userID)
+	return !h.isOnline(eventID, userID)
+}
+
+// forget removes the user from the room as seen through the hub from.
+func (h *Hub) forget(from string, eventID, userID int64) {
+	delete(h.peers[from][eventID], userID)
+	if len(h.peers[from][eventID]) == 0 {
+		delete(h.peers[from], eventID)
+	}
+	if len(h.peers[from]) == 0 {
+		delete(h.peers, from)
+	}
+}
+
+// isOnline reports whether the user is connected to the room through any hub.
+func (h *Hub) isOnline(eventID, userID int64) bool {
+	for _, rooms := range h.peers {
+		if _, ok := rooms[eventID][userID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// onlineUsers returns the users connected to the room through any hub.
+func (h *Hub) onlineUsers(eventID int64) []PresenceUser {
+	seen := make(map[int64]bool)
+	users := []PresenceUser{}
+	for _, rooms := range h.peers {
+		for userID, m := range rooms[eventID] {
+			if !seen[userID] {
+				seen[userID] = true
+				users = append(users, m.user)
+			}
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users
+}
+
+// receivePresence applies a heartbeat of the hub from. It replaces the
+// connection counts that join and leave envelopes added up, since those may
+// have been missed or arrived out of order.
+func (h *Hub) receivePresence(from string, snapshot *presenceSnapshot) {
+	now := time.Now()
+	for _, pm := range snapshot.Members {
+		if pm.Conns <= 0 {
+			continue
+		}
+		user := pm.User
+		online := h.isOnline(snapshot.EventID, user.UserID)
+		h.track(from, snapshot.EventID, user, now).conns = pm.Conns
+		if !online {
+			h.sendRoom(&Envelope{Version: ProtocolVersion, Type: TypePresenceJoin, EventID: snapshot.EventID, User: &user})
+		}
+	}
+}
+
+// expirePresence takes the members no hub has vouched for within the TTL
+// offline.
+func (h *Hub) expirePresence(now time.Time) {
+	for from, rooms := range h.peers {
+		for eventID, room := range rooms {
+			for userID, m := range room {
+				if now.Sub(m.seen) <= h.presenceTTL {
+					continue
+				}
+				user := m.user
+				h.forget(from, eventID, userID)
+				if !h.isOnline(eventID, userID) {
+					h.sendRoom(&Envelope{Version: ProtocolVersion, Type: TypePresenceLeave, EventID: eventID, User: &user})
+				}
+			}
+		}
+	}
+}
+
+// heartbeat publishes who is connected to this hub every presenceInterval
+// until quit is closed. It runs apart from Run, which receives the heartbeats
+// and must not wait on the broker for itself.
+func (h *Hub) heartbeat() {
+	if !h.enter() {
+		return
+	}
+	defer h.handlers.Done()
+
+	ticker := time.NewTicker(h.presenceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.quit:
+			return
+		case <-ticker.C:
+			for _, snapshot := range h.presenceSnapshots() {
+				h.publish(brokerMessage{From: h.id, Presence: snapshot})
+			}
+		}
+	}
+}
+
+// presenceSnapshots counts the connections of every user to every room on
+// this hub, in batches of at most presenceBatch members.
+func (h *Hub) presenceSnapshots() []*presenceSnapshot {
+	h.mu.Lock()
+	counts := make(map[int64]map[int64]*presenceMember)
+	for client := range h.clients {
+		if client.eventID == 0 {
+			continue
+		}
+		room, ok := counts[client.eventID]
+		if !ok {
+			room = make(map[int64]*presenceMember)
+			counts[client.eventID] = room
+		}
+		pm, ok := room[client.userID]
+		if !ok {
+			pm = &presenceMember{User: PresenceUser{UserID: client.userID, Username: client.username}}
+			room[client.userID] = pm
+		}
+		pm.Conns++
+	}
+	h.mu.Unlock()
+
+	var snapshots []*presenceSnapshot
+	for eventID, room := range counts {
+		snapshot := &presenceSnapshot{EventID: eventID}
+		for _, pm := range room {
+			if len(snapshot.Members) == presenceBatch {
+				snapshots = append(snapshots, snapshot)
+				snapshot = &presenceSnapshot{EventID: eventID}
+			}
+			snapshot.Members = append(snapshot.Members, *pm)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
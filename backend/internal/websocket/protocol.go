@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ProtocolVersion is sent in every envelope and bumped on incompatible
+// changes, so clients can tell whether they understand the server.
+const ProtocolVersion = 1
+
+// Envelope types. Clients send message, typing and read_receipt. The server
+// sends every type, but doesn't echo typing to the user typing. Moderation
+// types are only sent by the server, moderation goes through the REST API.
+const (
+	TypeMessage       = "message"
+	TypeTyping        = "typing"
+	TypePresence      = "presence"
+	TypePresenceJoin  = "presence_join"
+	TypePresenceLeave = "presence_leave"
+	TypeReadReceipt   = "read_receipt"
+	TypeError         = "error"
+	TypeHistory       = "history"
+
+	TypeMessageEdited  = "message_edited"
+	TypeMessageDeleted = "message_deleted"
+	TypeUserMuted      = "user_muted"
+	TypeUserUnmuted    = "user_unmuted"
+	TypeUserKicked     = "user_kicked"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	ErrUnknownType        = errors.New("unknown message type")
+	ErrEmptyMessage       = errors.New("message content is required")
+	ErrMessageTooLong     = errors.New("message content is too long")
+	ErrMissingMessageID   = errors.New("messageId is required")
+	ErrMessageNotSent     = errors.New("message could not be sent")
+	ErrRateLimited        = errors.New("sending too fast, slow down")
+)
+
+// maxContentLength is the longest chat message, in bytes.
+const maxContentLength = 2000
+
+// maxEncodedContentLength is the longest chat message once encoding/json has
+// escaped it, which turns <, > and & into six bytes each. It leaves the rest
+// of the envelope room under the payload limit of the Postgres broker.
+const maxEncodedContentLength = 6000
+
+// Envelope is every frame of the chat protocol. Which fields are set depends
+// on Type:
+//
+//	message         Message (Content when sent by a client)
+//	history         Messages, oldest first, sent once on connect
+//	presence        Users online in the room, sent once on connect
+//	presence_join   User, who came online
+//	presence_leave  User, who went offline
+//	typing          User, who is typing
+//	read_receipt    User has read up to MessageID
+//	error           Error, only sent to the client that caused it
+//	message_edited  Message, with its new content
+//	message_deleted MessageID was deleted
+//	user_muted      User can't send messages until Until
+//	user_unmuted    User can send messages again
+//	user_kicked     User was disconnected from the room
+type Envelope struct {
+	Version   int            `json:"v"`
+	Type      string         `json:"type"`
+	EventID   int64          `json:"eventId,omitempty"`
+	Content   string         `json:"content,omitempty"`
+	Message   *Message       `json:"message,omitempty"`
+	Messages  []Message      `json:"messages,omitempty"`
+	User      *PresenceUser  `json:"user,omitempty"`
+	Users     []PresenceUser `json:"users,omitempty"`
+	MessageID int64          `json:"messageId,omitempty"`
+	Until     string         `json:"until,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// PresenceUser is a member of a chat room.
+type PresenceUser struct {
+	UserID   int64  `json:"userId"`
+	Username string `json:"username"`
+}
+
+// validate checks an envelope sent by a client. Frames without a type come
+// from clients predating the envelope and are plain chat messages.
+func (e *Envelope) validate() error {
+	if e.Version != 0 && e.Version != ProtocolVersion {
+		return ErrUnsupportedVersion
+	}
+	if e.Type == "" {
+		e.Type = TypeMessage
+	}
+
+	switch e.Type {
+	case TypeMessage:
+		if strings.TrimSpace(e.Content) == "" {
+			return ErrEmptyMessage
+		}
+		if len(e.Content) > maxContentLength {
+			return ErrMessageTooLong
+		}
+		if encoded, err := json.Marshal(e.Content); err != nil || len(encoded) > maxEncodedContentLength {
+			return ErrMessageTooLong
+		}
+	case TypeTyping:
+	case TypeReadReceipt:
+		if e.MessageID <= 0 {
+			return ErrMissingMessageID
+		}
+	default:
+		return ErrUnknownType
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MishNia/Sportify.git/internal/ratelimit"
+	"github.com/MishNia/Sportify.git/internal/store"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newChatServer serves the chat of event 1, as the user given by the path
+func newChatServer(t *testing.T, hub *Hub) (*httptest.Server, func(user string) *websocket.Conn) {
+	users := map[string]int64{"/alice": 1, "/bob": 2}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		hub.HandleWebSocket(conn, 1, users[r.URL.Path], strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dial := func(user string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"/"+user, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to WebSocket server: %v", err)
+		}
+		return conn
+	}
+	return server, dial
+}
+
+// TestPresence tests the snapshot sent on connect and the joins and leaves
+// that follow
+func TestPresence(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+
+	assert.Empty(t, readEnvelope(t, alice, TypePresence).Users, "Alice is alone")
+	assert.Equal(t, &PresenceUser{UserID: 1, Username: "alice"}, readEnvelope(t, alice, TypePresenceJoin).User)
+
+	bob := dial("bob")
+
+	snapshot := readEnvelope(t, bob, TypePresence)
+	assert.Equal(t, ProtocolVersion, snapshot.Version)
+	assert.Equal(t, []PresenceUser{{UserID: 1, Username: "alice"}}, snapshot.Users)
+	assert.Equal(t, int64(2), readEnvelope(t, alice, TypePresenceJoin).User.UserID)
+
+	// A second connection of the same user doesn't join again
+	bob2 := dial("bob")
+	defer bob2.Close()
+	assert.Len(t, readEnvelope(t, bob2, TypePresence).Users, 2)
+
+	// Bob only goes offline once every one of Bob's connections closes
+	bob.Close()
+	bob2.Close()
+	leave := readEnvelope(t, alice, TypePresenceLeave)
+	assert.Equal(t, int64(2), leave.User.UserID)
+
+	bob3 := dial("bob")
+	defer bob3.Close()
+	assert.Equal(t, []PresenceUser{{UserID: 1, Username: "alice"}}, readEnvelope(t, bob3, TypePresence).Users, "Only Alice is left online")
+}
+
+// TestTypingAndReadReceipts tests that typing indicators reach the other
+// members only and read receipts reach everyone
+func TestTypingAndReadReceipts(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+	bob := dial("bob")
+	defer bob.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeTyping}))
+	typing := readEnvelope(t, bob, TypeTyping)
+	assert.Equal(t, int64(1), typing.User.UserID)
+
+	assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: "Kickoff at 6"}))
+	message := readEnvelope(t, bob, TypeMessage).Message
+	assert.Equal(t, "Kickoff at 6", message.Content)
+
+	// Alice's next frame is the message, the typing indicator isn't echoed
+	alice.SetReadDeadline(time.Now().Add(time.Second))
+	var env Envelope
+	assert.NoError(t, alice.ReadJSON(&env))
+	assert.Equal(t, TypeMessage, env.Type)
+
+	assert.NoError(t, bob.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeReadReceipt, MessageID: message.ID}))
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		receipt := readEnvelope(t, conn, TypeReadReceipt)
+		assert.Equal(t, int64(2), receipt.User.UserID)
+		assert.Equal(t, message.ID, receipt.MessageID)
+	}
+}
+
+// TestProtocolErrors tests that invalid frames get an error back instead of
+// closing the connection
+func TestProtocolErrors(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	tests := []struct {
+		name  string
+		frame string
+		err   error
+	}{
+		{name: "unknown type", frame: `{"v": 1, "type": "shout"}`, err: ErrUnknownType},
+		{name: "newer version", frame: `{"v": 2, "type": "message", "content": "hi"}`, err: ErrUnsupportedVersion},
+		{name: "empty message", frame: `{"v": 1, "type": "message", "content": "  "}`, err: ErrEmptyMessage},
+		{name: "message too long", frame: `{"v": 1, "type": "message", "content": "` + strings.Repeat("a", maxContentLength+1) + `"}`, err: ErrMessageTooLong},
+		{name: "message too long once escaped", frame: `{"v": 1, "type": "message", "content": "` + strings.Repeat("<", maxContentLength) + `"}`, err: ErrMessageTooLong},
+		{name: "receipt without message", frame: `{"v": 1, "type": "read_receipt"}`, err: ErrMissingMessageID},
+		{name: "not json", frame: `hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, alice.WriteMessage(websocket.TextMessage, []byte(tt.frame)))
+			env := readEnvelope(t, alice, TypeError)
+			if tt.err != nil {
+				assert.Equal(t, tt.err.Error(), env.Error)
+			} else {
+				assert.NotEmpty(t, env.Error)
+			}
+		})
+	}
+
+	// Frames without a type are chat messages from older clients
+	assert.NoError(t, alice.WriteJSON(Message{Content: "still works"}))
+	assert.Equal(t, "still works", readEnvelope(t, alice, TypeMessage).Message.Content)
+}
+
+// TestMessageNotPublished tests that the sender is told when the broker
+// refuses a message
+func TestMessageNotPublished(t *testing.T) {
+	hub, err := NewHubWithBroker(newMemoryMessageStore(), &refusingBroker{LocalBroker: NewLocalBroker()})
+	assert.NoError(t, err)
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+	readEnvelope(t, alice, TypePresence)
+
+	assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: "hello"}))
+	assert.Equal(t, ErrMessageNotSent.Error(), readEnvelope(t, alice, TypeError).Error)
+}
+
+// refusingBroker delivers nothing, like a Postgres broker given payloads
+// that are too large
+type refusingBroker struct{ *LocalBroker }
+
+func (*refusingBroker) Publish(ctx context.Context, payload []byte) error {
+	return ErrPayloadTooLarge
+}
+
+// TestModeration tests that muted users get an error back and kicked users
+// are told before being disconnected
+func TestModeration(t *testing.T) {
+	messages := newMemoryMessageStore()
+	messages.muted = map[int64]bool{2: true}
+	hub := NewHub(messages)
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+	bob := dial("bob")
+	defer bob.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	assert.NoError(t, bob.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: "let me talk"}))
+	assert.Equal(t, store.ErrMuted.Error(), readEnvelope(t, bob, TypeError).Error)
+
+	hub.Broadcast(&Envelope{Type: TypeUserKicked, EventID: 1, User: &PresenceUser{UserID: 2, Username: "bob"}})
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		kicked := readEnvelope(t, conn, TypeUserKicked)
+		assert.Equal(t, int64(2), kicked.User.UserID)
+	}
+
+	// Bob's connection is closed, and Alice sees Bob leave
+	bob.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := bob.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "unexpected error: %v", err)
+	assert.Equal(t, int64(2), readEnvelope(t, alice, TypePresenceLeave).User.UserID)
+}
+
+// TestMessageRateLimit tests that frames over the limit get an error back
+// and aren't passed on
+func TestMessageRateLimit(t *testing.T) {
+	messages := newMemoryMessageStore()
+	hub := NewHub(messages)
+	hub.LimitMessages(ratelimit.NewTokenBucket(ratelimit.Config{Requests: 2, Window: time.Minute}))
+	go hub.Run()
+
+	server, dial := newChatServer(t, hub)
+	defer server.Close()
+
+	alice := dial("alice")
+	defer alice.Close()
+	readEnvelope(t, alice, TypePresenceJoin)
+
+	for _, content := range []string{"one", "two", "three"} {
+		assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: content}))
+	}
+
+	// The error skips the broker, so it may arrive before the messages
+	var received []string
+	var errs []string
+	alice.SetReadDeadline(time.Now().Add(time.Second))
+	for len(received)+len(errs) < 3 {
+		var env Envelope
+		if err := alice.ReadJSON(&env); err != nil {
+			t.Fatalf("Failed to receive: %v", err)
+		}
+		switch env.Type {
+		case TypeMessage:
+			received = append(received, env.Message.Content)
+		case TypeError:
+			errs = append(errs, env.Error)
+		}
+	}
+	assert.Equal(t, []string{"one", "two"}, received)
+	assert.Equal(t, []string{ErrRateLimited.Error()}, errs)
+	assert.Len(t, messages.byEvent(1), 2)
+}
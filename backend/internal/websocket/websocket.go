@@ -1,87 +1,458 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/MishNia/Sportify.git/internal/ratelimit"
+	"github.com/MishNia/Sportify.git/internal/store"
 	"github.com/gorilla/websocket"
 )
 
-type Client struct {
-	conn     *websocket.Conn
-	eventID  int64
-	userID   int64
-	username string
+// historySize is the number of past messages replayed to a client when it
+// joins a room. Older messages are fetched through the REST endpoint.
+const historySize = store.DefaultMessagePageSize
+
+// MessageStore persists chat messages so history survives restarts.
+type MessageStore interface {
+	Create(context.Context, *store.ChatMessage) error
+	GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*store.ChatMessage, error)
 }
 
 type Message struct {
+	ID        int64  `json:"id"`
 	EventID   int64  `json:"eventId"`
 	UserID    int64  `json:"userId"`
 	Username  string `json:"username"`
 	Content   string `json:"content"`
 	Timestamp string `json:"timestamp"`
+	EditedAt  string `json:"editedAt,omitempty"`
+}
+
+// NewMessage converts a stored chat message into its wire format.
+func NewMessage(m *store.ChatMessage) Message {
+	msg := Message{
+		ID:        m.ID,
+		EventID:   m.EventID,
+		UserID:    m.UserID,
+		Username:  m.Username,
+		Content:   m.Content,
+		Timestamp: m.CreatedAt.Format(time.RFC3339),
+	}
+	if m.EditedAt != nil {
+		msg.EditedAt = m.EditedAt.Format(time.RFC3339)
+	}
+	return msg
+}
+
+// NotificationMessage is what notification connections receive.
+type NotificationMessage struct {
+	Type         string              `json:"type"`
+	Notification *store.Notification `json:"notification"`
 }
 
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan Message
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.Mutex
-	messages   map[int64][]Message
+	messages   MessageStore
+	broker     Broker
+
+	// incoming is the hub's subscription to the broker, unsubscribe ends
+	// it
+	incoming    <-chan []byte
+	unsubscribe context.CancelFunc
+
+	// limiter limits the frames each user sends to event chats, nil means
+	// no limit
+	limiter ratelimit.Limiter
+
+	// id tells this hub's broker messages apart from the others'
+	id string
+
+	// peers tracks who is online in each event's chat, per hub sharing the
+	// broker, this one included. Only Run touches it.
+	peers map[string]map[int64]map[int64]*member
+
+	// presenceInterval is how often the hub sends and expires presence,
+	// presenceTTL how long a member lasts without a heartbeat
+	presenceInterval time.Duration
+	presenceTTL      time.Duration
+
+	// handlers counts the connection handlers and heartbeat still running,
+	// which may publish until they return. Once closing is set, under mu,
+	// no more start.
+	handlers sync.WaitGroup
+	closing  bool
+
+	// quit is closed to ask Run to stop, done is closed once it has
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan Message),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		messages:   make(map[int64][]Message),
+// brokerMessage is what hubs exchange through the broker.
+type brokerMessage struct {
+	From         string              `json:"from,omitempty"`
+	Room         *Envelope           `json:"room,omitempty"`
+	Presence     *presenceSnapshot   `json:"presence,omitempty"`
+	Notification *store.Notification `json:"notification,omitempty"`
+}
+
+// NewHub returns a hub that only reaches the clients of this process.
+func NewHub(messages MessageStore) *Hub {
+	// Subscribing to a local broker can't fail
+	hub, _ := NewHubWithBroker(messages, NewLocalBroker())
+	return hub
+}
+
+// NewHubWithBroker returns a hub that shares its rooms with every hub using
+// the same broker. It fails if the hub can't subscribe to the broker, as the
+// hub couldn't deliver anything, not even to its own clients.
+func NewHubWithBroker(messages MessageStore, broker Broker) (*Hub, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	incoming, err := broker.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
+
+	return &Hub{
+		clients:          make(map[*Client]bool),
+		register:         make(chan *Client),
+		unregister:       make(chan *Client),
+		messages:         messages,
+		broker:           broker,
+		incoming:         incoming,
+		unsubscribe:      cancel,
+		id:               newHubID(),
+		peers:            make(map[string]map[int64]map[int64]*member),
+		presenceInterval: presenceInterval,
+		presenceTTL:      presenceTTL,
+		quit:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}, nil
+}
+
+// LimitMessages limits the frames each user may send to event chats, across
+// all their connections. It must be called before Run.
+func (h *Hub) LimitMessages(limiter ratelimit.Limiter) {
+	h.limiter = limiter
 }
 
+// Run dispatches messages to the clients until Shutdown is called.
 func (h *Hub) Run() {
+	defer close(h.done)
+	defer h.unsubscribe()
+
+	go h.heartbeat()
+	expire := time.NewTicker(h.presenceInterval)
+	defer expire.Stop()
+
 	for {
 		select {
+		case <-h.quit:
+			h.closeAll()
+			return
+
+		case now := <-expire.C:
+			h.expirePresence(now)
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
-			h.mu.Unlock()
-
-			h.mu.Lock()
-			if messages, ok := h.messages[client.eventID]; ok {
-				for _, msg := range messages {
-					client.conn.WriteJSON(msg)
-				}
+			if client.eventID != 0 {
+				h.sendPresence(client)
 			}
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.conn.Close()
+				h.detach(client)
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.Lock()
-			h.messages[message.EventID] = append(h.messages[message.EventID], message)
-
-			for client := range h.clients {
-				if client.eventID == message.EventID {
-					err := client.conn.WriteJSON(message)
-					if err != nil {
-						log.Printf("error: %v", err)
-						client.conn.Close()
-						delete(h.clients, client)
-					}
-				}
+		case payload := <-h.incoming:
+			var msg brokerMessage
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				log.Printf("error decoding broker message: %v", err)
+				continue
 			}
-			h.mu.Unlock()
+			if msg.Room != nil {
+				h.receiveRoom(msg.From, msg.Room)
+			}
+			if msg.Presence != nil {
+				h.receivePresence(msg.From, msg.Presence)
+			}
+			if msg.Notification != nil {
+				h.sendNotification(msg.Notification)
+			}
+		}
+	}
+}
+
+// receiveRoom updates the presence of the room and passes the envelope on
+// to its clients. Joins and leaves are only passed on when a user's first
+// connection, across all hubs, opens or last one closes.
+func (h *Hub) receiveRoom(from string, env *Envelope) {
+	if env.User == nil && (env.Type == TypePresenceJoin || env.Type == TypePresenceLeave || env.Type == TypeTyping || env.Type == TypeUserKicked) {
+		log.Printf("error: %s envelope without user", env.Type)
+		return
+	}
+
+	switch env.Type {
+	case TypePresenceJoin:
+		online := h.isOnline(env.EventID, env.User.UserID)
+		h.track(from, env.EventID, *env.User, time.Now()).conns++
+		if online {
+			return
+		}
+
+	case TypePresenceLeave:
+		if !h.left(from, env.EventID, env.User.UserID) {
+			return
+		}
+
+	case TypeUserKicked:
+		// Everyone, including the kicked user, is told before the
+		// connections close
+		h.sendRoom(env)
+		h.kick(env.EventID, env.User.UserID)
+		return
+	}
+
+	h.sendRoom(env)
+}
+
+// kick closes the connections of the user to the event's chat on this hub.
+// Their handlers publish the presence leave as the connections go away.
+func (h *Hub) kick(eventID, userID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "kicked from the chat")
+	for client := range h.clients {
+		if client.eventID != eventID || client.userID != userID {
+			continue
+		}
+		// The write pump sends what is queued, including the kick, before
+		// the close frame
+		client.closeMsg = msg
+		h.detach(client)
+	}
+}
+
+// sendRoom queues the envelope for the clients of its event connected to
+// this hub. Typing indicators aren't sent back to the user typing.
+func (h *Hub) sendRoom(env *Envelope) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.eventID != env.EventID {
+			continue
+		}
+		if env.Type == TypeTyping && client.userID == env.User.UserID {
+			continue
+		}
+		h.deliver(client, data)
+	}
+}
+
+// sendPresence queues the users online in the client's room for it. The
+// caller must hold h.mu.
+func (h *Hub) sendPresence(client *Client) {
+	users := h.onlineUsers(client.eventID)
+	data, err := json.Marshal(&Envelope{Version: ProtocolVersion, Type: TypePresence, EventID: client.eventID, Users: users})
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+	h.deliver(client, data)
+}
+
+// sendError queues the error for the client only.
+func (h *Hub) sendError(client *Client, err error) {
+	data, err := json.Marshal(&Envelope{Version: ProtocolVersion, Type: TypeError, EventID: client.eventID, Error: err.Error()})
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[client] {
+		h.deliver(client, data)
+	}
+}
+
+// sendNotification queues the notification for the notification connections
+// of its user connected to this hub.
+func (h *Hub) sendNotification(notification *store.Notification) {
+	data, err := json.Marshal(NotificationMessage{Type: "notification", Notification: notification})
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if client.eventID == 0 && client.userID == notification.UserID {
+			h.deliver(client, data)
+		}
+	}
+}
+
+// Broadcast sends the envelope to the chat of its event on every instance.
+// It is how moderation done through the REST API reaches the room.
+func (h *Hub) Broadcast(env *Envelope) {
+	h.publishRoom(env)
+}
+
+// publishRoom sends the envelope to the clients of its event on every
+// instance.
+func (h *Hub) publishRoom(env *Envelope) error {
+	env.Version = ProtocolVersion
+	return h.publish(brokerMessage{From: h.id, Room: env})
+}
+
+// publish sends the message to the hubs of every instance through the
+// broker. Failures are logged as well as returned, for callers with nobody
+// to tell.
+func (h *Hub) publish(msg brokerMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+	if err := h.broker.Publish(ctx, payload); err != nil {
+		log.Printf("error publishing to broker: %v", err)
+		return err
+	}
+	return nil
+}
+
+// deliver queues the message for the client. A client whose buffer is full
+// can't keep up, so it is dropped rather than holding up everyone else. The
+// caller must hold h.mu.
+func (h *Hub) deliver(client *Client, data []byte) {
+	if client.holding && len(client.held) < sendBufferSize {
+		client.held = append(client.held, data)
+		return
+	}
+	if !client.holding && client.queue(data) {
+		return
+	}
+
+	log.Printf("dropping slow client: event %d, user %d", client.eventID, client.userID)
+	h.detach(client)
+	client.conn.Close()
+}
+
+// detach forgets the client and closes its send buffer, which ends its write
+// pump. The caller must hold h.mu.
+func (h *Hub) detach(client *Client) {
+	delete(h.clients, client)
+	close(client.send)
+	client.detached = true
+}
+
+// Shutdown stops Run after sending a close frame to every client, and waits
+// for it and the connection handlers to return or for ctx to be done. The
+// broker must stay open until then, as the handlers publish the leaves of
+// their users on the way out.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+	h.stopOnce.Do(func() { close(h.quit) })
+
+	idle := make(chan struct{})
+	go func() {
+		h.handlers.Wait()
+		close(idle)
+	}()
+
+	for _, finished := range []chan struct{}{h.done, idle} {
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// enter counts a goroutine that may publish until it returns, so Shutdown
+// waits for it. It reports false once the hub is shutting down, in which
+// case the goroutine must not start.
+func (h *Hub) enter() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return false
+	}
+	h.handlers.Add(1)
+	return true
+}
+
+// closeAll tells every client the server is going away and closes their
+// connections.
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for client := range h.clients {
+		if err := client.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWait)); err != nil {
+			log.Printf("error sending close frame: %v", err)
 		}
+		h.detach(client)
+		client.conn.Close()
+	}
+}
+
+// Notify pushes the notification to every notification connection of its
+// user. Users that aren't connected will see it the next time they list
+// their notifications.
+func (h *Hub) Notify(notification *store.Notification) {
+	h.publish(brokerMessage{Notification: notification})
+}
+
+// add registers the client, or closes its connection and returns false if
+// the hub has stopped.
+func (h *Hub) add(client *Client) bool {
+	select {
+	case h.register <- client:
+		return true
+	case <-h.done:
+		client.conn.Close()
+		return false
+	}
+}
+
+// remove unregisters the client. Once the hub has stopped its connection has
+// already been closed.
+func (h *Hub) remove(client *Client) {
+	select {
+	case h.unregister <- client:
+	case <-h.done:
 	}
 }
 
@@ -90,33 +461,171 @@ var upgrader = websocket.Upgrader{
 	WriteBufferSize: 1024,
 }
 
+// HandleWebSocket serves the chat of the event to the user until the
+// connection closes. Clients get the recent history and who is online first.
 func (h *Hub) HandleWebSocket(conn *websocket.Conn, eventID int64, userID int64, username string) {
-	client := &Client{
-		conn:     conn,
-		eventID:  eventID,
-		userID:   userID,
-		username: username,
+	if !h.enter() {
+		conn.Close()
+		return
 	}
+	defer h.handlers.Done()
 
-	h.register <- client
+	client := newClient(conn, eventID, userID, username)
+	user := &PresenceUser{UserID: userID, Username: username}
+
+	// Register before loading the history so nothing published in between
+	// is missed, but hold it back until the history has been queued
+	client.holding = true
+	if !h.add(client) {
+		return
+	}
+	h.sendHistory(client)
 
+	h.publishRoom(&Envelope{Type: TypePresenceJoin, EventID: eventID, User: user})
 	defer func() {
-		h.unregister <- client
+		h.remove(client)
+		h.publishRoom(&Envelope{Type: TypePresenceLeave, EventID: eventID, User: user})
 	}()
 
+	go client.writePump()
+	client.keepAlive()
+
 	for {
-		var msg Message
-		err := conn.ReadJSON(&msg)
+		_, data, err := conn.ReadMessage()
 		if err != nil {
 			log.Printf("error: %v", err)
 			break
 		}
 
-		msg.EventID = eventID
-		msg.UserID = userID
-		msg.Username = username
-		msg.Timestamp = time.Now().Format(time.RFC3339)
+		if h.limiter != nil {
+			if ok, _ := h.limiter.Allow(strconv.FormatInt(userID, 10)); !ok {
+				h.sendError(client, ErrRateLimited)
+				continue
+			}
+		}
+
+		var in Envelope
+		if err := json.Unmarshal(data, &in); err != nil {
+			h.sendError(client, err)
+			continue
+		}
+		if err := in.validate(); err != nil {
+			h.sendError(client, err)
+			continue
+		}
+
+		switch in.Type {
+		case TypeMessage:
+			stored := &store.ChatMessage{
+				EventID:  eventID,
+				UserID:   userID,
+				Username: username,
+				Content:  in.Content,
+			}
+			if err := h.messages.Create(context.Background(), stored); err != nil {
+				if errors.Is(err, store.ErrMuted) {
+					h.sendError(client, err)
+					continue
+				}
+				log.Printf("error saving chat message: %v", err)
+				h.sendError(client, ErrMessageNotSent)
+				continue
+			}
+
+			// The room never sees a message the broker refused, so the
+			// sender has to be told
+			message := NewMessage(stored)
+			if err := h.publishRoom(&Envelope{Type: TypeMessage, EventID: eventID, Message: &message}); err != nil {
+				h.sendError(client, ErrMessageNotSent)
+			}
+
+		case TypeTyping:
+			h.publishRoom(&Envelope{Type: TypeTyping, EventID: eventID, User: user})
+
+		case TypeReadReceipt:
+			h.publishRoom(&Envelope{Type: TypeReadReceipt, EventID: eventID, User: user, MessageID: in.MessageID})
+		}
+	}
+}
+
+// HandleNotifications keeps the connection registered for the user's
+// notifications until the client goes away.
+func (h *Hub) HandleNotifications(conn *websocket.Conn, userID int64) {
+	if !h.enter() {
+		conn.Close()
+		return
+	}
+	defer h.handlers.Done()
+
+	client := newClient(conn, 0, userID, "")
+
+	if !h.add(client) {
+		return
+	}
+	defer h.remove(client)
+
+	go client.writePump()
+	client.keepAlive()
+
+	// Clients don't send anything on this connection, reading only notices
+	// when it is closed
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
+// sendHistory queues the recent history of the client's room, followed by
+// what the hub held back for the client while loading it. Messages published
+// in the meantime may be in both and are only sent once.
+func (h *Hub) sendHistory(client *Client) {
+	var data []byte
+	sent := make(map[int64]bool)
+
+	history, err := h.messages.GetByEvent(context.Background(), client.eventID, 0, historySize)
+	if err != nil {
+		log.Printf("error loading chat history: %v", err)
+	} else {
+		messages := make([]Message, 0, len(history))
+		for _, m := range history {
+			messages = append(messages, NewMessage(m))
+			sent[m.ID] = true
+		}
+
+		data, err = json.Marshal(&Envelope{Version: ProtocolVersion, Type: TypeHistory, EventID: client.eventID, Messages: messages})
+		if err != nil {
+			log.Printf("error: %v", err)
+			data = nil
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	held := client.held
+	client.holding, client.held = false, nil
+
+	// The client may have been kicked or dropped in the meantime
+	if data != nil && !client.detached {
+		h.deliver(client, data)
+	}
+	for _, d := range held {
+		if client.detached {
+			return
+		}
+		if isSentMessage(d, sent) {
+			continue
+		}
+		h.deliver(client, d)
+	}
+}
 
-		h.broadcast <- msg
+// isSentMessage reports whether data is a chat message whose ID is in sent.
+func isSentMessage(data []byte, sent map[int64]bool) bool {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return false
 	}
+	return env.Type == TypeMessage && env.Message != nil && sent[env.Message.ID]
 }
@@ -1,23 +1,87 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/MishNia/Sportify.git/internal/store"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
 
+// memoryMessageStore is an in-memory MessageStore used in place of Postgres
+type memoryMessageStore struct {
+	mu       sync.Mutex
+	messages []*store.ChatMessage
+	muted    map[int64]bool
+}
+
+func newMemoryMessageStore() *memoryMessageStore {
+	return &memoryMessageStore{}
+}
+
+func (s *memoryMessageStore) Create(ctx context.Context, msg *store.ChatMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.muted[msg.UserID] {
+		return store.ErrMuted
+	}
+	msg.ID = int64(len(s.messages) + 1)
+	msg.CreatedAt = time.Now()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *memoryMessageStore) GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*store.ChatMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []*store.ChatMessage{}
+	for _, m := range s.messages {
+		if m.EventID == eventID && (before == 0 || m.ID < before) {
+			result = append(result, m)
+		}
+	}
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (s *memoryMessageStore) byEvent(eventID int64) []*store.ChatMessage {
+	messages, _ := s.GetByEvent(context.Background(), eventID, 0, 1000)
+	return messages
+}
+
+// readEnvelope reads frames until one of the given type arrives
+func readEnvelope(t testing.TB, conn *websocket.Conn, typ string) Envelope {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var env Envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			t.Fatalf("Failed to receive %s: %v", typ, err)
+		}
+		if env.Type == typ {
+			return env
+		}
+	}
+}
+
 // TestNewHub tests the creation of a new Hub
 func TestNewHub(t *testing.T) {
-	hub := NewHub()
+	hub := NewHub(newMemoryMessageStore())
 	assert.NotNil(t, hub)
 	assert.NotNil(t, hub.clients)
-	assert.NotNil(t, hub.broadcast)
+	assert.NotNil(t, hub.incoming)
 	assert.NotNil(t, hub.register)
 	assert.NotNil(t, hub.unregister)
 	assert.NotNil(t, hub.messages)
@@ -25,7 +89,7 @@ func TestNewHub(t *testing.T) {
 
 // TestHubRun tests the Run method of the Hub
 func TestHubRun(t *testing.T) {
-	hub := NewHub()
+	hub := NewHub(newMemoryMessageStore())
 	
 	// Start the hub in a goroutine
 	go hub.Run()
@@ -42,12 +106,7 @@ func TestHubRun(t *testing.T) {
 		}
 		
 		// Create a test client with a valid connection
-		client := &Client{
-			conn:     conn,
-			eventID:  1,
-			userID:   1,
-			username: "testuser",
-		}
+		client := newClient(conn, 1, 1, "testuser")
 		
 		// Register the client
 		hub.register <- client
@@ -91,7 +150,8 @@ func TestHubRun(t *testing.T) {
 
 // TestHandleWebSocket tests the HandleWebSocket method
 func TestHandleWebSocket(t *testing.T) {
-	hub := NewHub()
+	messageStore := newMemoryMessageStore()
+	hub := NewHub(messageStore)
 	
 	// Start the hub in a goroutine
 	go hub.Run()
@@ -143,11 +203,8 @@ func TestHandleWebSocket(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	
 	// Check if the message was stored
-	hub.mu.Lock()
-	messages, exists := hub.messages[1]
-	hub.mu.Unlock()
+	messages := messageStore.byEvent(1)
 	
-	assert.True(t, exists, "Messages should exist for event ID 1")
 	assert.Equal(t, 1, len(messages), "There should be one message")
 	assert.Equal(t, "Hello, world!", messages[0].Content, "Message content should match")
 	assert.Equal(t, int64(1), messages[0].EventID, "Event ID should match")
@@ -157,7 +214,7 @@ func TestHandleWebSocket(t *testing.T) {
 
 // TestMessageBroadcast tests the message broadcasting functionality
 func TestMessageBroadcast(t *testing.T) {
-	hub := NewHub()
+	hub := NewHub(newMemoryMessageStore())
 	
 	// Start the hub in a goroutine
 	go hub.Run()
@@ -214,11 +271,7 @@ func TestMessageBroadcast(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	
 	// Check if client 2 received the message
-	var receivedMessage Message
-	err = conn2.ReadJSON(&receivedMessage)
-	if err != nil {
-		t.Fatalf("Failed to receive message: %v", err)
-	}
+	receivedMessage := readEnvelope(t, conn2, TypeMessage).Message
 	
 	assert.Equal(t, "Hello from user1!", receivedMessage.Content, "Message content should match")
 	assert.Equal(t, int64(1), receivedMessage.EventID, "Event ID should match")
@@ -228,7 +281,7 @@ func TestMessageBroadcast(t *testing.T) {
 
 // TestMessageHistory tests that new clients receive message history
 func TestMessageHistory(t *testing.T) {
-	hub := NewHub()
+	hub := NewHub(newMemoryMessageStore())
 	
 	// Start the hub in a goroutine
 	go hub.Run()
@@ -282,11 +335,9 @@ func TestMessageHistory(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	
 	// Check if the new client received the message history
-	var receivedMessage Message
-	err = conn2.ReadJSON(&receivedMessage)
-	if err != nil {
-		t.Fatalf("Failed to receive message history: %v", err)
-	}
+	history := readEnvelope(t, conn2, TypeHistory).Messages
+	assert.Len(t, history, 1, "History should contain the message")
+	receivedMessage := history[0]
 	
 	assert.Equal(t, "Hello, world!", receivedMessage.Content, "Message content should match")
 	assert.Equal(t, int64(1), receivedMessage.EventID, "Event ID should match")
@@ -294,6 +345,123 @@ func TestMessageHistory(t *testing.T) {
 	assert.Equal(t, "testuser", receivedMessage.Username, "Username should match")
 }
 
+// TestMessageHistoryFromStore tests that history is replayed from the store
+// rather than from hub memory, so it survives a restart
+func TestMessageHistoryFromStore(t *testing.T) {
+	messageStore := newMemoryMessageStore()
+	messageStore.Create(context.Background(), &store.ChatMessage{EventID: 1, UserID: 2, Username: "user2", Content: "before restart"})
+	messageStore.Create(context.Background(), &store.ChatMessage{EventID: 2, UserID: 2, Username: "user2", Content: "other event"})
+
+	hub := NewHub(messageStore)
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		hub.HandleWebSocket(conn, 1, 1, "testuser")
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket server: %v", err)
+	}
+	defer conn.Close()
+
+	history := readEnvelope(t, conn, TypeHistory).Messages
+	assert.Len(t, history, 1, "History should only contain the event's messages")
+	receivedMessage := history[0]
+
+	assert.Equal(t, int64(1), receivedMessage.ID, "Message ID should be carried over")
+	assert.Equal(t, "before restart", receivedMessage.Content, "Message content should match")
+	assert.Equal(t, "user2", receivedMessage.Username, "Username should match")
+}
+
+// TestMessageDuringHistory tests that a message sent while a client's history
+// loads reaches it exactly once, whether or not the history has it
+func TestMessageDuringHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		inHistory bool
+	}{
+		{name: "not in history", inHistory: false},
+		{name: "in history", inHistory: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := &blockingHistoryStore{
+				memoryMessageStore: newMemoryMessageStore(),
+				snapshotFirst:      !tt.inHistory,
+				loading:            make(chan struct{}),
+				release:            make(chan struct{}),
+			}
+			hub := NewHub(messages)
+			go hub.Run()
+
+			server, dial := newChatServer(t, hub)
+			defer server.Close()
+
+			alice := dial("alice")
+			defer alice.Close()
+			readEnvelope(t, alice, TypeHistory)
+
+			// Bob's history load hangs until Alice's message went out
+			messages.block.Store(true)
+			bob := dial("bob")
+			defer bob.Close()
+			<-messages.loading
+
+			assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: "first"}))
+			readEnvelope(t, alice, TypeMessage)
+			close(messages.release)
+
+			history := readEnvelope(t, bob, TypeHistory).Messages
+			if tt.inHistory {
+				assert.Len(t, history, 1)
+			} else {
+				assert.Empty(t, history)
+				assert.Equal(t, "first", readEnvelope(t, bob, TypeMessage).Message.Content)
+			}
+
+			// Nothing is sent twice, the next message is the next one sent
+			assert.NoError(t, alice.WriteJSON(Envelope{Version: ProtocolVersion, Type: TypeMessage, Content: "second"}))
+			assert.Equal(t, "second", readEnvelope(t, bob, TypeMessage).Message.Content)
+		})
+	}
+}
+
+// blockingHistoryStore holds up the history load while block is set, until
+// release is closed. With snapshotFirst it returns the messages from before
+// the wait, like a query that ran before a concurrent insert.
+type blockingHistoryStore struct {
+	*memoryMessageStore
+	snapshotFirst bool
+	block         atomic.Bool
+	loading       chan struct{}
+	release       chan struct{}
+}
+
+func (s *blockingHistoryStore) GetByEvent(ctx context.Context, eventID, before int64, limit int) ([]*store.ChatMessage, error) {
+	if !s.block.CompareAndSwap(true, false) {
+		return s.memoryMessageStore.GetByEvent(ctx, eventID, before, limit)
+	}
+
+	var snapshot []*store.ChatMessage
+	if s.snapshotFirst {
+		snapshot, _ = s.memoryMessageStore.GetByEvent(ctx, eventID, before, limit)
+	}
+	close(s.loading)
+	<-s.release
+	if s.snapshotFirst {
+		return snapshot, nil
+	}
+	return s.memoryMessageStore.GetByEvent(ctx, eventID, before, limit)
+}
+
 // TestMessageJSON tests the JSON serialization of messages
 func TestMessageJSON(t *testing.T) {
 	message := Message{
@@ -323,4 +491,305 @@ func TestMessageJSON(t *testing.T) {
 	assert.Equal(t, message.Username, deserializedMessage.Username, "Username should match")
 	assert.Equal(t, message.Content, deserializedMessage.Content, "Content should match")
 	assert.Equal(t, message.Timestamp, deserializedMessage.Timestamp, "Timestamp should match")
-} 
\ No newline at end of file
+} 
+// TestNotificationPush tests that notifications only reach their user's
+// notification connections
+func TestNotificationPush(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		switch r.URL.Path {
+		case "/chat":
+			hub.HandleWebSocket(conn, 1, 1, "user1")
+		case "/user1":
+			hub.HandleNotifications(conn, 1)
+		case "/user2":
+			hub.HandleNotifications(conn, 2)
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	dial := func(path string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to WebSocket server: %v", err)
+		}
+		return conn
+	}
+
+	chat := dial("/chat")
+	defer chat.Close()
+	user1 := dial("/user1")
+	defer user1.Close()
+	user2 := dial("/user2")
+	defer user2.Close()
+
+	// Give the hub time to process the connections
+	time.Sleep(10 * time.Millisecond)
+
+	hub.Notify(&store.Notification{ID: 7, UserID: 1, Type: store.NotificationEventJoined, Message: "Test User joined \"Pickup\""})
+
+	var received NotificationMessage
+	user1.SetReadDeadline(time.Now().Add(time.Second))
+	if err := user1.ReadJSON(&received); err != nil {
+		t.Fatalf("Failed to receive notification: %v", err)
+	}
+	assert.Equal(t, "notification", received.Type)
+	assert.Equal(t, int64(7), received.Notification.ID)
+
+	// Neither the chat of the same user nor other users get it
+	for _, conn := range []*websocket.Conn{chat, user2} {
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				break
+			}
+			assert.NotContains(t, string(data), `"notification"`)
+		}
+	}
+}
+
+// TestHubShutdown tests that shutting down sends a close frame to every
+// client and stops Run
+func TestHubShutdown(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		if r.URL.Path == "/chat" {
+			hub.HandleWebSocket(conn, 1, 1, "user1")
+		} else {
+			hub.HandleNotifications(conn, 1)
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	var conns []*websocket.Conn
+	for _, path := range []string{"/chat", "/notifications"} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+path, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to WebSocket server: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	// Give the hub time to process the connections
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, hub.Shutdown(ctx))
+
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		var err error
+		for err == nil {
+			_, _, err = conn.ReadMessage()
+		}
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "expected a going away close frame, got %v", err)
+	}
+
+	// A stopped hub turns new connections away instead of blocking
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"/chat", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket server: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+
+	// Shutting down twice is harmless
+	assert.NoError(t, hub.Shutdown(ctx))
+}
+
+// TestHubShutdown_WaitsForHandlers tests that Shutdown only returns once the
+// connection handlers have published their leaves, so the broker can be
+// closed right after
+func TestHubShutdown_WaitsForHandlers(t *testing.T) {
+	broker := NewLocalBroker()
+	hub, err := NewHubWithBroker(newMemoryMessageStore(), broker)
+	assert.NoError(t, err)
+	go hub.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	published, err := broker.Subscribe(ctx)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		hub.HandleWebSocket(conn, 1, 1, "user1")
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket server: %v", err)
+	}
+	defer conn.Close()
+	readEnvelope(t, conn, TypePresenceJoin)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+	assert.NoError(t, hub.Shutdown(shutdownCtx))
+
+	// Nothing may be published once Shutdown has returned, so the leave must
+	// already be there
+	for {
+		select {
+		case payload := <-published:
+			var msg brokerMessage
+			assert.NoError(t, json.Unmarshal(payload, &msg))
+			if msg.Room != nil && msg.Room.Type == TypePresenceLeave {
+				return
+			}
+		default:
+			t.Fatal("Shutdown returned before the leave was published")
+		}
+	}
+}
+
+// TestSlowClientDropped tests that a client whose buffer is full is dropped
+// without holding up the other clients of the event
+func TestSlowClientDropped(t *testing.T) {
+	hub := NewHub(newMemoryMessageStore())
+	go hub.Run()
+
+	slow := make(chan *Client, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Fatalf("Failed to upgrade connection: %v", err)
+		}
+		if r.URL.Path == "/slow" {
+			// Registered without a write pump, so nothing drains its buffer
+			client := newClient(conn, 1, 2, "slow")
+			hub.add(client)
+			slow <- client
+			return
+		}
+		hub.HandleWebSocket(conn, 1, 1, "fast")
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	slowConn, _, err := websocket.DefaultDialer.Dial(wsURL+"/slow", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket server: %v", err)
+	}
+	defer slowConn.Close()
+	fastConn, _, err := websocket.DefaultDialer.Dial(wsURL+"/fast", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket server: %v", err)
+	}
+	defer fastConn.Close()
+
+	slowClient := <-slow
+	time.Sleep(10 * time.Millisecond)
+
+	// One more message than the slow client can buffer
+	go func() {
+		for i := 0; i <= sendBufferSize; i++ {
+			hub.Broadcast(&Envelope{Type: TypeMessage, EventID: 1, Message: &Message{EventID: 1, Content: "message"}})
+		}
+	}()
+
+	for i := 0; i <= sendBufferSize; i++ {
+		readEnvelope(t, fastConn, TypeMessage)
+	}
+
+	hub.mu.Lock()
+	_, exists := hub.clients[slowClient]
+	hub.mu.Unlock()
+	assert.False(t, exists, "Slow client should be dropped")
+}
+
+// BenchmarkBroadcast measures delivering a chat message to every client of
+// an event, with and without clients that stop reading
+func BenchmarkBroadcast(b *testing.B) {
+	for _, bm := range []struct {
+		name    string
+		clients int
+		slow    int
+	}{
+		{name: "100 clients", clients: 100},
+		{name: "500 clients", clients: 500},
+		{name: "500 clients 50 slow", clients: 500, slow: 50},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			hub := NewHub(newMemoryMessageStore())
+			go hub.Run()
+			defer hub.Shutdown(context.Background())
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					b.Errorf("Failed to upgrade connection: %v", err)
+					return
+				}
+				hub.HandleWebSocket(conn, 1, 1, "user")
+			}))
+			defer server.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+			received := make(chan struct{}, bm.clients)
+			for i := 0; i < bm.clients; i++ {
+				conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+				if err != nil {
+					b.Fatalf("Failed to connect to WebSocket server: %v", err)
+				}
+				defer conn.Close()
+
+				if i < bm.slow {
+					continue
+				}
+				go func() {
+					for {
+						var env Envelope
+						if err := conn.ReadJSON(&env); err != nil {
+							return
+						}
+						if env.Type == TypeMessage {
+							received <- struct{}{}
+						}
+					}
+				}()
+			}
+
+			for {
+				hub.mu.Lock()
+				n := len(hub.clients)
+				hub.mu.Unlock()
+				if n == bm.clients {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				hub.Broadcast(&Envelope{Type: TypeMessage, EventID: 1, Message: &Message{EventID: 1, Content: "benchmark"}})
+				for j := bm.slow; j < bm.clients; j++ {
+					<-received
+				}
+			}
+		})
+	}
+}
@@ -12,6 +12,8 @@ import CreateEvent from './pages/CreateEvent'
 import UpdateEvent from './pages/UpdateEvent'
 import MyProfile from "./pages/MyProfile";
 import EventDetails from "./pages/EventDetails";
+import VerifyEmail from "./pages/VerifyEmail";
+import ResetPassword from "./pages/ResetPassword";
 import './App.css';
 
 // Auth Context
@@ -45,6 +47,8 @@ function App() {
             <Route path="/login" element={<Login />}/>
             <Route path="/Forgotpass" element={<Forgotpass />}/>
             <Route path="/Register" element={<Register />}/>
+            <Route path="/verify" element={<VerifyEmail />}/>
+            <Route path="/reset-password" element={<ResetPassword />}/>
             <Route path="/auth/google/callback" element={<GoogleCallback />}/>
             <Route path="/events/:eventId" element={<EventDetails />}/>
             {/* Routes that only require authentication */}
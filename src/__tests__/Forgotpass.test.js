@@ -1,7 +1,12 @@
 import React from "react";
-import { render, screen, fireEvent } from "@testing-library/react";
+import { render, screen, fireEvent, waitFor } from "@testing-library/react";
 import { MemoryRouter } from "react-router-dom";
 import Forgotpass from "../../src/pages/Forgotpass";
+import { forgotPassword } from "../../src/api";
+
+jest.mock("../../src/api", () => ({
+  forgotPassword: jest.fn(() => Promise.resolve({ data: "ok" })),
+}));
 
 
 const mockNavigate = jest.fn();
@@ -37,7 +42,7 @@ describe("Forgot Password Page Unit Tests", () => {
     expect(emailInput.value).toBe("test@example.com");
   });
 
-  it("should show alert when reset password is clicked", () => {
+  it("should show alert when reset password is clicked", async () => {
     
     jest.spyOn(window, "alert").mockImplementation(() => {});
 
@@ -54,7 +59,10 @@ describe("Forgot Password Page Unit Tests", () => {
     const resetButton = screen.getByRole("button", { name: /Reset Password/i });
     fireEvent.click(resetButton);
 
-    expect(window.alert).toHaveBeenCalledWith("Password reset link has been sent to your email.");
+    await waitFor(() =>
+      expect(window.alert).toHaveBeenCalledWith("Password reset link has been sent to your email.")
+    );
+    expect(forgotPassword).toHaveBeenCalledWith("test@example.com");
 
     
     window.alert.mockRestore();
@@ -2,6 +2,12 @@ import React from "react";
 import { render, screen, fireEvent } from "@testing-library/react";
 import { MemoryRouter } from "react-router-dom";
 import Register from "../../src/pages/Register";
+import { signupUser } from "../../src/api";
+
+jest.mock("../../src/api", () => ({
+  signupUser: jest.fn(() => Promise.resolve({ data: { id: 1, email: "test@example.com" } })),
+  resendVerification: jest.fn(() => Promise.resolve({ data: "ok" })),
+}));
 
 //Mocking useNavigate globally
 const mockNavigate = jest.fn();
@@ -92,4 +98,21 @@ describe("Register Page Unit Tests", () => {
     fireEvent.click(registerButton);
     // expect(mockNavigate).toHaveBeenCalledWith("/Profile");
   });
-});
\ No newline at end of file
+
+  it("should ask the user to verify their email after signing up", async () => {
+    render(
+      <MemoryRouter>
+        <Register />
+      </MemoryRouter>
+    );
+
+    fireEvent.change(screen.getByPlaceholderText("Enter your email"), { target: { value: "test@example.com" } });
+    fireEvent.change(screen.getByPlaceholderText("Enter your password"), { target: { value: "ValidPass1!" } });
+    fireEvent.change(screen.getByPlaceholderText("Confirm your password"), { target: { value: "ValidPass1!" } });
+    fireEvent.click(screen.getByRole("button", { name: /Register/i }));
+
+    expect(await screen.findByText("Check your email")).toBeInTheDocument();
+    expect(signupUser).toHaveBeenCalledWith("test@example.com", "ValidPass1!");
+    expect(localStorage.getItem("token")).toBeNull();
+  });
+});
@@ -10,8 +10,27 @@ const getAuthHeaders = () => {
     };
 };
 
+// Exchange the stored refresh token for a new access token.
+// Returns the new access token, or null if the session is over.
+const refreshAccessToken = async () => {
+    const refreshToken = localStorage.getItem("refreshToken");
+    if (!refreshToken) return null;
+
+    try {
+        const response = await axios.post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken });
+        const { token, refresh_token } = response.data.data;
+        localStorage.setItem("token", token);
+        localStorage.setItem("refreshToken", refresh_token);
+        return token;
+    } catch (error) {
+        console.error('Error refreshing access token:', error);
+        localStorage.removeItem("refreshToken");
+        return null;
+    }
+};
+
 // Helper function for authenticated requests
-const authRequest = async (method, url, data = null) => {
+const authRequest = async (method, url, data = null, retried = false) => {
     try {
         const headers = getAuthHeaders();
         const config = { headers };
@@ -37,6 +56,13 @@ const authRequest = async (method, url, data = null) => {
     } catch (error) {
         console.error(`Error in ${method.toUpperCase()} request to ${url}:`, error);
 
+        // If the access token expired, refresh it once and retry
+        if (error.response && error.response.status === 401 && !retried) {
+            if (await refreshAccessToken()) {
+                return authRequest(method, url, data, true);
+            }
+        }
+
         // If token is invalid/expired, redirect to login
         if (error.response && error.response.status === 401) {
             console.log('Authentication error, redirecting to login');
@@ -62,27 +88,34 @@ export const signupUser = async (email, password) => {
     }
 };
 
+// Store the refresh token and user ID of a fresh session and return the
+// response with just the access token, which is all callers need
+const saveSession = (responseData) => {
+    const { token, refresh_token } = responseData.data;
+    localStorage.setItem('refreshToken', refresh_token);
+    try {
+        // JWT tokens are in format: header.payload.signature
+        // We need the payload part which is the second part
+        const payload = token.split('.')[1];
+        // The payload is base64 encoded, so we need to decode it
+        const decodedPayload = JSON.parse(atob(payload));
+        // Store the user ID from the 'sub' claim
+        if (decodedPayload.sub) {
+            localStorage.setItem('userId', decodedPayload.sub);
+        }
+    } catch (decodeError) {
+        console.error('Error decoding JWT token:', decodeError);
+    }
+
+    return { ...responseData, data: token };
+};
+
 // Login API Call
 export const loginUser = async (email, password) => {
     try {
         const response = await axios.post(`${API_BASE_URL}/auth/login`, { email, password });
-
-        // Decode the JWT token to get user ID
         if (response.data && response.data.data) {
-            const token = response.data.data;
-            try {
-                // JWT tokens are in format: header.payload.signature
-                // We need the payload part which is the second part
-                const payload = token.split('.')[1];
-                // The payload is base64 encoded, so we need to decode it
-                const decodedPayload = JSON.parse(atob(payload));
-                // Store the user ID from the 'sub' claim
-                if (decodedPayload.sub) {
-                    localStorage.setItem('userId', decodedPayload.sub);
-                }
-            } catch (decodeError) {
-                console.error('Error decoding JWT token:', decodeError);
-            }
+            return saveSession(response.data);
         }
 
         return response.data;
@@ -91,6 +124,46 @@ export const loginUser = async (email, password) => {
     }
 };
 
+// Verify Email API Call, logs the user in on success
+export const verifyEmail = async (token) => {
+    try {
+        const response = await axios.post(`${API_BASE_URL}/auth/verify`, { token });
+        return saveSession(response.data);
+    } catch (error) {
+        return { error: error.response?.data?.error || "Verification link is invalid or has expired" };
+    }
+};
+
+// Resend Verification Email API Call
+export const resendVerification = async (email) => {
+    try {
+        const response = await axios.post(`${API_BASE_URL}/auth/resend-verification`, { email });
+        return response.data;
+    } catch (error) {
+        return { error: error.response?.data?.error || "Something went wrong" };
+    }
+};
+
+// Forgot Password API Call
+export const forgotPassword = async (email) => {
+    try {
+        const response = await axios.post(`${API_BASE_URL}/auth/forgot-password`, { email });
+        return response.data;
+    } catch (error) {
+        return { error: error.response?.data?.error || "Something went wrong" };
+    }
+};
+
+// Reset Password API Call, logs the user in on success
+export const resetPassword = async (token, password) => {
+    try {
+        const response = await axios.post(`${API_BASE_URL}/auth/reset-password`, { token, password });
+        return saveSession(response.data);
+    } catch (error) {
+        return { error: error.response?.data?.error || "Reset link is invalid or has expired" };
+    }
+};
+
 // Create Profile API Call
 export const createProfile = async (profileData) => {
     return authRequest('post', '/profile', profileData);
@@ -262,10 +335,16 @@ export const updateEvent = async (eventId, eventData) => {
     }
 };
 
-export const getEvents = async () => {
+// Fetches a single page of events. Pass the next_cursor of the previous page
+// as cursor to load the page after it.
+export const getEvents = async (cursor = '', limit = 20) => {
     try {
         const token = localStorage.getItem('token');
-        const response = await fetch('http://localhost:8080/v1/events/all', {
+        const params = new URLSearchParams({ limit });
+        if (cursor) {
+            params.set('cursor', cursor);
+        }
+        const response = await fetch(`http://localhost:8080/v1/events/all?${params}`, {
             method: 'GET',
             headers: {
                 'Authorization': `Bearer ${token}`
@@ -1,11 +1,19 @@
 import React, { useState, useEffect, useRef } from 'react';
 import './ChatWindow.css';
 
+// Version of the chat protocol this component speaks
+const PROTOCOL_VERSION = 1;
+// How long someone is shown as typing after their last keystroke
+const TYPING_TIMEOUT = 3000;
+
 export default function ChatWindow({ eventId, isParticipant }) {
     const [messages, setMessages] = useState([]);
     const [newMessage, setNewMessage] = useState('');
     const [socket, setSocket] = useState(null);
+    const [online, setOnline] = useState([]);
+    const [typing, setTyping] = useState({});
     const messagesEndRef = useRef(null);
+    const lastTypingSent = useRef(0);
 
     useEffect(() => {
         if (!isParticipant) return;
@@ -19,8 +27,42 @@ export default function ChatWindow({ eventId, isParticipant }) {
         };
 
         ws.onmessage = (event) => {
-            const message = JSON.parse(event.data);
-            setMessages(prev => [...prev, message]);
+            const envelope = JSON.parse(event.data);
+            switch (envelope.type) {
+                case 'history':
+                    setMessages(envelope.messages || []);
+                    break;
+                case 'message':
+                    setMessages(prev => [...prev, envelope.message]);
+                    setTyping(prev => {
+                        const { [envelope.message.userId]: _, ...rest } = prev;
+                        return rest;
+                    });
+                    break;
+                case 'message_edited':
+                    setMessages(prev => prev.map(m => (m.id === envelope.message.id ? envelope.message : m)));
+                    break;
+                case 'message_deleted':
+                    setMessages(prev => prev.filter(m => m.id !== envelope.messageId));
+                    break;
+                case 'presence':
+                    setOnline(envelope.users || []);
+                    break;
+                case 'presence_join':
+                    setOnline(prev => [...prev.filter(u => u.userId !== envelope.user.userId), envelope.user]);
+                    break;
+                case 'presence_leave':
+                    setOnline(prev => prev.filter(u => u.userId !== envelope.user.userId));
+                    break;
+                case 'typing':
+                    setTyping(prev => ({ ...prev, [envelope.user.userId]: { ...envelope.user, at: Date.now() } }));
+                    break;
+                case 'error':
+                    console.warn('Chat error:', envelope.error);
+                    break;
+                default:
+                    break;
+            }
         };
 
         ws.onclose = () => {
@@ -37,17 +79,42 @@ export default function ChatWindow({ eventId, isParticipant }) {
         messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' });
     }, [messages]);
 
+    // Forget typing indicators that weren't refreshed
+    useEffect(() => {
+        const interval = setInterval(() => {
+            setTyping(prev => {
+                const now = Date.now();
+                const active = Object.fromEntries(Object.entries(prev).filter(([, u]) => now - u.at < TYPING_TIMEOUT));
+                return Object.keys(active).length === Object.keys(prev).length ? prev : active;
+            });
+        }, 1000);
+        return () => clearInterval(interval);
+    }, []);
+
+    const handleChange = (e) => {
+        setNewMessage(e.target.value);
+
+        // Let the others know, at most once per timeout
+        const now = Date.now();
+        if (socket && now - lastTypingSent.current > TYPING_TIMEOUT / 2) {
+            socket.send(JSON.stringify({ v: PROTOCOL_VERSION, type: 'typing' }));
+            lastTypingSent.current = now;
+        }
+    };
+
     const handleSendMessage = (e) => {
         e.preventDefault();
         if (!newMessage.trim() || !socket) return;
 
         const message = {
-            content: newMessage,
-            timestamp: new Date().toISOString()
+            v: PROTOCOL_VERSION,
+            type: 'message',
+            content: newMessage
         };
 
         socket.send(JSON.stringify(message));
         setNewMessage('');
+        lastTypingSent.current = 0;
     };
 
     if (!isParticipant) {
@@ -67,24 +134,33 @@ export default function ChatWindow({ eventId, isParticipant }) {
         <div className="chat-window">
             <div className="chat-header">
                 <h3>Event Chat</h3>
+                <div className="chat-presence">
+                    {online.length} online{online.length > 0 && `: ${online.map(u => u.username).join(', ')}`}
+                </div>
             </div>
             <div className="chat-messages">
                 {messages.map((msg, index) => (
-                    <div key={index} className="message">
+                    <div key={msg.id || index} className="message">
                         <span className="username">{msg.username}: </span>
                         <span className="content">{msg.content}</span>
+                        {msg.editedAt && <span className="edited"> (edited)</span>}
                         <span className="timestamp">
                             {new Date(msg.timestamp).toLocaleTimeString()}
                         </span>
                     </div>
                 ))}
+                {Object.keys(typing).length > 0 && (
+                    <div className="chat-typing">
+                        {Object.values(typing).map(u => u.username).join(', ')} typing...
+                    </div>
+                )}
                 <div ref={messagesEndRef} />
             </div>
             <form onSubmit={handleSendMessage} className="chat-input">
                 <input
                     type="text"
                     value={newMessage}
-                    onChange={(e) => setNewMessage(e.target.value)}
+                    onChange={handleChange}
                     placeholder="Type a message..."
                 />
                 <button type="submit" disabled={!socket}>
@@ -1,14 +1,20 @@
 import React, { useState } from "react";
 import { useNavigate } from "react-router-dom";
+import { forgotPassword } from "../api";
 import "./Forgotpass.css"; // Ensure this CSS file exists
 
 export default function Forgotpass() {
     const navigate = useNavigate();
     const [email, setEmail] = useState("");
 
-    const handleResetPassword = (e) => {
+    const handleResetPassword = async (e) => {
         e.preventDefault();
-        console.log("Reset link sent to:", email);
+        // The answer is the same whether or not the email has an account
+        const result = await forgotPassword(email);
+        if (result.error) {
+            alert(result.error);
+            return;
+        }
         alert("Password reset link has been sent to your email.");
     };
 
@@ -21,6 +21,7 @@ export default function GoogleCallback() {
       if (token) {
         // Store the token
         localStorage.setItem('token', token);
+        localStorage.setItem('refreshToken', params.get('refresh_token'));
         console.log('Token stored in localStorage');
 
 
@@ -16,6 +16,8 @@ export default function Home() {
     const [loading, setLoading] = useState(true);
     const [joinedEvents, setJoinedEvents] = useState([]);
     const [initialCheckDone, setInitialCheckDone] = useState(false);
+    const [nextCursor, setNextCursor] = useState('');
+    const [loadingMore, setLoadingMore] = useState(false);
 
     // Check if user has a profile only once when component mounts
     useEffect(() => {
@@ -102,6 +104,7 @@ export default function Home() {
                 console.log("Home component: Events received: ", eventsData);
                 setEvents(eventsData);
                 setFilteredEvents(eventsData); // Initialize filtered events with all events
+                setNextCursor(eventsResponse.next_cursor || '');
             } catch (error) {
                 console.error('Home component: Error fetching events:', error);
                 setError('Failed to load events');
@@ -146,25 +149,19 @@ export default function Home() {
                 setJoinedEvents([...joinedEvents, eventId]);
                 alert('Successfully joined the event!');
 
-                // Refresh events to get updated data
-                const eventsResponse = await getEvents();
-                if (eventsResponse.data) {
-                    // Update the events with the new data
-                    const updatedEvents = eventsResponse.data.map(event => {
-                        if (event.id === eventId) {
-                            // Add current user to participants if not already there
-                            const currentUserId = parseInt(localStorage.getItem('userId'));
-                            if (!event.participants.some(p => p.user_id === currentUserId)) {
-                                event.participants.push({
-                                    user_id: currentUserId
-                                });
-                                event.registered_count = (event.registered_count || 0) + 1;
-                            }
-                        }
+                // Mark the current user as a participant of the joined event
+                const currentUserId = parseInt(localStorage.getItem('userId'));
+                setEvents(events.map(event => {
+                    if (event.id !== eventId || !event.participants ||
+                        event.participants.some(p => p.user_id === currentUserId)) {
                         return event;
-                    });
-                    setEvents(updatedEvents);
-                }
+                    }
+                    return {
+                        ...event,
+                        participants: [...event.participants, { user_id: currentUserId }],
+                        registered_count: (event.registered_count || 0) + 1
+                    };
+                }));
             }
         } catch (error) {
             console.error('Error joining event:', error);
@@ -175,6 +172,23 @@ export default function Home() {
         }
     };
 
+    const handleLoadMore = async () => {
+        if (!nextCursor) {
+            return;
+        }
+        try {
+            setLoadingMore(true);
+            const eventsResponse = await getEvents(nextCursor);
+            setEvents([...events, ...(eventsResponse.data || [])]);
+            setNextCursor(eventsResponse.next_cursor || '');
+        } catch (error) {
+            console.error('Home component: Error loading more events:', error);
+            setError('Failed to load more events');
+        } finally {
+            setLoadingMore(false);
+        }
+    };
+
     const handleViewDetails = (eventId) => {
         // TODO: Implement view event functionality
         console.log('Viewing event:', eventId);
@@ -479,6 +493,25 @@ export default function Home() {
                     ))
                 )}
             </div>
+
+            {nextCursor && (
+                <div style={{ textAlign: "center", margin: "20px 0" }}>
+                    <button
+                        onClick={handleLoadMore}
+                        disabled={loadingMore}
+                        style={{
+                            backgroundColor: "#4CAF50",
+                            color: "white",
+                            border: "none",
+                            padding: "10px 20px",
+                            borderRadius: "4px",
+                            cursor: loadingMore ? "not-allowed" : "pointer"
+                        }}
+                    >
+                        {loadingMore ? "Loading..." : "Load More"}
+                    </button>
+                </div>
+            )}
         </div>
     );
     
@@ -1,8 +1,6 @@
 import React, { useState } from "react";
 import { useNavigate } from "react-router-dom";
-import { signupUser } from "../api"; // Import API function
-import { useAuth } from "../context/AuthContext";
-import {jwtDecode} from 'jwt-decode';
+import { signupUser, resendVerification } from "../api"; // Import API function
 import "./Register.css"; // Reuse the same styling as Login
 
 export default function Register() {
@@ -14,6 +12,8 @@ export default function Register() {
   const [passwordError, setPasswordError] = useState("");
   const [confirmPasswordError, setConfirmPasswordError] = useState("");
   const [apiError, setApiError] = useState("");
+  const [registeredEmail, setRegisteredEmail] = useState("");
+  const [resendMessage, setResendMessage] = useState("");
 
   const validateEmail = (email) =>
     /^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$/.test(email);
@@ -58,8 +58,6 @@ export default function Register() {
     );
   };
 
-  const { login } = useAuth();
-
   const handleSubmit = async (e) => {
     e.preventDefault();
     if (isDisabled) return;
@@ -68,23 +66,18 @@ export default function Register() {
     if (result.error) {
       setApiError(result.error);
     } else {
-        localStorage.setItem("token", result.data);
-
-        const decoded=jwtDecode(localStorage.getItem("token"));
-        const userId=decoded.sub;
-        console.log("User ID:",userId);
-        localStorage.setItem("userId", userId);
-
-      // Use the login function from auth context
-      login(result.data);
-      alert("Signup successful!");
-
-      // After signup, users always need to create a profile
-      // No need to check if profile exists since this is a new user
-      navigate("/Profile");
+      // The account stays inactive until the link in the verification email
+      // is opened, which logs the user in and sends them to /Profile
+      setApiError("");
+      setRegisteredEmail(email);
     }
   };
 
+  const handleResend = async () => {
+    const result = await resendVerification(registeredEmail);
+    setResendMessage(result.error || "A new verification email is on its way.");
+  };
+
   return (
     <div className="login-container">
       <div style={{ minHeight: "100vh", display: "flex", flexDirection: "row" }}>
@@ -114,6 +107,27 @@ export default function Register() {
 
         {/* Register Form */}
         <div style={{ flex: 1 }} className="login-right">
+          {registeredEmail ? (
+          <div className="login-box">
+            <h2>Check your email</h2>
+            <p>
+              We sent a verification link to <b>{registeredEmail}</b>. Open it to
+              activate your account and finish setting up your profile.
+            </p>
+            {resendMessage && <p style={{ fontSize: "14px", textAlign: "center" }}>{resendMessage}</p>}
+
+            <button type="button" className="login-button" onClick={handleResend}>
+              Resend email
+            </button>
+
+            <p className="new-user">
+              Already verified?{" "}
+              <span onClick={() => navigate("/login")} className="click-text">
+                Log in here
+              </span>
+            </p>
+          </div>
+          ) : (
           <div className="login-box">
             <h2>Sign Up</h2>
             {apiError && <p style={{ color: "red", fontSize: "14px", textAlign: "center" }}>{apiError}</p>}
@@ -184,6 +198,7 @@ export default function Register() {
               </span>
             </p>
           </div>
+          )}
         </div>
       </div>
     </div>
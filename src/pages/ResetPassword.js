@@ -0,0 +1,111 @@
+import React, { useState } from "react";
+import { useNavigate, useLocation } from "react-router-dom";
+import { resetPassword } from "../api";
+import { useAuth } from "../context/AuthContext";
+import "./Forgotpass.css"; // Same layout as the page that sends the link
+
+export default function ResetPassword() {
+    const navigate = useNavigate();
+    const location = useLocation();
+    const { login, checkUserProfile } = useAuth();
+    const [password, setPassword] = useState("");
+    const [confirmPassword, setConfirmPassword] = useState("");
+    const [error, setError] = useState("");
+
+    const token = new URLSearchParams(location.search).get("token");
+
+    const validatePassword = (password) =>
+        /^(?=.*[a-z])(?=.*[A-Z])(?=.*\d)(?=.*[@$!%*?&])[A-Za-z\d@$!%*?&]{8,}$/.test(password);
+
+    const handleResetPassword = async (e) => {
+        e.preventDefault();
+        if (!validatePassword(password)) {
+            setError("Password must be at least 8 characters, include uppercase, lowercase, number, and special character.");
+            return;
+        }
+        if (password !== confirmPassword) {
+            setError("Passwords do not match.");
+            return;
+        }
+
+        const result = await resetPassword(token, password);
+        if (result.error) {
+            setError(result.error);
+            return;
+        }
+
+        // Every older session was revoked, so continue with the new one
+        await login(result.data);
+        const hasProfile = await checkUserProfile();
+        alert("Your password has been reset.");
+        navigate(hasProfile ? "/Home" : "/Profile");
+    };
+
+    return (
+        <div className="forgotpass-container">
+            <div style={{ minHeight: "100vh", display: "flex", flexDirection: "row" }}>
+                <div style={{ backgroundColor: "black", width: '32%', padding: "50px", opacity: "80%", color: "white", alignContent: "center", textAlign: "center", justifyContent: "center" }} className="relative w-1/2 flex flex-col justify-center items-center text-white p-10">
+
+                    <div className="relative z-10 text-center">
+                        <h1 style={{ fontSize: "80px", fontFamily: "sans-serif", marginBottom: "20px" }}><i>SPORT!FY</i></h1>
+                        <p style={{ fontSize: "20px" }}>
+                            Connect with friends and the world around you through sports. Find people who share your love for sports, join local events, and never miss a game again. Whether you're an athlete, a casual player, or just a fan, Sportify connects you to the world of sports like never before!
+                        </p>
+                    </div>
+                </div>
+
+                <div style={{ flex: 1, }} className="forgotpass-right">
+                    {token ? (
+                        <form className="forgotpass-box" onSubmit={handleResetPassword}>
+                            <h2>Choose a New Password</h2>
+
+                            <label>New Password</label>
+                            <input
+                                type="password"
+                                name="password"
+                                placeholder="Enter your new password"
+                                value={password}
+                                onChange={(e) => setPassword(e.target.value)}
+                                required
+                            />
+
+                            <label>Confirm Password</label>
+                            <input
+                                type="password"
+                                name="confirmPassword"
+                                placeholder="Confirm your new password"
+                                value={confirmPassword}
+                                onChange={(e) => setConfirmPassword(e.target.value)}
+                                required
+                            />
+
+                            {error && <p style={{ color: "red", fontSize: "14px" }}>{error}</p>}
+
+                            <div className="button-container">
+                                <button type="button" className="cancel-button" onClick={() => navigate("/login")}>
+                                    Cancel
+                                </button>
+                                <button type="submit" className="reset-button">
+                                    Save Password
+                                </button>
+                            </div>
+                        </form>
+                    ) : (
+                        <div className="forgotpass-box">
+                            <h2>Invalid Link</h2>
+                            <p>This password reset link is incomplete. Request a new one to continue.</p>
+                            <div className="button-container">
+                                <button type="button" className="cancel-button" onClick={() => navigate("/login")}>
+                                    Cancel
+                                </button>
+                                <button type="button" className="reset-button" onClick={() => navigate("/Forgotpass")}>
+                                    New Link
+                                </button>
+                            </div>
+                        </div>
+                    )}
+                </div>
+            </div>
+        </div>
+    );
+}
@@ -0,0 +1,110 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { useNavigate, useLocation } from 'react-router-dom';
+import { verifyEmail, resendVerification } from '../api';
+import { useAuth } from '../context/AuthContext';
+
+
+export default function VerifyEmail() {
+  const navigate = useNavigate();
+  const location = useLocation();
+  const { login } = useAuth();
+  const [error, setError] = useState('');
+  const [email, setEmail] = useState('');
+  const [resendMessage, setResendMessage] = useState('');
+  // Verification tokens only work once, so don't send it twice when the
+  // effect runs again in development
+  const verifying = useRef(false);
+
+
+  useEffect(() => {
+    if (verifying.current) return;
+    verifying.current = true;
+
+    const handleVerify = async () => {
+      const token = new URLSearchParams(location.search).get('token');
+      if (!token) {
+        setError('This verification link is incomplete.');
+        return;
+      }
+
+      const result = await verifyEmail(token);
+      if (result.error) {
+        setError(result.error);
+        return;
+      }
+
+      await login(result.data);
+
+      // Freshly verified users never have a profile yet
+      localStorage.setItem('hasProfile', 'false');
+      navigate('/Profile');
+    };
+
+
+    handleVerify();
+  }, [navigate, location, login]);
+
+
+  const handleResend = async (e) => {
+    e.preventDefault();
+    const result = await resendVerification(email);
+    setResendMessage(result.error || 'If that account still needs verifying, a new link is on its way.');
+  };
+
+
+  return (
+    <div style={{
+      display: 'flex',
+      justifyContent: 'center',
+      alignItems: 'center',
+      height: '100vh',
+      backgroundImage: "url('/sports.jpg')",
+      backgroundSize: "cover",
+      backgroundPosition: 'center'
+    }}>
+      <div style={{
+        backgroundColor: 'white',
+        padding: '2rem',
+        borderRadius: '10px',
+        textAlign: 'center',
+        width: '350px'
+      }}>
+        {error ? (
+          <>
+            <h2>Verification failed</h2>
+            <p>{error}</p>
+            <form onSubmit={handleResend}>
+              <input
+                type="email"
+                placeholder="Enter your email"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                style={{ width: '100%', padding: '10px', marginBottom: '10px', boxSizing: 'border-box' }}
+                required
+              />
+              <button
+                type="submit"
+                style={{
+                  backgroundColor: 'black',
+                  color: 'white',
+                  border: 'none',
+                  padding: '10px 20px',
+                  borderRadius: '5px',
+                  cursor: 'pointer'
+                }}
+              >
+                Send a new link
+              </button>
+            </form>
+            {resendMessage && <p style={{ fontSize: '14px' }}>{resendMessage}</p>}
+          </>
+        ) : (
+          <>
+            <h2>Verifying your email...</h2>
+            <p>Please wait while we activate your account.</p>
+          </>
+        )}
+      </div>
+    </div>
+  );
+}